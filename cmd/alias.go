@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jpappel/atlas/pkg/data"
+)
+
+// Manage author aliases, args are the subcommand followed by its arguments
+func RunAlias(gFlags GlobalFlags, db *data.Query, args []string) byte {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "No alias subcommand provided")
+		Help("alias", os.Stderr)
+		return 2
+	}
+
+	ctx, cancel := gFlags.Context()
+	defer cancel()
+
+	switch subcommand := args[0]; subcommand {
+	case "add":
+		if len(args) < 3 {
+			fmt.Fprintf(os.Stderr, "Usage: %s alias add <author> <alias>...\n", os.Args[0])
+			return 2
+		}
+		if err := db.AddAliases(ctx, args[1], args[2:]...); err != nil {
+			fmt.Fprintln(os.Stderr, "Error while adding aliases:", err)
+			return 1
+		}
+	case "remove", "rm":
+		if len(args) < 2 {
+			fmt.Fprintf(os.Stderr, "Usage: %s alias remove <alias>...\n", os.Args[0])
+			return 2
+		}
+		if err := db.RemoveAliases(ctx, args[1:]...); err != nil {
+			fmt.Fprintln(os.Stderr, "Error while removing aliases:", err)
+			return 1
+		}
+	case "list", "ls":
+		aliases, err := db.Aliases(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error while listing aliases:", err)
+			return 1
+		}
+		for _, alias := range aliases {
+			fmt.Printf("%s -> %s\n", alias.Alias, alias.Author)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, "Unrecognized alias subcommand: ", subcommand)
+		return 2
+	}
+
+	return 0
+}
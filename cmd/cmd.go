@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"io/fs"
@@ -10,16 +11,54 @@ import (
 	"time"
 
 	"github.com/adrg/xdg"
+	"github.com/jpappel/atlas/pkg/data"
 )
 
 type GlobalFlags struct {
-	IndexRoot  string
-	DBPath     string
-	LogLevel   string
-	LogJson    bool
-	NumWorkers uint
-	DateFormat string
-	LogFile    string
+	IndexRoot         string
+	DBPath            string
+	DBPaths           []string
+	Webhooks          []string
+	LogLevel          string
+	LogJson           bool
+	NumWorkers        uint
+	DateFormat        string
+	LogFile           string
+	LogAppend         bool
+	LogMaxSize        int64
+	LogRetain         int
+	Timeout           time.Duration
+	BusyTimeout       time.Duration
+	WalAutocheckpoint int
+	FuzzyDistance     int
+	CacheSize         int
+	CPUProfile        string
+	MemProfile        string
+	Trace             string
+}
+
+// Flag value for -db which can be repeated. The first path provided replaces
+// the default path.
+type dbPathsValue struct {
+	flags *GlobalFlags
+	isSet bool
+}
+
+func (v *dbPathsValue) String() string {
+	if v == nil || v.flags == nil {
+		return ""
+	}
+	return strings.Join(v.flags.DBPaths, ",")
+}
+
+func (v *dbPathsValue) Set(s string) error {
+	if !v.isSet {
+		v.flags.DBPaths = v.flags.DBPaths[:0]
+		v.isSet = true
+	}
+	v.flags.DBPaths = append(v.flags.DBPaths, s)
+	v.flags.DBPath = v.flags.DBPaths[0]
+	return nil
 }
 
 func SetupGlobalFlags(fs_ *flag.FlagSet, flags *GlobalFlags) {
@@ -34,11 +73,46 @@ func SetupGlobalFlags(fs_ *flag.FlagSet, flags *GlobalFlags) {
 		panic(err)
 	}
 
+	flags.DBPath = dataHome + string(os.PathSeparator) + "default.db"
+	flags.DBPaths = []string{flags.DBPath}
+
 	flag.StringVar(&flags.IndexRoot, "root", xdg.UserDirs.Documents, "root `directory` for indexing")
-	flag.StringVar(&flags.DBPath, "db", dataHome+string(os.PathSeparator)+"default.db", "`path` to document database")
+	flag.Var(&dbPathsValue{flags: flags}, "db", "`path` to document database, repeat to query multiple databases")
 	flag.StringVar(&flags.LogLevel, "logLevel", "error", "set log `level` (debug, info, warn, error)")
 	flag.BoolVar(&flags.LogJson, "logJson", false, "log to json")
 	flag.UintVar(&flags.NumWorkers, "numWorkers", uint(runtime.NumCPU()), "number of worker threads to use (defaults to core count)")
 	flag.StringVar(&flags.DateFormat, "dateFormat", time.RFC3339, "`format` for dates (see https://pkg.go.dev/time#Layout for more details)")
 	flag.StringVar(&flags.LogFile, "logFile", "", "`file` to log errors to, use '-' for stdout and empty for stderr")
+	flag.BoolVar(&flags.LogAppend, "logAppend", false, "append to -logFile instead of truncating it")
+	flag.Int64Var(&flags.LogMaxSize, "logMaxSize", 0, "rotate -logFile after it exceeds `bytes`, 0 to disable")
+	flag.IntVar(&flags.LogRetain, "logRetain", 3, "`number` of rotated log files to keep")
+	flag.DurationVar(&flags.Timeout, "timeout", 0, "maximum `duration` of database operations, 0 for no timeout")
+	flag.DurationVar(&flags.BusyTimeout, "busyTimeout", data.DefaultDBOpts.BusyTimeout, "maximum `duration` to wait for a locked database")
+	flag.IntVar(&flags.WalAutocheckpoint, "walAutocheckpoint", data.DefaultDBOpts.WalAutocheckpoint, "checkpoint the write ahead log after it exceeds `pages`, 0 to disable")
+	flag.IntVar(&flags.FuzzyDistance, "fuzzyDistance", data.DefaultDBOpts.FuzzyDistance, "maximum `edits` between values matched with ?=")
+	flag.Func("webhook", "POST index changes as JSON to `url` after each update, repeat for multiple urls", func(s string) error {
+		flags.Webhooks = append(flags.Webhooks, s)
+		return nil
+	})
+	flag.IntVar(&flags.CacheSize, "cacheSize", 1000, "`number` of documents the server and shell cache between queries, 0 to disable")
+	flag.StringVar(&flags.CPUProfile, "cpuprofile", "", "write a cpu profile to `file`")
+	flag.StringVar(&flags.MemProfile, "memprofile", "", "write a memory profile to `file` on exit")
+	flag.StringVar(&flags.Trace, "trace", "", "write an execution trace to `file`")
+}
+
+// Create a context which is cancelled after -timeout, if set
+func (flags GlobalFlags) Context() (context.Context, context.CancelFunc) {
+	if flags.Timeout > 0 {
+		return context.WithTimeout(context.Background(), flags.Timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// Database connection settings from -busyTimeout, -walAutocheckpoint, and -fuzzyDistance
+func (flags GlobalFlags) DBOpts() data.DBOpts {
+	return data.DBOpts{
+		BusyTimeout:       flags.BusyTimeout,
+		WalAutocheckpoint: flags.WalAutocheckpoint,
+		FuzzyDistance:     flags.FuzzyDistance,
+	}
 }
@@ -0,0 +1,50 @@
+package cmd_test
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/jpappel/atlas/pkg/data"
+	"github.com/jpappel/atlas/pkg/index"
+)
+
+// Run f with os.Stdout redirected, returning what was written and f's exit code
+func captureStdout(t *testing.T, f func() byte) (string, byte) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	out := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- string(b)
+	}()
+
+	code := f()
+	w.Close()
+	return <-out, code
+}
+
+// Create an in memory database containing docs
+func newTestDB(t *testing.T, docs ...*index.Document) *data.Query {
+	t.Helper()
+	db := data.NewMemQuery("test")
+	t.Cleanup(func() { db.Close() })
+
+	pathDocs := make(map[string]*index.Document, len(docs))
+	for _, doc := range docs {
+		pathDocs[doc.Path] = doc
+	}
+	if err := db.Put(t.Context(), index.Index{Documents: pathDocs}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/jpappel/atlas/pkg/data"
+)
+
+type DiffFlags struct {
+	Mode string
+}
+
+func SetupDiffFlags(args []string, fs *flag.FlagSet, flags *DiffFlags) {
+	fs.StringVar(&flags.Mode, "mode", "diff", "set `operation` to report (diff, union, intersect)")
+
+	fs.Usage = func() {
+		f := fs.Output()
+		Help("diff", f)
+		PrintGlobalFlags(f)
+	}
+
+	fs.Parse(args)
+}
+
+// Compare the documents matching two queries
+func RunDiff(gFlags GlobalFlags, dFlags DiffFlags, db *data.Query, queryA string, queryB string) byte {
+	switch dFlags.Mode {
+	case "diff", "union", "intersect":
+	default:
+		fmt.Fprintln(os.Stderr, "Unrecognized diff mode: ", dFlags.Mode)
+		return 2
+	}
+
+	ctx, cancel := gFlags.Context()
+	defer cancel()
+
+	results := make([]map[string]bool, 2)
+	for i, searchQuery := range []string{queryA, queryB} {
+		artifact, err := db.Compile(ctx, searchQuery, 0, gFlags.NumWorkers)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to compile query `%s`: %s\n", searchQuery, err)
+			return 1
+		}
+
+		paths, err := db.ExecutePaths(ctx, artifact)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to execute query `%s`: %s\n", searchQuery, err)
+			return 1
+		}
+		results[i] = make(map[string]bool, len(paths))
+		for _, path := range paths {
+			results[i][path] = true
+		}
+	}
+
+	var onlyA, onlyB, both []string
+	for path := range results[0] {
+		if results[1][path] {
+			both = append(both, path)
+		} else {
+			onlyA = append(onlyA, path)
+		}
+	}
+	for path := range results[1] {
+		if !results[0][path] {
+			onlyB = append(onlyB, path)
+		}
+	}
+
+	switch dFlags.Mode {
+	case "diff":
+		fmt.Printf("Only in A (%d):\n", len(onlyA))
+		printPaths(onlyA)
+		fmt.Printf("\nOnly in B (%d):\n", len(onlyB))
+		printPaths(onlyB)
+		fmt.Printf("\nIn both (%d):\n", len(both))
+		printPaths(both)
+	case "union":
+		union := slices.Collect(maps.Keys(results[0]))
+		printPaths(append(union, onlyB...))
+	case "intersect":
+		printPaths(both)
+	}
+
+	return 0
+}
+
+func printPaths(paths []string) {
+	slices.Sort(paths)
+	for _, path := range paths {
+		fmt.Println(path)
+	}
+}
@@ -0,0 +1,49 @@
+package cmd_test
+
+import (
+	"testing"
+
+	"github.com/jpappel/atlas/cmd"
+	"github.com/jpappel/atlas/pkg/index"
+)
+
+func TestRunDiff(t *testing.T) {
+	db := newTestDB(t,
+		&index.Document{Path: "/a", Title: "A", Tags: []string{"work"}},
+		&index.Document{Path: "/b", Title: "B", Tags: []string{"work", "home"}},
+		&index.Document{Path: "/c", Title: "C", Tags: []string{"home"}},
+		&index.Document{Path: "/d", Title: "D", Tags: []string{"misc"}},
+	)
+	gFlags := cmd.GlobalFlags{NumWorkers: 1}
+
+	tests := []struct {
+		name     string
+		mode     string
+		queryA   string
+		queryB   string
+		want     string
+		wantCode byte
+	}{
+		{"diff", "diff", "t:work", "t:home", "Only in A (1):\n/a\n\nOnly in B (1):\n/c\n\nIn both (1):\n/b\n", 0},
+		{"diff disjoint", "diff", "t:work", "t:misc", "Only in A (2):\n/a\n/b\n\nOnly in B (1):\n/d\n\nIn both (0):\n", 0},
+		{"diff empty", "diff", "t:missing", "t:missing", "Only in A (0):\n\nOnly in B (0):\n\nIn both (0):\n", 0},
+		{"union", "union", "t:work", "t:home", "/a\n/b\n/c\n", 0},
+		{"intersect", "intersect", "t:work", "t:home", "/b\n", 0},
+		{"intersect disjoint", "intersect", "t:work", "t:misc", "", 0},
+		{"unknown mode", "xor", "t:work", "t:home", "", 2},
+		{"invalid query", "diff", "t:work", "t=", "", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, code := captureStdout(t, func() byte {
+				return cmd.RunDiff(gFlags, cmd.DiffFlags{Mode: tt.mode}, db, tt.queryA, tt.queryB)
+			})
+			if code != tt.wantCode {
+				t.Errorf("RunDiff() = %d, want %d", code, tt.wantCode)
+			}
+			if got != tt.want {
+				t.Errorf("RunDiff() wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
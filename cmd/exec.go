@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// maximum number of paths passed to a single command with -execBatch
+const execBatchSize = 512
+
+// Run a command for each path, replacing {} in its arguments with the path.
+// The command is split on whitespace and is not run in a shell.
+func runExec(ctx context.Context, command string, paths []string) error {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return errors.New("Empty command")
+	}
+
+	var errs []error
+	for _, path := range paths {
+		cmdArgs := make([]string, len(args))
+		for i, arg := range args {
+			cmdArgs[i] = strings.ReplaceAll(arg, "{}", path)
+		}
+		if err := runCommand(ctx, cmdArgs); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Run a command with many paths at once, like xargs. An argument of {} is
+// replaced by the paths, otherwise they are appended to the command.
+func runExecBatch(ctx context.Context, command string, paths []string) error {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return errors.New("Empty command")
+	}
+
+	var errs []error
+	for start := 0; start < len(paths); start += execBatchSize {
+		batch := paths[start:min(start+execBatchSize, len(paths))]
+
+		cmdArgs := make([]string, 0, len(args)+len(batch))
+		substituted := false
+		for _, arg := range args {
+			if arg == "{}" {
+				cmdArgs = append(cmdArgs, batch...)
+				substituted = true
+			} else {
+				cmdArgs = append(cmdArgs, arg)
+			}
+		}
+		if !substituted {
+			cmdArgs = append(cmdArgs, batch...)
+		}
+
+		if err := runCommand(ctx, cmdArgs); err != nil {
+			errs = append(errs, err)
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func runCommand(ctx context.Context, args []string) error {
+	c := exec.CommandContext(ctx, args[0], args[1:]...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	return c.Run()
+}
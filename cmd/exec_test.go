@@ -0,0 +1,97 @@
+package cmd_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jpappel/atlas/cmd"
+	"github.com/jpappel/atlas/pkg/data"
+	"github.com/jpappel/atlas/pkg/index"
+)
+
+// Write a script echoing its arguments that fails when its first argument is fail
+func failingScript(t *testing.T, fail string) string {
+	t.Helper()
+	script := filepath.Join(t.TempDir(), "script")
+	content := fmt.Sprintf("#!/bin/sh\necho \"$@\"\ntest \"$1\" != %s\n", fail)
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return script
+}
+
+func TestRunQuery_Exec(t *testing.T) {
+	db := newTestDB(t,
+		&index.Document{Path: "/a.md", Title: "Note"},
+		&index.Document{Path: "/b.md", Title: "Note"},
+		&index.Document{Path: "/c.md", Title: "Note"},
+	)
+	script := failingScript(t, "/b.md")
+
+	tests := []struct {
+		name     string
+		flags    cmd.QueryFlags
+		want     string
+		wantCode byte
+	}{
+		{"substitute", cmd.QueryFlags{Exec: "echo path={} {}"}, "path=/a.md /a.md\npath=/b.md /b.md\npath=/c.md /c.md\n", 0},
+		{"no placeholder", cmd.QueryFlags{Exec: "echo hi"}, "hi\nhi\nhi\n", 0},
+		{"failing command continues", cmd.QueryFlags{Exec: script + " {}"}, "/a.md\n/b.md\n/c.md\n", 1},
+		{"missing command", cmd.QueryFlags{Exec: filepath.Join(t.TempDir(), "missing")}, "", 1},
+		{"empty command", cmd.QueryFlags{Exec: " "}, "", 1},
+		{"batch substitute", cmd.QueryFlags{ExecBatch: "echo start {} end"}, "start /a.md /b.md /c.md end\n", 0},
+		{"batch append", cmd.QueryFlags{ExecBatch: "echo start"}, "start /a.md /b.md /c.md\n", 0},
+		{"batch only whole placeholder", cmd.QueryFlags{ExecBatch: "echo x{}"}, "x{} /a.md /b.md /c.md\n", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, code := captureStdout(t, func() byte {
+				return cmd.RunQuery(cmd.GlobalFlags{NumWorkers: 1}, tt.flags, []*data.Query{db}, []string{"T:note"})
+			})
+			if code != tt.wantCode {
+				t.Errorf("RunQuery() = %d, want %d", code, tt.wantCode)
+			}
+			if got != tt.want {
+				t.Errorf("RunQuery() wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunQuery_ExecBatchSize(t *testing.T) {
+	docs := make([]*index.Document, 0, 513)
+	paths := make([]string, 0, 513)
+	for i := range 513 {
+		path := fmt.Sprintf("/%03d.md", i)
+		docs = append(docs, &index.Document{Path: path, Title: "Note"})
+		paths = append(paths, path)
+	}
+	db := newTestDB(t, docs...)
+
+	tests := []struct {
+		name     string
+		command  string
+		want     string
+		wantCode byte
+	}{
+		{"batches", "echo", strings.Join(paths[:512], " ") + "\n" + paths[512] + "\n", 0},
+		{"failing batch continues", failingScript(t, paths[0]), strings.Join(paths[:512], " ") + "\n" + paths[512] + "\n", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qFlags := cmd.QueryFlags{ExecBatch: tt.command}
+			got, code := captureStdout(t, func() byte {
+				return cmd.RunQuery(cmd.GlobalFlags{NumWorkers: 1}, qFlags, []*data.Query{db}, []string{"T:note"})
+			})
+			if code != tt.wantCode {
+				t.Errorf("RunQuery() = %d, want %d", code, tt.wantCode)
+			}
+			if got != tt.want {
+				t.Errorf("RunQuery() wrote %d bytes, want %d", len(got), len(tt.want))
+			}
+		})
+	}
+}
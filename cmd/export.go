@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jpappel/atlas/pkg/data"
+	"github.com/jpappel/atlas/pkg/query"
+)
+
+type ExportFlags struct {
+	Output            string
+	OptimizationLevel int
+}
+
+func SetupExportFlags(args []string, fs *flag.FlagSet, flags *ExportFlags) {
+	fs.StringVar(&flags.Output, "out", "", "`file` to write documents to, empty for stdout")
+	fs.IntVar(&flags.OptimizationLevel, "optLevel", 0, "optimization `level` for queries, 0 is automatic, <0 to disable")
+
+	fs.Usage = func() {
+		f := fs.Output()
+		Help("export", f)
+		PrintGlobalFlags(f)
+	}
+
+	fs.Parse(args)
+}
+
+// Export documents matching searchQuery, or all documents when it is empty
+func RunExport(gFlags GlobalFlags, eFlags ExportFlags, db *data.Query, searchQuery string) byte {
+	ctx, cancel := gFlags.Context()
+	defer cancel()
+
+	var artifact *query.CompilationArtifact
+	if searchQuery != "" {
+		a, err := db.Compile(ctx, searchQuery, eFlags.OptimizationLevel, gFlags.NumWorkers)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to compile query: ", err)
+			return 1
+		}
+		artifact = &a
+	}
+
+	var w io.Writer = os.Stdout
+	if eFlags.Output != "" {
+		f, err := os.Create(eFlags.Output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Cannot create output file:", err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+
+	n, err := db.Export(ctx, w, artifact)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error while exporting:", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d documents\n", n)
+
+	return 0
+}
+
+// Import documents from files, reads from stdin when no files or '-' are given
+func RunImport(gFlags GlobalFlags, db *data.Query, paths []string) byte {
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+
+	ctx, cancel := gFlags.Context()
+	defer cancel()
+
+	total := 0
+	for _, path := range paths {
+		var r io.ReadCloser = io.NopCloser(os.Stdin)
+		if path != "-" {
+			f, err := os.Open(path)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Cannot open import file:", err)
+				return 1
+			}
+			r = f
+		}
+
+		n, err := db.Import(ctx, r)
+		r.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error while importing %s: %s\n", path, err)
+			return 1
+		}
+		total += n
+	}
+	fmt.Printf("Imported %d documents\n", total)
+
+	return 0
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+
+	"github.com/jpappel/atlas/pkg/data"
+	"github.com/jpappel/atlas/pkg/index"
+)
+
+type GrepFlags struct {
+	IgnoreCase        bool
+	OnlyMatching      bool
+	OptimizationLevel int
+}
+
+func SetupGrepFlags(args []string, fs *flag.FlagSet, flags *GrepFlags) {
+	fs.BoolVar(&flags.IgnoreCase, "i", false, "case insensitive matching")
+	fs.BoolVar(&flags.OnlyMatching, "o", false, "print only the matched parts of a line")
+	fs.IntVar(&flags.OptimizationLevel, "optLevel", 0, "optimization `level` for queries, 0 is automatic, <0 to disable")
+
+	fs.Usage = func() {
+		f := fs.Output()
+		Help("grep", f)
+		PrintGlobalFlags(f)
+	}
+
+	fs.Parse(args)
+}
+
+// Search the contents of documents matching searchQuery for pattern,
+// all documents are searched when searchQuery is empty.
+// Like grep, returns 0 if a line matched, 1 if none did, and 2 on errors.
+func RunGrep(gFlags GlobalFlags, grepFlags GrepFlags, db *data.Query, pattern string, searchQuery string) byte {
+	if grepFlags.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid pattern:", err)
+		return 2
+	}
+
+	ctx, cancel := gFlags.Context()
+	defer cancel()
+
+	var docs map[string]*index.Document
+	if searchQuery != "" {
+		artifact, err := db.Compile(ctx, searchQuery, grepFlags.OptimizationLevel, gFlags.NumWorkers)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to compile query: ", err)
+			return 2
+		}
+		docs, err = db.Execute(ctx, artifact)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to execute query: ", err)
+			return 2
+		}
+	} else {
+		idx, err := db.Get(ctx, gFlags.IndexRoot)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to read index: ", err)
+			return 2
+		}
+		docs = idx.Documents
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	matched := false
+	for _, path := range slices.Sorted(maps.Keys(docs)) {
+		n, err := grepFile(ctx, w, path, re, grepFlags.OnlyMatching)
+		matched = matched || n > 0
+		if errors.Is(err, context.DeadlineExceeded) {
+			fmt.Fprintln(os.Stderr, "Grep timed out after", gFlags.Timeout)
+			return 2
+		} else if err != nil {
+			slog.Warn("Cannot search document",
+				slog.String("path", path),
+				slog.String("err", err.Error()),
+			)
+		}
+	}
+
+	if !matched {
+		return 1
+	}
+	return 0
+}
+
+// Write path:line:match for each line in a file matching re, returning the
+// number of matching lines
+func grepFile(ctx context.Context, w io.Writer, path string, re *regexp.Regexp, onlyMatching bool) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	n := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Bytes()
+		if !onlyMatching {
+			if re.Match(line) {
+				n++
+				fmt.Fprintf(w, "%s:%d:%s\n", path, lineNum, line)
+			}
+			continue
+		}
+		matches := re.FindAll(line, -1)
+		if len(matches) > 0 {
+			n++
+		}
+		for _, match := range matches {
+			fmt.Fprintf(w, "%s:%d:%s\n", path, lineNum, match)
+		}
+	}
+
+	return n, scanner.Err()
+}
@@ -0,0 +1,59 @@
+package cmd_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jpappel/atlas/cmd"
+	"github.com/jpappel/atlas/pkg/index"
+)
+
+func TestRunGrep(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"a.md": "---\ntitle: Alpha\n---\nTODO: write alpha\ndone\n",
+		"b.md": "---\ntitle: Beta\n---\nnothing to see\ntodo later, TODO soon\n",
+	}
+	docs := make([]*index.Document, 0, len(files))
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		docs = append(docs, &index.Document{Path: path, Title: name})
+	}
+	db := newTestDB(t, docs...)
+	gFlags := cmd.GlobalFlags{IndexRoot: root, NumWorkers: 1}
+	a, b := filepath.Join(root, "a.md"), filepath.Join(root, "b.md")
+
+	tests := []struct {
+		name     string
+		flags    cmd.GrepFlags
+		pattern  string
+		query    string
+		want     string
+		wantCode byte
+	}{
+		{"all documents", cmd.GrepFlags{}, "TODO", "", a + ":4:TODO: write alpha\n" + b + ":5:todo later, TODO soon\n", 0},
+		{"query", cmd.GrepFlags{}, "TODO", "T:b.md", b + ":5:todo later, TODO soon\n", 0},
+		{"only matching", cmd.GrepFlags{OnlyMatching: true, IgnoreCase: true}, "todo", "T:b.md", b + ":5:todo\n" + b + ":5:TODO\n", 0},
+		{"no match", cmd.GrepFlags{}, "FIXME", "", "", 1},
+		{"no documents", cmd.GrepFlags{}, "TODO", "T:missing", "", 1},
+		{"invalid pattern", cmd.GrepFlags{}, "(", "", "", 2},
+		{"invalid query", cmd.GrepFlags{}, "TODO", "T=", "", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, code := captureStdout(t, func() byte {
+				return cmd.RunGrep(gFlags, tt.flags, db, tt.pattern, tt.query)
+			})
+			if code != tt.wantCode {
+				t.Errorf("RunGrep() = %d, want %d", code, tt.wantCode)
+			}
+			if got != tt.want {
+				t.Errorf("RunGrep() wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
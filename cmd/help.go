@@ -16,8 +16,18 @@ var helpTopics = []string{
 	"index update", "i update",
 	"index tidy", "i tidy",
 	"query", "q",
+	"export",
+	"import",
+	"alias",
+	"saved",
+	"grep",
+	"links",
+	"pin",
+	"diff",
+	"publish",
 	"shell",
 	"server",
+	"rpc",
 }
 
 func PrintHelp(w io.Writer) {
@@ -26,8 +36,18 @@ func PrintHelp(w io.Writer) {
 	fmt.Fprintln(w, "Commands:")
 	fmt.Fprintln(w, "  index <subcommand>    - build, update, or modify an index")
 	fmt.Fprintln(w, "  query <subcommand>    - search against an index")
+	fmt.Fprintln(w, "  export [query]        - write documents as newline delimited JSON")
+	fmt.Fprintln(w, "  import [file]...      - read documents from newline delimited JSON")
+	fmt.Fprintln(w, "  alias <subcommand>    - manage author aliases")
+	fmt.Fprintln(w, "  saved <subcommand>    - manage saved queries")
+	fmt.Fprintln(w, "  grep <regex> [query]  - search the contents of matching documents")
+	fmt.Fprintln(w, "  links [subcommand]    - report broken links, orphans, and most linked documents")
+	fmt.Fprintln(w, "  pin [path]...         - pin documents so they are listed first in results")
+	fmt.Fprintln(w, "  diff <query> <query>  - compare the documents matching two queries")
+	fmt.Fprintln(w, "  publish               - render documents into a static site")
 	fmt.Fprintln(w, "  shell                 - start a debug shell")
 	fmt.Fprintln(w, "  server                - start an http query server (EXPERIMENTAL)")
+	fmt.Fprintln(w, "  rpc                   - serve JSON-RPC requests on stdin and stdout")
 	fmt.Fprintln(w, "  help  <help-topic>    - print help info")
 }
 
@@ -56,22 +76,55 @@ func Help(topic string, w io.Writer) {
 		fmt.Fprintf(w, "\nSee %s help index <subcommand> for subcommand help\n\n", os.Args[0])
 		fmt.Fprintln(w, "Index Flags:")
 		PrintFlagSet(w, fs)
+		fmt.Fprintln(w, "\nProgress:")
+		fmt.Fprintln(w, "  With `-progress json` the crawl, filter, parse, and write stages each write events as they run")
+		fmt.Fprintln(w, "  ex. {\"stage\": \"parse\", \"processed\": 120, \"total\": 300, \"errors\": 2}")
+		fmt.Fprintln(w, "  a total of 0 is not yet known, a failed stage has an \"error\" with the reason")
+		fmt.Fprintln(w, "\nSchemas:")
+		fmt.Fprintln(w, "  With `-validate` each header is checked before the index is written,")
+		fmt.Fprintln(w, "  nonconforming documents are reported as file:line and still indexed")
+		fmt.Fprintln(w, "  add `-strict` to instead exit with status 1 without writing the index")
+		fmt.Fprintln(w, "  required - keys every header must contain")
+		fmt.Fprintln(w, "  types    - key to type (string, number, bool, date, list, map)")
+		fmt.Fprintln(w, "  tags     - allowed tags and keywords, a tag allows tags nested beneath it")
+		fmt.Fprintln(w, "  ex. required: [title, date]")
+		fmt.Fprintln(w, "      types: {date: date, draft: bool}")
+		fmt.Fprintln(w, "      tags: [work, home]")
 	case "i build", "index build":
 		fmt.Fprintf(w, "%s [global-flags] index [index-flags] build\n\n", os.Args[0])
 		fmt.Fprintln(w, "Crawl files starting at `-root` to build an index stored in `-db`")
 		fmt.Fprintln(w, "Use this subcommand to generate the initial index, then update it with `atlas index update`")
+		fmt.Fprintln(w, "Pandoc metadata is supported: headers may end with `...`, `keywords` are indexed as tags,")
+		fmt.Fprintln(w, "and authors may be given as mappings with a `name`, other author details are kept in meta")
+		fmt.Fprintln(w, "Zettel ids are read from an `id` header key or matched in filenames with `-idPattern`,")
+		fmt.Fprintln(w, "links to a zettel id resolve to the document with that id")
+		fmt.Fprintln(w, "A document's language is read from a `lang` header key, otherwise it is guessed from its contents")
+		fmt.Fprintln(w, "Headings, links, and citations within html comments (<!-- ... -->) are not indexed")
 	case "i update", "index update":
 		fmt.Fprintf(w, "%s [global-flags] index [index-flags] update\n\n", os.Args[0])
 		fmt.Fprintln(w, "Crawl files starting at `-root` to update an index stored in `-db`")
 		fmt.Fprintln(w, "Use this subcommand to update an existing index.")
 		fmt.Fprintln(w, "Deleted documents are removed from the index. To remove unused authors and tags run `atlas index tidy`")
+		fmt.Fprintln(w, "Use `-diff` to preview added (+), modified (~), and removed (-) documents before updating")
+		fmt.Fprintln(w, "Each url given with `-webhook` is POSTed the changes as JSON after the index is written,")
+		fmt.Fprintln(w, "failed deliveries are reported as warnings and do not change the exit status")
+		fmt.Fprintln(w, "  ex. {\"root\": \"/notes\", \"added\": [\"/notes/a.md\"], \"updated\": [], \"removed\": []}")
 	case "i tidy", "index tidy":
 		fmt.Fprintf(w, "%s [global-flags] index tidy\n\n", os.Args[0])
 		fmt.Fprintln(w, "Remove unused authors or tags and optimize the database")
+		fmt.Fprintln(w, "Prints the number of authors and tags removed, bytes reclaimed, and full text index segments merged")
 	case "query", "q":
 		SetupQueryFlags(nil, fs, &QueryFlags{}, "")
-		fmt.Fprintf(w, "%s [global-flags] query [query-flags] <query>...\n\n", os.Args[0])
+		fmt.Fprintf(w, "%s [global-flags] query [query-flags] <query>...\n", os.Args[0])
+		fmt.Fprintf(w, "%s [global-flags] query [query-flags] -template <query> [arg]...\n\n", os.Args[0])
 		fmt.Fprintln(w, "Execute a query against the connected database")
+		fmt.Fprintln(w, "With `-template` each argument is bound to the values $1, $2, ... of the template")
+		fmt.Fprintln(w, "Arguments are always values, so quoting or parentheses in them cannot change the query")
+		fmt.Fprintf(w, "  ex. %s query -template 'a=$1 d>$2' \"Rob Pike\" 2024-01-01\n\n", os.Args[0])
+		fmt.Fprintln(w, "With `-select` each result is reduced to the values of jq-like paths,")
+		fmt.Fprintln(w, ".key selects a field, [n] a list element counting back from the end when negative, and [\"key\"] any key")
+		fmt.Fprintln(w, "Fields are those of json output except meta, a mapping of typed header fields")
+		fmt.Fprintf(w, "  ex. %s query -select '.title, .tags[0], .meta.project' t=work\n\n", os.Args[0])
 		fmt.Fprintln(w, "Query Flags:")
 		PrintFlagSet(w, fs)
 		fmt.Fprintln(w, "\nQuery Language:")
@@ -101,7 +154,42 @@ how operators are applied to it.
 	t tags     - Set
 	h headings - String
 	l links    - Set
+	i id       - String
+	c cite     - Set
+	lang       - String
+	pinned     - Boolean
 	m meta     - String
+	m.<key>    - Field
+
+  Fields compare the typed value of a header key with = != : or /, true and false are booleans when using = or !=.
+  Unquoted numbers compare numerically and also support < <= >= >, quote a number to compare it as a string.
+  Negated fields and != also match documents without the key, nested keys are joined with dots.
+    atlas query m.draft=true -> documents with a header containing draft: true
+    atlas query "m.status:progress" -> documents whose status contains progress
+    atlas query m.project.name=atlas -> documents with a project mapping containing name: atlas
+
+  Authors may be written as "Name <email>", = or != on an author matches either its name or email.
+    atlas query a=r@golang.org -> documents by the author with the email r@golang.org, ignoring case
+
+  Tags are hierarchical, separated by /, and = or != on a tag also applies to its descendants.
+    atlas query t=work -> documents tagged work, work/project, or work/project/atlas
+
+  Languages are two letter codes such as en or de, values such as DE or en-US are matched as de and en.
+    atlas query lang=de -> documents written in German
+
+  Pinned documents are set with atlas pin and are listed before other results regardless of sorting.
+    atlas query pinned=true -> pinned documents
+
+  Saved queries are referenced with @name and expand to an 'and' clause of their statements.
+  union(@a,@b) and intersect(@a,@b) combine saved queries with 'or' and 'and'.
+    atlas saved add inbox "t=inbox -t=archived"
+    atlas query "@inbox -t=done" -> (and (and t=inbox -t=archived) -t=done)
+    atlas query "union(@inbox,@reading)" -> (or @inbox @reading)
+
+  ?= tolerates typos, matching values within -fuzzyDistance edits ignoring case.
+  Sets compare whole values while other strings match any part of their text.
+    atlas query 'a?="jon smth"' -> documents by John Smith
+    atlas query "T?=metting" -> documents with meeting in their title
 
   Operator    - Supported Types - Value
   	!=        - All             - Not Equal (Not In for Sets)
@@ -112,9 +200,14 @@ how operators are applied to it.
   	=         - All             - Equal (In for Sets)
   	: ~       - All             - Approximate (Approximately In for Sets)
   	/         - String,Set      - Regular Expression
+  	?=        - String,Set      - Typo Tolerant
 
 Values containg spaces must be surrounded in double quotes.
 Atlas recognizes many of the common date formats.
+Relative dates such as today, yesterday, "last week", "3 months ago", and weekday names are also recognized.
+Years (2024), compact dates (20240102), ISO weeks (2024-W23), quarters (2024Q2),
+and unix timestamps (@1700000000, or any unsigned integer longer than 8 digits) are also recognized,
+with equality matching any time within a year, week, or quarter.
   Example:
     atlas query date>January 1, 2025 -> error
 	atlas query date>"2025 January 1" ->  success
@@ -143,6 +236,10 @@ Atlas recognizes many of the common date formats.
 	   %h     - Str  - headings (newline separated)
        %l     - List - links
        %m     - Str  - meta
+       %i     - Str  - zettel id
+       %c     - List - citations
+       %L     - Str  - language
+       %D     - Str  - source database (only set when querying multiple databases)
 
   Examples:
     "%p %T %d tags:%t" -> '/a/path/to/document A Title 2006-01-02T15:04:05Z07:00 tags:tag1, tag2\n'
@@ -150,6 +247,98 @@ Atlas recognizes many of the common date formats.
 
 `
 		fmt.Fprint(w, outHelp)
+	case "export":
+		SetupExportFlags(nil, fs, &ExportFlags{})
+		fmt.Fprintf(w, "%s [global-flags] export [export-flags] [query]...\n\n", os.Args[0])
+		fmt.Fprintln(w, "Write documents from `-db` as newline delimited JSON, one document per line")
+		fmt.Fprintln(w, "When a query is provided only matching documents are exported")
+		fmt.Fprintf(w, "See %s help query for the query language\n\n", os.Args[0])
+		fmt.Fprintln(w, "Export Flags:")
+		PrintFlagSet(w, fs)
+	case "import":
+		fmt.Fprintf(w, "%s [global-flags] import [file]...\n\n", os.Args[0])
+		fmt.Fprintln(w, "Read newline delimited JSON documents, as written by export, into `-db`")
+		fmt.Fprintln(w, "Reads from stdin when no file or '-' is given")
+		fmt.Fprintln(w, "Existing documents are only replaced by documents with a newer filetime,")
+		fmt.Fprintln(w, "older or unchanged documents are skipped and not counted as imported")
+	case "alias":
+		fmt.Fprintf(w, "%s [global-flags] alias <subcommand>\n\n", os.Args[0])
+		fmt.Fprintln(w, "Manage aliases which map alternate spellings of an author to a single author")
+		fmt.Fprintln(w, "Documents are indexed and queried under the aliased author")
+		fmt.Fprintln(w, "\nSubcommands:")
+		fmt.Fprintln(w, "  add <author> <alias>...  - alias each alias to author")
+		fmt.Fprintln(w, "  remove <alias>...        - remove aliases")
+		fmt.Fprintln(w, "  list                     - list aliases")
+	case "saved":
+		fmt.Fprintf(w, "%s [global-flags] saved <subcommand>\n\n", os.Args[0])
+		fmt.Fprintln(w, "Manage named queries which can be referenced as @name from other queries")
+		fmt.Fprintln(w, "References are expanded when a query is parsed, so later changes to a saved query apply everywhere")
+		fmt.Fprintf(w, "See %s help query for the query language\n", os.Args[0])
+		fmt.Fprintln(w, "\nSubcommands:")
+		fmt.Fprintln(w, "  add <name> <query>...  - save a query as name, replacing any query saved with that name")
+		fmt.Fprintln(w, "  remove <name>...       - remove saved queries")
+		fmt.Fprintln(w, "  list                   - list saved queries")
+	case "grep":
+		SetupGrepFlags(nil, fs, &GrepFlags{})
+		fmt.Fprintf(w, "%s [global-flags] grep [grep-flags] <pattern> [query]...\n\n", os.Args[0])
+		fmt.Fprintln(w, "Search the contents of documents matching a query for a regular expression")
+		fmt.Fprintln(w, "Matches are printed as path:line:match, all documents are searched when no query is given")
+		fmt.Fprintln(w, "Exits with 0 when a line matched, 1 when none did, and 2 on errors")
+		fmt.Fprintf(w, "See %s help query for the query language\n\n", os.Args[0])
+		fmt.Fprintln(w, "Grep Flags:")
+		PrintFlagSet(w, fs)
+	case "links":
+		SetupLinksFlags(nil, fs, &LinksFlags{})
+		fmt.Fprintf(w, "%s [global-flags] links [links-flags] [subcommand]\n\n", os.Args[0])
+		fmt.Fprintln(w, "Report on links between indexed documents, all reports are printed when no subcommand is given")
+		fmt.Fprintln(w, "Links without an extension also match markdown documents")
+		fmt.Fprintln(w, "\nSubcommands:")
+		fmt.Fprintln(w, "  broken  - links to files which are not indexed and don't exist")
+		fmt.Fprintln(w, "  orphans - documents without any inbound links")
+		fmt.Fprintln(w, "  top     - documents with the most inbound links")
+		fmt.Fprintln(w, "\nLinks Flags:")
+		PrintFlagSet(w, fs)
+	case "pin":
+		SetupPinFlags(nil, fs, &PinFlags{})
+		fmt.Fprintf(w, "%s [global-flags] pin [pin-flags] [path]...\n\n", os.Args[0])
+		fmt.Fprintln(w, "Pin indexed documents so they are listed before other query results")
+		fmt.Fprintln(w, "Pins are stored in `-db` rather than the document and are lost when the index is rebuilt")
+		fmt.Fprintln(w, "Pinned documents are listed when no paths are given")
+		fmt.Fprintln(w, "\nPin Flags:")
+		PrintFlagSet(w, fs)
+	case "diff":
+		SetupDiffFlags(nil, fs, &DiffFlags{})
+		fmt.Fprintf(w, "%s [global-flags] diff [diff-flags] <query A> <query B>\n\n", os.Args[0])
+		fmt.Fprintln(w, "Run two queries and compare the paths of their results")
+		fmt.Fprintln(w, "  ex. atlas diff t=project t=projects -> audit a tag migration")
+		fmt.Fprintf(w, "See %s help query for the query language\n", os.Args[0])
+		fmt.Fprintln(w, "\nModes:")
+		fmt.Fprintln(w, "  diff      - documents only in A, only in B, and in both")
+		fmt.Fprintln(w, "  union     - documents in either A or B")
+		fmt.Fprintln(w, "  intersect - documents in both A and B")
+		fmt.Fprintln(w, "\nDiff Flags:")
+		PrintFlagSet(w, fs)
+	case "publish":
+		SetupPublishFlags(nil, fs, &PublishFlags{})
+		fmt.Fprintf(w, "%s [global-flags] publish [publish-flags] -out <directory>\n\n", os.Args[0])
+		fmt.Fprintln(w, "Render documents matching `-query` and index pages into a static site")
+		fmt.Fprintln(w, "  ex. atlas publish -query t=blog -template theme/ -out site/")
+		fmt.Fprintln(w, "Each document is written to the path of its file under `-root` with an .html extension,")
+		fmt.Fprintln(w, "index.html lists every document and tags/<tag>.html lists the documents with a tag")
+		fmt.Fprintln(w, "Nothing is published when two documents would be written to the same page,")
+		fmt.Fprintln(w, "or a document would be written to index.html or under tags/")
+		fmt.Fprintln(w, "\nTemplates:")
+		fmt.Fprintln(w, "  `-template` is a directory of html/template files, other files in it are copied to the site")
+		fmt.Fprintln(w, "  an output directory inside the template directory is not copied")
+		fmt.Fprintln(w, "  document.html - a document page, index.html - index and tag pages")
+		fmt.Fprintln(w, "  Missing templates use the defaults, other *.html files may define shared templates")
+		fmt.Fprintln(w, "  Fields: .Title .Root .Document .Documents .Tag .Tags")
+		fmt.Fprintln(w, "    documents have the fields of a query result with .File, .URL and .Content")
+		fmt.Fprintln(w, "    .File is the page's location and .URL is its escaped link")
+		fmt.Fprintln(w, "    links should be prefixed with .Root, the relative path to the site root")
+		fmt.Fprintln(w, "  Functions: tagURL <tag> - escaped link to a tag's index page")
+		fmt.Fprintln(w, "\nPublish Flags:")
+		PrintFlagSet(w, fs)
 	case "shell":
 		fmt.Fprintf(w, "%s [global-flags] shell\n", os.Args[0])
 		fmt.Fprintln(w, "Simple shell for debugging queries")
@@ -163,10 +352,26 @@ Atlas recognizes many of the common date formats.
 		fmt.Fprintln(w, "  To execute a query POST it in the request body to /search")
 		fmt.Fprintln(w, "  ex. curl -d 'T:notes d>=\"January 1, 2025\"' 127.0.0.1:8080/search")
 		fmt.Fprintln(w, "  To have the backend use the query params `sortBy` and `sortOrder`")
-		fmt.Fprintln(w, "    sortBy: path, title, date, filetime, meta")
+		fmt.Fprintln(w, "    sortBy: path, id, title, lang, date, filetime, meta, comma separate fields to break ties")
 		fmt.Fprintln(w, "    sortOrder: desc, descending")
+		fmt.Fprintln(w, "Maintenance:")
+		fmt.Fprintln(w, "  The database is optimized and tidied on the schedules set by `-optimize` and `-tidy`")
+		fmt.Fprintln(w, "  Schedules are cron expressions (minute hour day-of-month month day-of-week),")
+		fmt.Fprintln(w, "  @hourly, @daily, @weekly, @monthly, @yearly, or @every <duration>")
+		fmt.Fprintln(w, "  ex. -tidy '30 3 * * 0' -> tidy at 3:30 every Sunday")
 		fmt.Fprintln(w, "Server Flags:")
 		PrintFlagSet(w, fs)
+	case "rpc":
+		fmt.Fprintf(w, "%s [global-flags] rpc\n", os.Args[0])
+		fmt.Fprintln(w, "Serve JSON-RPC 2.0 requests for editor integrations")
+		fmt.Fprintln(w, "Each request is read from stdin as a single line of JSON and each response is written as a line to stdout")
+		fmt.Fprintln(w, "  ex. {\"jsonrpc\": \"2.0\", \"id\": 1, \"method\": \"search\", \"params\": {\"query\": \"T:notes\"}}")
+		fmt.Fprintln(w, "\nMethods:")
+		fmt.Fprintln(w, "  search      - {query, sortBy, sortDesc, limit, offset} returns matching documents")
+		fmt.Fprintln(w, "  getDocument - {path} returns a single document")
+		fmt.Fprintln(w, "  tags        - returns every tag with its document count")
+		fmt.Fprintln(w, "  reindex     - update the index from -root, returns counts of crawled, filtered, and parsed files")
+		fmt.Fprintln(w, "                notifies each `-webhook` url of the changes")
 	case "help", "":
 		PrintHelp(w)
 		fmt.Fprintln(w, "\nHelp Topics:")
@@ -191,7 +396,7 @@ Atlas recognizes many of the common date formats.
 		PrintGlobalFlags(w)
 	default:
 		fmt.Fprintln(os.Stderr, "Unrecognized topic: ", topic)
-		if suggestion, ok := util.Nearest(topic, helpTopics, util.LevensteinDistance, 3); ok {
+		if suggestion, ok := util.Nearest(topic, helpTopics, util.FoldedDistance, 3); ok {
 			fmt.Fprintf(w, "Did you mean %s?\n", suggestion)
 		}
 		fmt.Fprintln(w, "See `atlas help`")
@@ -1,25 +1,101 @@
 package cmd
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
+	"maps"
 	"os"
+	"regexp"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/jpappel/atlas/pkg/data"
 	"github.com/jpappel/atlas/pkg/index"
+	"github.com/jpappel/atlas/pkg/server"
 )
 
 type IndexFlags struct {
 	Filters    []index.DocFilter
 	Subcommand string
+	Diff       bool
+	Yes        bool
+	Progress   string
+	Traversal  string
+	Schema     *index.Schema
+	Strict     bool
 	index.ParseOpts
 }
 
+// Progress of an indexing stage, emitted as a line of JSON with -progress json
+type progressEvent struct {
+	Stage     string `json:"stage"`
+	Processed int    `json:"processed"`
+	Total     int    `json:"total"`
+	Errors    uint64 `json:"errors"`
+	Error     string `json:"error,omitempty"` // reason the stage failed
+}
+
+// Minimum time between throttled progress events of a stage
+const progressInterval = 100 * time.Millisecond
+
+// Writes progress events as lines of JSON
+type progressReporter struct {
+	mu       sync.Mutex
+	enc      *json.Encoder
+	last     map[string]progressEvent // last event emitted for each stage
+	lastTime map[string]time.Time
+}
+
+func newProgressReporter(w io.Writer) *progressReporter {
+	return &progressReporter{
+		enc:      json.NewEncoder(w),
+		last:     make(map[string]progressEvent),
+		lastTime: make(map[string]time.Time),
+	}
+}
+
+// Emit an event when a stage starts, completes, or progressInterval has passed
+// since its last event. Events behind the last emitted for a stage are dropped.
+func (r *progressReporter) Update(stage string, processed int, total int, errors uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if last, ok := r.last[stage]; ok {
+		if processed <= last.Processed {
+			return
+		} else if time.Since(r.lastTime[stage]) < progressInterval && (total == 0 || processed < total) {
+			return
+		}
+	}
+	r.emit(progressEvent{Stage: stage, Processed: processed, Total: total, Errors: errors})
+}
+
+// Emit an event regardless of throttling, unless it repeats the last event of its stage
+func (r *progressReporter) Emit(ev progressEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if last, ok := r.last[ev.Stage]; ok && last == ev {
+		return
+	}
+	r.emit(ev)
+}
+
+func (r *progressReporter) emit(ev progressEvent) {
+	r.enc.Encode(ev)
+	r.last[ev.Stage] = ev
+	r.lastTime[ev.Stage] = time.Now()
+}
+
 func SetupIndexFlags(args []string, fs *flag.FlagSet, flags *IndexFlags) {
 	flags.ParseLinks = true
+	flags.ParseCitations = true
+	flags.DetectLanguage = true
 	flags.ParseMeta = true
 	flags.ParseHeadings = true
 	fs.BoolVar(&flags.IgnoreDateError, "ignoreBadDates", false, "ignore malformed dates while indexing")
@@ -36,7 +112,60 @@ func SetupIndexFlags(args []string, fs *flag.FlagSet, flags *IndexFlags) {
 		flags.ParseLinks = false
 		return nil
 	})
+	fs.BoolFunc("ignoreCitations", "don't parse file contents for citations", func(s string) error {
+		flags.ParseCitations = false
+		return nil
+	})
+	fs.BoolFunc("ignoreLanguage", "don't detect the language of documents without a lang header", func(s string) error {
+		flags.DetectLanguage = false
+		return nil
+	})
 	fs.BoolVar(&flags.IgnoreHidden, "ignoreHidden", false, "ignore hidden files while crawling")
+	flags.IDPattern = index.DefaultIDPattern
+	fs.Func("idPattern", "`regex` matching zettel ids in filenames, uses the group named id if present\n(default 12 or 14 digit timestamps, empty to disable)", func(s string) error {
+		if s == "" {
+			flags.IDPattern = nil
+			return nil
+		}
+		pattern, err := regexp.Compile(s)
+		if err != nil {
+			return err
+		}
+		flags.IDPattern = pattern
+		return nil
+	})
+	fs.Func("validate", "check headers against the schema in `file` and report nonconforming documents", func(s string) error {
+		schema, err := index.LoadSchema(s)
+		if err != nil {
+			return err
+		}
+		flags.Schema = schema
+		return nil
+	})
+	fs.BoolVar(&flags.Strict, "strict", false, "fail indexing when documents do not conform to the -validate schema")
+	fs.BoolVar(&flags.Diff, "diff", false, "print added, modified, and removed documents and confirm before updating")
+	fs.BoolVar(&flags.Yes, "yes", false, "skip confirmation when using -diff")
+	flags.Progress = "text"
+	fs.Func("progress", "progress `format` (text, json), json writes events to stderr (default text)", func(s string) error {
+		switch s {
+		case "text", "json":
+			flags.Progress = s
+			return nil
+		default:
+			return fmt.Errorf("Unrecognized progress format: %s", s)
+		}
+	})
+
+	flags.Traversal = "parallel"
+	fs.Func("traversal", "crawling `strategy` (parallel, walk), walk reads one directory at a time\nand parses while crawling (default parallel)", func(s string) error {
+		switch s {
+		case "parallel", "walk":
+			flags.Traversal = s
+			return nil
+		default:
+			return fmt.Errorf("Unrecognized traversal strategy: %s", s)
+		}
+	})
 
 	customFilters := false
 	flags.Filters = index.DefaultFilters()
@@ -69,8 +198,13 @@ func SetupIndexFlags(args []string, fs *flag.FlagSet, flags *IndexFlags) {
 	remainingArgs := fs.Args()
 	if len(remainingArgs) == 0 {
 		flags.Subcommand = "build"
-	} else if len(remainingArgs) == 1 {
+	} else {
+		// allow flags after the subcommand
 		flags.Subcommand = remainingArgs[0]
+		fs.Parse(remainingArgs[1:])
+		if fs.NArg() > 0 {
+			flags.Subcommand = ""
+		}
 	}
 }
 
@@ -90,15 +224,44 @@ func RunIndex(gFlags GlobalFlags, iFlags IndexFlags, db *data.Query) byte {
 			)
 		}
 
-		traversedFiles := idx.Traverse(gFlags.NumWorkers, iFlags.IgnoreHidden)
-		fmt.Print("Crawled ", len(traversedFiles))
-
-		filteredFiles := idx.Filter(traversedFiles, gFlags.NumWorkers)
-		fmt.Print(", Filtered ", len(filteredFiles))
+		var progress *progressReporter
+		if iFlags.Progress == "json" {
+			progress = newProgressReporter(os.Stderr)
+			idx.Progress = progress.Update
+		}
+		report := func(stage string, processed, total int, errCnt uint64) {
+			if progress != nil {
+				progress.Emit(progressEvent{Stage: stage, Processed: processed, Total: total, Errors: errCnt})
+			}
+		}
 
 		var errCnt uint64
-		idx.Documents, errCnt = index.ParseDocs(filteredFiles, gFlags.NumWorkers, iFlags.ParseOpts)
-		fmt.Print(", Parsed ", len(idx.Documents), "\n")
+		if iFlags.Traversal == "walk" {
+			var stats index.WalkStats
+			var err error
+			idx.Documents, stats, err = idx.Walk(gFlags.NumWorkers, iFlags.IgnoreHidden, iFlags.ParseOpts)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error walking index root:", err)
+				return 1
+			}
+			errCnt = stats.Errors
+			report("crawl", stats.Crawled, stats.Crawled, 0)
+			report("filter", stats.Crawled, stats.Crawled, 0)
+			report("parse", stats.Filtered, stats.Filtered, errCnt)
+			fmt.Print("Crawled ", stats.Crawled, ", Filtered ", stats.Filtered, ", Parsed ", len(idx.Documents), "\n")
+		} else {
+			traversedFiles := idx.Traverse(gFlags.NumWorkers, iFlags.IgnoreHidden)
+			report("crawl", len(traversedFiles), len(traversedFiles), 0)
+			fmt.Print("Crawled ", len(traversedFiles))
+
+			filteredFiles := idx.Filter(traversedFiles, gFlags.NumWorkers)
+			report("filter", len(traversedFiles), len(traversedFiles), 0)
+			fmt.Print(", Filtered ", len(filteredFiles))
+
+			idx.Documents, errCnt = idx.ParseDocs(filteredFiles, gFlags.NumWorkers, iFlags.ParseOpts)
+			report("parse", len(filteredFiles), len(filteredFiles), errCnt)
+			fmt.Print(", Parsed ", len(idx.Documents), "\n")
+		}
 		if errCnt > 0 {
 			fmt.Printf("Encountered %d document parse errors", errCnt)
 			if !slog.Default().Enabled(context.Background(), slog.LevelWarn) {
@@ -107,23 +270,84 @@ func RunIndex(gFlags GlobalFlags, iFlags IndexFlags, db *data.Query) byte {
 			fmt.Println()
 		}
 
+		if iFlags.Schema != nil && !validateIndex(os.Stderr, *iFlags.Schema, idx) && iFlags.Strict {
+			return 1
+		}
+
+		ctx, cancel := gFlags.Context()
+		defer cancel()
+
+		// documents as they would be stored, for comparison with prev
+		var prev *index.Index
+		var next index.Index
+		if (iFlags.Diff && iFlags.Subcommand == "update") || len(gFlags.Webhooks) > 0 {
+			var err error
+			prev, err = db.Get(ctx, gFlags.IndexRoot)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error reading index:", err)
+				return 1
+			}
+			next, err = db.ResolveAliases(ctx, idx)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error reading aliases:", err)
+				return 1
+			}
+		}
+
+		if iFlags.Diff && iFlags.Subcommand == "update" {
+			diff := prev.Diff(next)
+			if diff.Empty() {
+				fmt.Println("No changes")
+				return 0
+			}
+			printDiff(os.Stdout, diff)
+			if !iFlags.Yes && !confirm("Apply changes?") {
+				fmt.Println("Aborted")
+				return 0
+			}
+			// the timeout does not include waiting for confirmation
+			cancel()
+			ctx, cancel = gFlags.Context()
+			defer cancel()
+		}
+
 		var err error
 		// switch in order to appease gopls...
 		switch iFlags.Subcommand {
 		case "build":
-			err = db.Put(context.Background(), idx)
+			err = db.Put(ctx, idx)
 		case "update":
-			err = db.Update(context.Background(), idx)
+			err = db.Update(ctx, idx)
 		}
 		if err != nil {
+			if progress != nil {
+				progress.Emit(progressEvent{Stage: "write", Total: len(idx.Documents), Error: err.Error()})
+			}
 			fmt.Fprintln(os.Stderr, "Error modifying index:", err)
 			return 1
 		}
+		report("write", len(idx.Documents), len(idx.Documents), 0)
+
+		// the index is written regardless of whether webhooks are delivered
+		if prev != nil && len(gFlags.Webhooks) > 0 {
+			if diff := prev.Diff(next); !diff.Empty() {
+				payload := server.NewWebhookPayload(gFlags.IndexRoot, diff)
+				if err := server.NotifyWebhooks(ctx, gFlags.Webhooks, payload); err != nil {
+					fmt.Fprintln(os.Stderr, "Warning: index was written but notifying webhooks failed:", err)
+				}
+			}
+		}
 	case "tidy":
-		if err := db.Tidy(); err != nil {
+		ctx, cancel := gFlags.Context()
+		defer cancel()
+
+		report, err := db.Tidy(ctx)
+		if err != nil {
 			fmt.Fprintln(os.Stderr, "Error while tidying:", err)
 			return 1
 		}
+		fmt.Printf("Removed %d authors and %d tags, reclaimed %d bytes, merged %d index segments\n",
+			report.AuthorsRemoved, report.TagsRemoved, report.BytesReclaimed, report.SegmentsMerged)
 	default:
 		fmt.Fprintln(os.Stderr, "Unrecognized index subcommands: ", iFlags.Subcommand)
 		return 2
@@ -131,3 +355,60 @@ func RunIndex(gFlags GlobalFlags, iFlags IndexFlags, db *data.Query) byte {
 
 	return 0
 }
+
+// Report documents which do not conform to schema, returns true when all conform
+func validateIndex(w io.Writer, schema index.Schema, idx index.Index) bool {
+	nonconforming := 0
+	for _, path := range slices.Sorted(maps.Keys(idx.Documents)) {
+		errs, err := schema.Validate(path)
+		if err != nil {
+			fmt.Fprintln(w, "Error validating document:", err)
+			nonconforming++
+			continue
+		}
+		for _, err := range errs {
+			fmt.Fprintln(w, err)
+		}
+		if len(errs) > 0 {
+			nonconforming++
+		}
+	}
+
+	if nonconforming > 0 {
+		fmt.Fprintf(w, "%d of %d documents do not conform to the schema\n", nonconforming, len(idx.Documents))
+		return false
+	}
+	return true
+}
+
+func printDiff(w io.Writer, diff index.Diff) {
+	for _, path := range diff.Added {
+		fmt.Fprintln(w, "+", path)
+	}
+	for _, path := range diff.ModifiedPaths() {
+		fmt.Fprintf(w, "~ %s (%s)\n", path, strings.Join(diff.Modified[path], ", "))
+	}
+	for _, path := range diff.Removed {
+		fmt.Fprintln(w, "-", path)
+	}
+	fmt.Fprintf(w, "%d added, %d modified, %d removed\n",
+		len(diff.Added), len(diff.Modified), len(diff.Removed),
+	)
+}
+
+// Prompt on stdout and read a yes or no answer from stdin, defaults to no
+func confirm(prompt string) bool {
+	fmt.Print(prompt, " [y/N] ")
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Println()
+		return false
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,68 @@
+package cmd_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jpappel/atlas/cmd"
+	"github.com/jpappel/atlas/pkg/index"
+)
+
+func TestRunIndex_Validate(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"a.md": "---\ntitle: Alpha\n---\nbody\n",
+		"b.md": "---\nauthor: someone\n---\nbody\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	schemaPath := filepath.Join(t.TempDir(), "schema.yaml")
+	if err := os.WriteFile(schemaPath, []byte("required: [title]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	schema, err := index.LoadSchema(schemaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gFlags := cmd.GlobalFlags{IndexRoot: root, NumWorkers: 1}
+
+	tests := []struct {
+		name     string
+		strict   bool
+		wantCode byte
+		wantDocs int
+	}{
+		{"report only", false, 0, 2},
+		{"strict", true, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			iFlags := cmd.IndexFlags{
+				Subcommand: "build",
+				Progress:   "text",
+				Traversal:  "parallel",
+				Schema:     schema,
+				Strict:     tt.strict,
+			}
+			_, code := captureStdout(t, func() byte {
+				return cmd.RunIndex(gFlags, iFlags, db)
+			})
+			if code != tt.wantCode {
+				t.Errorf("RunIndex() = %d, want %d", code, tt.wantCode)
+			}
+
+			idx, err := db.Get(t.Context(), root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(idx.Documents) != tt.wantDocs {
+				t.Errorf("Indexed %d documents, want %d", len(idx.Documents), tt.wantDocs)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jpappel/atlas/pkg/data"
+)
+
+type LinksFlags struct {
+	Subcommand string
+	Top        int
+}
+
+func SetupLinksFlags(args []string, fs *flag.FlagSet, flags *LinksFlags) {
+	fs.IntVar(&flags.Top, "n", 10, "`number` of most linked documents to show, 0 for all")
+
+	fs.Usage = func() {
+		f := fs.Output()
+		Help("links", f)
+		PrintGlobalFlags(f)
+	}
+
+	fs.Parse(args)
+
+	flags.Subcommand = fs.Arg(0)
+}
+
+// Report broken links, orphaned documents, and the most linked documents
+func RunLinks(gFlags GlobalFlags, lFlags LinksFlags, db *data.Query) byte {
+	ctx, cancel := gFlags.Context()
+	defer cancel()
+
+	idx, err := db.Get(ctx, gFlags.IndexRoot)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to read index:", err)
+		return 1
+	}
+	report := idx.LinkReport()
+
+	all := lFlags.Subcommand == ""
+	switch lFlags.Subcommand {
+	case "", "broken", "orphans", "top":
+	default:
+		fmt.Fprintln(os.Stderr, "Unrecognized links subcommand: ", lFlags.Subcommand)
+		return 2
+	}
+
+	if all || lFlags.Subcommand == "broken" {
+		if all {
+			fmt.Printf("Broken links (%d):\n", len(report.Broken))
+		}
+		for _, broken := range report.Broken {
+			fmt.Printf("%s -> %s\n", broken.Path, broken.Link)
+		}
+	}
+	if all || lFlags.Subcommand == "orphans" {
+		if all {
+			fmt.Printf("\nOrphans (%d):\n", len(report.Orphans))
+		}
+		for _, path := range report.Orphans {
+			fmt.Println(path)
+		}
+	}
+	if all || lFlags.Subcommand == "top" {
+		if all {
+			fmt.Println("\nMost linked:")
+		}
+		mostLinked := report.MostLinked
+		if lFlags.Top > 0 && lFlags.Top < len(mostLinked) {
+			mostLinked = mostLinked[:lFlags.Top]
+		}
+		for _, linked := range mostLinked {
+			fmt.Printf("%d %s\n", linked.Count, linked.Path)
+		}
+	}
+
+	return 0
+}
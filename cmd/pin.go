@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jpappel/atlas/pkg/data"
+)
+
+type PinFlags struct {
+	Remove bool
+}
+
+func SetupPinFlags(args []string, fs *flag.FlagSet, flags *PinFlags) {
+	fs.BoolVar(&flags.Remove, "remove", false, "unpin documents instead of pinning them")
+
+	fs.Usage = func() {
+		f := fs.Output()
+		Help("pin", f)
+		PrintGlobalFlags(f)
+	}
+
+	fs.Parse(args)
+}
+
+// Pin documents by path, or list pinned documents when no paths are given
+func RunPin(gFlags GlobalFlags, pFlags PinFlags, db *data.Query, paths []string) byte {
+	ctx, cancel := gFlags.Context()
+	defer cancel()
+
+	if len(paths) == 0 {
+		pinned, err := db.Pinned(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error while listing pinned documents:", err)
+			return 1
+		}
+		for _, path := range pinned {
+			fmt.Println(path)
+		}
+		return 0
+	}
+
+	absPaths := make([]string, len(paths))
+	for i, path := range paths {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot resolve path `%s`: %s\n", path, err)
+			return 1
+		}
+		absPaths[i] = absPath
+	}
+
+	if err := db.SetPinned(ctx, !pFlags.Remove, absPaths...); err != nil {
+		fmt.Fprintln(os.Stderr, "Error while pinning documents:", err)
+		return 1
+	}
+
+	return 0
+}
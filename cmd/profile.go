@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// Start any profiles requested by global flags.
+// The returned function stops them and writes the memory profile.
+func StartProfiling(gFlags GlobalFlags) (func() error, error) {
+	var cpuFile, traceFile *os.File
+
+	stop := func() error {
+		if cpuFile != nil {
+			pprof.StopCPUProfile()
+			cpuFile.Close()
+		}
+		if traceFile != nil {
+			trace.Stop()
+			traceFile.Close()
+		}
+		if gFlags.MemProfile != "" {
+			f, err := os.Create(gFlags.MemProfile)
+			if err != nil {
+				return fmt.Errorf("Cannot create memory profile: %w", err)
+			}
+			defer f.Close()
+
+			runtime.GC()
+			if err := pprof.Lookup("allocs").WriteTo(f, 0); err != nil {
+				return fmt.Errorf("Cannot write memory profile: %w", err)
+			}
+		}
+		return nil
+	}
+
+	if gFlags.CPUProfile != "" {
+		f, err := os.Create(gFlags.CPUProfile)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot create cpu profile: %w", err)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("Cannot start cpu profile: %w", err)
+		}
+		cpuFile = f
+	}
+
+	if gFlags.Trace != "" {
+		f, err := os.Create(gFlags.Trace)
+		if err != nil {
+			stop()
+			return nil, fmt.Errorf("Cannot create trace: %w", err)
+		}
+		if err := trace.Start(f); err != nil {
+			f.Close()
+			stop()
+			return nil, fmt.Errorf("Cannot start trace: %w", err)
+		}
+		traceFile = f
+	}
+
+	return stop, nil
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jpappel/atlas/pkg/data"
+	"github.com/jpappel/atlas/pkg/index"
+	"github.com/jpappel/atlas/pkg/publish"
+)
+
+type PublishFlags struct {
+	Query             string
+	Template          string
+	Output            string
+	OptimizationLevel int
+}
+
+func SetupPublishFlags(args []string, fs *flag.FlagSet, flags *PublishFlags) {
+	fs.StringVar(&flags.Query, "query", "", "publish documents matching `query`, empty for all documents")
+	fs.StringVar(&flags.Template, "template", "", "`directory` with document.html and index.html templates and static files, empty for the defaults")
+	fs.StringVar(&flags.Output, "out", "", "`directory` to write the site to")
+	fs.IntVar(&flags.OptimizationLevel, "optLevel", 0, "optimization `level` for queries, 0 is automatic, <0 to disable")
+
+	fs.Usage = func() {
+		f := fs.Output()
+		Help("publish", f)
+		PrintGlobalFlags(f)
+	}
+
+	fs.Parse(args)
+}
+
+// Render documents and index pages through templates into a static site
+func RunPublish(gFlags GlobalFlags, pFlags PublishFlags, db *data.Query) byte {
+	if pFlags.Output == "" {
+		fmt.Fprintln(os.Stderr, "No output directory provided")
+		return 2
+	}
+
+	tmpl, err := publish.ParseTemplates(pFlags.Template)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Cannot parse templates:", err)
+		return 1
+	}
+
+	ctx, cancel := gFlags.Context()
+	defer cancel()
+
+	var docs map[string]*index.Document
+	if pFlags.Query != "" {
+		artifact, err := db.Compile(ctx, pFlags.Query, pFlags.OptimizationLevel, gFlags.NumWorkers)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to compile query: ", err)
+			return 1
+		}
+		docs, err = db.Execute(ctx, artifact)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to execute query: ", err)
+			return 1
+		}
+	} else {
+		idx, err := db.Get(ctx, gFlags.IndexRoot)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to read index: ", err)
+			return 1
+		}
+		docs = idx.Documents
+	}
+
+	site, err := publish.NewSite(gFlags.IndexRoot, docs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Cannot publish documents:", err)
+		return 1
+	}
+	if pFlags.Template != "" {
+		if err := publish.CopyStaticFiles(pFlags.Template, pFlags.Output); err != nil {
+			fmt.Fprintln(os.Stderr, "Cannot copy static files:", err)
+			return 1
+		}
+	}
+
+	if err := site.Write(tmpl, pFlags.Output); err != nil {
+		fmt.Fprintln(os.Stderr, "Cannot publish site:", err)
+		return 1
+	}
+
+	fmt.Printf("Published %d documents and %d tags to %s\n", len(site.Documents), len(site.Tagged), pFlags.Output)
+
+	return 0
+}
@@ -1,11 +1,18 @@
 package cmd
 
 import (
+	"bufio"
+	"cmp"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"slices"
+	"strings"
+	"sync"
+	"syscall"
 
 	"github.com/jpappel/atlas/pkg/data"
 	"github.com/jpappel/atlas/pkg/index"
@@ -20,6 +27,12 @@ type QueryFlags struct {
 	OptimizationLevel int
 	SortBy            string
 	SortDesc          bool
+	Limit             int
+	Offset            int
+	Exec              string
+	ExecBatch         string
+	Template          string
+	Selection         *query.Selection
 }
 
 func SetupQueryFlags(args []string, fs *flag.FlagSet, flags *QueryFlags, dateFormat string) {
@@ -48,8 +61,30 @@ func SetupQueryFlags(args []string, fs *flag.FlagSet, flags *QueryFlags, dateFor
 			}
 		})
 
-	fs.StringVar(&flags.SortBy, "sortBy", "", "category to sort by (path,title,date,filetime,meta)")
+	fs.Func("fields", "comma separated `fields` to output delimited by tabs, shortcut for -outFormat custom\n(path,id,title,lang,date,filetime,authors,tags,headings,links,citations,meta,database)",
+		func(arg string) error {
+			var err error
+			flags.Outputer, err = query.NewFieldsOutput(strings.Split(arg, ","), "\t", dateFormat, "\n", flags.ListSeparator)
+			return err
+		})
+
+	fs.Func("select", "comma separated `paths` to output such as .title,.tags[0],.meta.project\ndelimited by tabs, or as objects with -outFormat json",
+		func(arg string) error {
+			sel, err := query.ParseSelection(arg)
+			if err != nil {
+				return err
+			}
+			flags.Selection = &sel
+			return nil
+		})
+
+	fs.StringVar(&flags.SortBy, "sortBy", "", "comma separated `fields` to sort by, later fields break ties (path,id,title,lang,date,filetime,meta)")
 	fs.BoolVar(&flags.SortDesc, "sortDesc", false, "sort in descending order")
+	fs.IntVar(&flags.Limit, "limit", 0, "maximum `number` of results, 0 for no limit")
+	fs.IntVar(&flags.Offset, "offset", 0, "`number` of results to skip, best used with -sortBy")
+	fs.StringVar(&flags.Exec, "exec", "", "run `command` for each result instead of printing, {} is replaced by the result path")
+	fs.StringVar(&flags.ExecBatch, "execBatch", "", "run `command` with many result paths at once, in place of {} or appended")
+	fs.StringVar(&flags.Template, "template", "", "`query` whose values $1, $2, ... are bound to the positional arguments")
 	fs.StringVar(&flags.CustomFormat, "outCustomFormat", query.DefaultOutputFormat, "`format` string for --outFormat custom, see `atlas help query` for more details")
 	fs.IntVar(&flags.OptimizationLevel, "optLevel", 0, "optimization `level` for queries, 0 is automatic, <0 to disable")
 	fs.StringVar(&flags.DocumentSeparator, "docSeparator", "\n", "separator for custom output format")
@@ -57,21 +92,119 @@ func SetupQueryFlags(args []string, fs *flag.FlagSet, flags *QueryFlags, dateFor
 
 	fs.Usage = func() {
 		w := fs.Output()
-		fmt.Fprintf(w, "%s [global-flags] query [query-flags] <query>...\n\n", os.Args[0])
+		fmt.Fprintf(w, "%s [global-flags] query [query-flags] <query>...\n", os.Args[0])
+		fmt.Fprintf(w, "%s [global-flags] query [query-flags] -template <query> [arg]...\n\n", os.Args[0])
 		fmt.Fprintln(w, "Query Flags:")
 		PrintFlagSet(w, fs)
 		PrintGlobalFlags(w)
 	}
 
 	fs.Parse(args)
+
+	if flags.Selection != nil {
+		_, asJson := flags.Outputer.(query.JsonOutput)
+		flags.Outputer = query.NewSelectOutput(*flags.Selection, asJson, dateFormat, flags.ListSeparator)
+	}
 }
 
-func RunQuery(gFlags GlobalFlags, qFlags QueryFlags, db *data.Query, searchQuery string) byte {
-	tokens := query.Lex(searchQuery)
-	clause, err := query.Parse(tokens)
-	if err != nil {
-		fmt.Fprintln(os.Stderr, "Failed to parse query: ", err)
-		return 1
+// Execute an artifact against each database concurrently and merge the results.
+// When more than one database is used, documents are annotated with their source
+// and the artifact's limit and offset are applied after merging.
+func executeMany(ctx context.Context, paths []string, dbs []*data.Query, artifact query.CompilationArtifact) ([]*index.Document, error) {
+	results := make([]map[string]*index.Document, len(dbs))
+	errs := make([]error, len(dbs))
+
+	limit, offset := artifact.Limit, artifact.Offset
+	if len(dbs) > 1 && (limit > 0 || offset > 0) {
+		// each database may contribute any of the first limit+offset results
+		if limit > 0 {
+			artifact.Limit = limit + offset
+		}
+		artifact.Offset = 0
+	}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(dbs))
+	for i, db := range dbs {
+		go func(i int, db *data.Query) {
+			defer wg.Done()
+			results[i], errs[i] = db.Execute(ctx, artifact)
+			if errs[i] != nil && len(dbs) > 1 {
+				errs[i] = fmt.Errorf("%s: %w", paths[i], errs[i])
+			}
+		}(i, db)
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	n := 0
+	for _, pathDocs := range results {
+		n += len(pathDocs)
+	}
+
+	docs := make([]*index.Document, 0, n)
+	for i, pathDocs := range results {
+		for _, doc := range pathDocs {
+			if len(dbs) > 1 {
+				doc.Database = paths[i]
+			}
+			docs = append(docs, doc)
+		}
+	}
+
+	if len(dbs) > 1 && (limit > 0 || offset > 0) {
+		// pinned documents from any database are kept before the cut, like a single database
+		slices.SortFunc(docs, resultCmp(artifact.SortBy, artifact.SortDesc))
+		docs = docs[min(offset, len(docs)):]
+		if limit > 0 {
+			docs = docs[:min(limit, len(docs))]
+		}
+	}
+
+	return docs, nil
+}
+
+// Order results by sortBy with pinned documents first. Remaining ties are
+// broken by path and database so the order does not depend on which database
+// finished first.
+func resultCmp(sortBy string, desc bool) func(a, b *index.Document) int {
+	docCmp, ok := index.NewDocCmp(sortBy, desc)
+	if !ok {
+		docCmp = index.PinnedCmp
+	}
+	return func(a, b *index.Document) int {
+		return cmp.Or(docCmp(a, b), strings.Compare(a.Path, b.Path), strings.Compare(a.Database, b.Database))
+	}
+}
+
+// Run a query joined from args, or bind args to -template
+func RunQuery(gFlags GlobalFlags, qFlags QueryFlags, dbs []*data.Query, args []string) byte {
+	ctx, cancel := gFlags.Context()
+	defer cancel()
+
+	var clause *query.Clause
+	if qFlags.Template != "" {
+		tmpl, err := query.NewTemplate(qFlags.Template)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid template: ", err)
+			return 1
+		}
+		clause, err = tmpl.Bind(args...)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to bind template: ", err)
+			return 1
+		}
+	} else {
+		var err error
+		// saved queries are read from the first database
+		clause, err = dbs[0].Parse(ctx, strings.Join(args, " "))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to parse query: ", err)
+			return 1
+		}
 	}
 
 	o := query.NewOptimizer(clause, gFlags.NumWorkers)
@@ -83,27 +216,54 @@ func RunQuery(gFlags GlobalFlags, qFlags QueryFlags, db *data.Query, searchQuery
 		return 1
 	}
 
-	results, err := db.Execute(context.Background(), artifact)
-	if err != nil {
+	if _, ok := index.NewDocCmp(qFlags.SortBy, qFlags.SortDesc); ok {
+		artifact.SortBy = qFlags.SortBy
+		artifact.SortDesc = qFlags.SortDesc
+	}
+	artifact.Limit = qFlags.Limit
+	artifact.Offset = qFlags.Offset
+
+	// formats without authors, tags, or links can be written as rows are read
+	if o, ok := qFlags.Outputer.(query.CustomOutput); ok && !o.NeedsRelations() &&
+		len(dbs) == 1 && qFlags.Exec == "" && qFlags.ExecBatch == "" {
+		return streamQuery(ctx, gFlags, o, dbs[0], artifact)
+	}
+
+	outputableResults, err := executeMany(ctx, gFlags.DBPaths, dbs, artifact)
+	if errors.Is(err, context.DeadlineExceeded) {
+		fmt.Fprintln(os.Stderr, "Query timed out after", gFlags.Timeout)
+		return 1
+	} else if err != nil {
 		fmt.Fprintln(os.Stderr, "Failed to execute query: ", err)
 		return 1
 	}
 
-	if len(results) == 0 {
+	if len(outputableResults) == 0 {
 		fmt.Println("No results.")
 		return 0
 	}
 
-	outputableResults := make([]*index.Document, 0, len(results))
-	for _, v := range results {
-		outputableResults = append(outputableResults, v)
-	}
+	slices.SortFunc(outputableResults, resultCmp(qFlags.SortBy, qFlags.SortDesc))
 
-	if qFlags.SortBy != "" {
-		docCmp, ok := index.NewDocCmp(qFlags.SortBy, qFlags.SortDesc)
-		if ok {
-			slices.SortFunc(outputableResults, docCmp)
+	if qFlags.Exec != "" || qFlags.ExecBatch != "" {
+		paths := make([]string, len(outputableResults))
+		for i, doc := range outputableResults {
+			paths[i] = doc.Path
 		}
+
+		// commands may outlive -timeout, which only limits the query
+		execCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+		defer stop()
+		if qFlags.Exec != "" {
+			err = runExec(execCtx, qFlags.Exec, paths)
+		} else {
+			err = runExecBatch(execCtx, qFlags.ExecBatch, paths)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error while running command: ", err)
+			return 1
+		}
+		return 0
 	}
 
 	_, err = qFlags.Outputer.OutputTo(os.Stdout, outputableResults)
@@ -113,3 +273,29 @@ func RunQuery(gFlags GlobalFlags, qFlags QueryFlags, db *data.Query, searchQuery
 	}
 	return 0
 }
+
+func streamQuery(ctx context.Context, gFlags GlobalFlags, o query.CustomOutput, db *data.Query, artifact query.CompilationArtifact) byte {
+	w := bufio.NewWriter(os.Stdout)
+	n := 0
+	err := db.ExecuteFunc(ctx, artifact, func(doc *index.Document) error {
+		n++
+		_, err := o.OutputOneTo(w, doc)
+		return err
+	})
+	if flushErr := w.Flush(); err == nil {
+		err = flushErr
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		fmt.Fprintln(os.Stderr, "Query timed out after", gFlags.Timeout)
+		return 1
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to execute query: ", err)
+		return 1
+	}
+
+	if n == 0 {
+		fmt.Println("No results.")
+	}
+	return 0
+}
@@ -0,0 +1,106 @@
+package cmd_test
+
+import (
+	"testing"
+
+	"github.com/jpappel/atlas/cmd"
+	"github.com/jpappel/atlas/pkg/data"
+	"github.com/jpappel/atlas/pkg/index"
+	"github.com/jpappel/atlas/pkg/query"
+)
+
+func TestRunQuery_LimitManyDatabases(t *testing.T) {
+	note := func(path string) *index.Document {
+		return &index.Document{Path: path, Title: "Note"}
+	}
+	dbs := []*data.Query{
+		newTestDB(t, note("/a.md"), note("/c.md"), note("/e.md")),
+		newTestDB(t, note("/a.md"), note("/b.md"), note("/d.md")),
+	}
+	gFlags := cmd.GlobalFlags{DBPaths: []string{"first.db", "second.db"}, NumWorkers: 1}
+
+	tests := []struct {
+		name   string
+		limit  int
+		offset int
+		want   string
+	}{
+		{"limit", 3, 0, "/a.md\tfirst.db\n/a.md\tsecond.db\n/b.md\tsecond.db\n"},
+		{"offset", 2, 2, "/b.md\tsecond.db\n/c.md\tfirst.db\n"},
+		{"offset only", 0, 4, "/d.md\tsecond.db\n/e.md\tfirst.db\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := query.NewFieldsOutput([]string{"path", "database"}, "\t", "", "\n", ",")
+			if err != nil {
+				t.Fatal(err)
+			}
+			qFlags := cmd.QueryFlags{Outputer: o, Limit: tt.limit, Offset: tt.offset}
+
+			// the cut must not depend on which database finishes first
+			for range 20 {
+				got, code := captureStdout(t, func() byte {
+					return cmd.RunQuery(gFlags, qFlags, dbs, []string{"T:note"})
+				})
+				if code != 0 {
+					t.Fatalf("RunQuery() = %d, want 0", code)
+				}
+				if got != tt.want {
+					t.Fatalf("RunQuery() wrote %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRunQuery_ManyDatabases(t *testing.T) {
+	first := newTestDB(t,
+		&index.Document{Path: "/a.md", Title: "Shared note", Tags: []string{"work"}},
+		&index.Document{Path: "/b.md", Title: "First note"},
+		&index.Document{Path: "/other.md", Title: "Unmatched"},
+	)
+	second := newTestDB(t,
+		&index.Document{Path: "/a.md", Title: "Shared note", Tags: []string{"home"}},
+		&index.Document{Path: "/c.md", Title: "Second note"},
+	)
+
+	tests := []struct {
+		name  string
+		paths []string
+		dbs   []*data.Query
+		want  string
+	}{
+		{
+			"merged",
+			[]string{"first.db", "second.db"},
+			[]*data.Query{first, second},
+			"/a.md\tfirst.db\twork\n/a.md\tsecond.db\thome\n/b.md\tfirst.db\t\n/c.md\tsecond.db\t\n",
+		},
+		{
+			"single database",
+			[]string{"first.db"},
+			[]*data.Query{first},
+			"/a.md\t\twork\n/b.md\t\t\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := query.NewFieldsOutput([]string{"path", "database", "tags"}, "\t", "", "\n", ",")
+			if err != nil {
+				t.Fatal(err)
+			}
+			gFlags := cmd.GlobalFlags{DBPaths: tt.paths, NumWorkers: 1}
+			qFlags := cmd.QueryFlags{Outputer: o, SortBy: "path"}
+
+			got, code := captureStdout(t, func() byte {
+				return cmd.RunQuery(gFlags, qFlags, tt.dbs, []string{"T:note"})
+			})
+			if code != 0 {
+				t.Fatalf("RunQuery() = %d, want 0", code)
+			}
+			if got != tt.want {
+				t.Errorf("RunQuery() wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/jpappel/atlas/pkg/data"
+	"github.com/jpappel/atlas/pkg/index"
+	"github.com/jpappel/atlas/pkg/server"
+)
+
+func RunRPC(gFlags GlobalFlags, db *data.Query) byte {
+	db.UseCache(gFlags.CacheSize)
+	s := server.RPCServer{
+		Db:      db,
+		Root:    gFlags.IndexRoot,
+		Filters: index.DefaultFilters(),
+		ParseOpts: index.ParseOpts{
+			ParseMeta:      true,
+			ParseHeadings:  true,
+			ParseLinks:     true,
+			ParseCitations: true,
+			DetectLanguage: true,
+			IDPattern:      index.DefaultIDPattern,
+		},
+		Workers:  gFlags.NumWorkers,
+		Timeout:  gFlags.Timeout,
+		Webhooks: gFlags.Webhooks,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	if err := s.Serve(ctx, os.Stdin, os.Stdout); err != nil && err != context.Canceled {
+		slog.Error("Error serving rpc requests", slog.String("err", err.Error()))
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jpappel/atlas/pkg/data"
+)
+
+// Manage saved queries, args are the subcommand followed by its arguments
+func RunSaved(gFlags GlobalFlags, db *data.Query, args []string) byte {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "No saved subcommand provided")
+		Help("saved", os.Stderr)
+		return 2
+	}
+
+	ctx, cancel := gFlags.Context()
+	defer cancel()
+
+	switch subcommand := args[0]; subcommand {
+	case "add":
+		if len(args) < 3 {
+			fmt.Fprintf(os.Stderr, "Usage: %s saved add <name> <query>...\n", os.Args[0])
+			return 2
+		}
+		if err := db.SaveQuery(ctx, args[1], strings.Join(args[2:], " ")); err != nil {
+			fmt.Fprintln(os.Stderr, "Error while saving query:", err)
+			return 1
+		}
+	case "remove", "rm":
+		if len(args) < 2 {
+			fmt.Fprintf(os.Stderr, "Usage: %s saved remove <name>...\n", os.Args[0])
+			return 2
+		}
+		if err := db.RemoveSavedQueries(ctx, args[1:]...); err != nil {
+			fmt.Fprintln(os.Stderr, "Error while removing saved queries:", err)
+			return 1
+		}
+	case "list", "ls":
+		saved, err := db.SavedQueries(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error while listing saved queries:", err)
+			return 1
+		}
+		for _, s := range saved {
+			fmt.Printf("@%s %s\n", s.Name, s.Query)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, "Unrecognized saved subcommand: ", subcommand)
+		return 2
+	}
+
+	return 0
+}
@@ -14,21 +14,44 @@ import (
 
 	"github.com/jpappel/atlas/pkg/data"
 	"github.com/jpappel/atlas/pkg/server"
+	"github.com/jpappel/atlas/pkg/util"
 )
 
 type ServerFlags struct {
 	Address string
 	Port    int
+	data.MaintenanceOpts
 }
 
 func SetupServerFlags(args []string, fs *flag.FlagSet, flags *ServerFlags) {
 	fs.StringVar(&flags.Address, "address", "127.0.0.1", "the address to listen on, prefix with 'unix:' to create a unixsocket")
 	fs.IntVar(&flags.Port, "port", 8080, "the port to bind to")
 
+	scheduleFlag := func(schedule *util.Schedule) func(string) error {
+		return func(s string) error {
+			if s == "" {
+				*schedule = nil
+				return nil
+			}
+			sched, err := util.ParseSchedule(s)
+			if err != nil {
+				return err
+			}
+			*schedule = sched
+			return nil
+		}
+	}
+	flags.Optimize = util.IntervalSchedule(time.Hour)
+	fs.Func("optimize", "`schedule` to optimize the database, empty to disable (default @every 1h)", scheduleFlag(&flags.Optimize))
+	flags.Tidy, _ = util.ParseSchedule("@daily")
+	fs.Func("tidy", "`schedule` to tidy the database, empty to disable (default @daily)", scheduleFlag(&flags.Tidy))
+	fs.DurationVar(&flags.Jitter, "jitter", 5*time.Minute, "maximum random `duration` to delay each scheduled task")
+
 	fs.Parse(args)
 }
 
 func RunServer(gFlags GlobalFlags, sFlags ServerFlags, db *data.Query) byte {
+	db.UseCache(gFlags.CacheSize)
 
 	var addr string
 	var s server.Server
@@ -55,9 +78,9 @@ func RunServer(gFlags GlobalFlags, sFlags ServerFlags, db *data.Query) byte {
 		close(serverErrors)
 	}(serverErrors)
 
-	optCtx, optCancel := context.WithCancel(context.Background())
-	go db.PeriodicOptimize(optCtx, 1*time.Hour)
-	defer optCancel()
+	maintainCtx, maintainCancel := context.WithCancel(context.Background())
+	go db.Maintain(maintainCtx, sFlags.MaintenanceOpts)
+	defer maintainCancel()
 
 	select {
 	case <-exit:
@@ -17,8 +17,11 @@ func RunShell(gFlags GlobalFlags, db *data.Query, version string) byte {
 	env["db_path"] = gFlags.DBPath
 	env["index_root"] = gFlags.IndexRoot
 	env["version"] = version
+	env["timeout"] = gFlags.Timeout.String()
 
+	db.UseCache(gFlags.CacheSize)
 	interpreter := shell.NewInterpreter(state, env, gFlags.NumWorkers, db)
+	interpreter.Timeout = gFlags.Timeout
 	if err := interpreter.Run(); err != nil && err != io.EOF {
 		slog.Error("Fatal error occured", slog.String("err", err.Error()))
 		return 1
@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	"github.com/jpappel/atlas/cmd"
 	"github.com/jpappel/atlas/pkg/data"
 	"github.com/jpappel/atlas/pkg/query"
+	"github.com/jpappel/atlas/pkg/util"
 )
 
 const VERSION = "0.5.1"
@@ -33,11 +35,25 @@ func main() {
 	queryFs := flag.NewFlagSet("query", flag.ExitOnError)
 	shellFs := flag.NewFlagSet("debug", flag.ExitOnError)
 	serverFs := flag.NewFlagSet("server", flag.ExitOnError)
+	exportFs := flag.NewFlagSet("export", flag.ExitOnError)
+	importFs := flag.NewFlagSet("import", flag.ExitOnError)
+	aliasFs := flag.NewFlagSet("alias", flag.ExitOnError)
+	savedFs := flag.NewFlagSet("saved", flag.ExitOnError)
+	grepFs := flag.NewFlagSet("grep", flag.ExitOnError)
+	linksFs := flag.NewFlagSet("links", flag.ExitOnError)
+	pinFs := flag.NewFlagSet("pin", flag.ExitOnError)
+	diffFs := flag.NewFlagSet("diff", flag.ExitOnError)
+	publishFs := flag.NewFlagSet("publish", flag.ExitOnError)
+	rpcFs := flag.NewFlagSet("rpc", flag.ExitOnError)
 	completionsFs := flag.NewFlagSet("completions", flag.ContinueOnError)
 
 	// set default usage for flagsets without subcommands
 	shellFs.Usage = addGlobalFlagUsage(shellFs)
 	serverFs.Usage = addGlobalFlagUsage(serverFs)
+	importFs.Usage = addGlobalFlagUsage(importFs)
+	aliasFs.Usage = addGlobalFlagUsage(aliasFs)
+	savedFs.Usage = addGlobalFlagUsage(savedFs)
+	rpcFs.Usage = addGlobalFlagUsage(rpcFs)
 
 	flag.Parse()
 	args := flag.Args()
@@ -45,6 +61,12 @@ func main() {
 	queryFlags := cmd.QueryFlags{Outputer: query.DefaultOutput{}}
 	indexFlags := cmd.IndexFlags{}
 	serverFlags := cmd.ServerFlags{Port: 8080}
+	exportFlags := cmd.ExportFlags{}
+	grepFlags := cmd.GrepFlags{}
+	linksFlags := cmd.LinksFlags{}
+	pinFlags := cmd.PinFlags{}
+	diffFlags := cmd.DiffFlags{}
+	publishFlags := cmd.PublishFlags{}
 
 	if len(args) < 1 {
 		fmt.Fprintln(os.Stderr, "No Command provided")
@@ -61,6 +83,34 @@ func main() {
 		cmd.SetupIndexFlags(args[1:], indexFs, &indexFlags)
 	case "server":
 		cmd.SetupServerFlags(args[1:], serverFs, &serverFlags)
+	case "export":
+		cmd.SetupExportFlags(args[1:], exportFs, &exportFlags)
+	case "import":
+		importFs.Parse(args[1:])
+	case "alias":
+		aliasFs.Parse(args[1:])
+	case "saved":
+		savedFs.Parse(args[1:])
+	case "grep":
+		cmd.SetupGrepFlags(args[1:], grepFs, &grepFlags)
+		if grepFs.NArg() < 1 {
+			fmt.Fprintln(os.Stderr, "No pattern provided")
+			grepFs.Usage()
+			os.Exit(ExitCommand)
+		}
+	case "links":
+		cmd.SetupLinksFlags(args[1:], linksFs, &linksFlags)
+	case "pin":
+		cmd.SetupPinFlags(args[1:], pinFs, &pinFlags)
+	case "diff":
+		cmd.SetupDiffFlags(args[1:], diffFs, &diffFlags)
+		if diffFs.NArg() != 2 {
+			fmt.Fprintln(os.Stderr, "Expected two queries")
+			diffFs.Usage()
+			os.Exit(ExitCommand)
+		}
+	case "publish":
+		cmd.SetupPublishFlags(args[1:], publishFs, &publishFlags)
 	case "completions":
 		completionsFs.Parse(args[1:])
 	case "help":
@@ -72,6 +122,8 @@ func main() {
 		return
 	case "shell":
 		shellFs.Parse(args[1:])
+	case "rpc":
+		rpcFs.Parse(args[1:])
 	default:
 		cmd.Help(command, os.Stderr)
 		os.Exit(ExitCommand)
@@ -94,20 +146,22 @@ func main() {
 		os.Exit(ExitCommand)
 	}
 
-	var logFile *os.File
-	var err error
+	var logFile io.Writer
 	switch globalFlags.LogFile {
 	case "":
 		logFile = os.Stderr
 	case "-":
 		logFile = os.Stdout
 	default:
-		logFile, err = os.Create(globalFlags.LogFile)
+		f, err := util.OpenLogFile(globalFlags.LogFile,
+			globalFlags.LogAppend, globalFlags.LogMaxSize, globalFlags.LogRetain,
+		)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Cannot use log file `%s`: %s", globalFlags.LogFile, err)
 			os.Exit(1)
 		}
-		defer logFile.Close()
+		defer f.Close()
+		logFile = f
 	}
 
 	var logHandler slog.Handler
@@ -126,18 +180,59 @@ func main() {
 	logger := slog.New(logHandler)
 	slog.SetDefault(logger)
 
-	querier := data.NewQuery(globalFlags.DBPath, VERSION)
+	if len(globalFlags.DBPaths) > 1 && command != "query" && command != "q" {
+		slog.Warn("Multiple databases are only supported by query, using the first",
+			slog.String("db", globalFlags.DBPath),
+		)
+	}
+
+	stopProfiling, err := cmd.StartProfiling(globalFlags)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	querier := data.NewQuery(globalFlags.DBPath, VERSION, globalFlags.DBOpts())
 
 	// command specific
 	var exitCode int
 	switch command {
 	case "query", "q":
-		searchQuery := strings.Join(queryFs.Args(), " ")
-		exitCode = int(cmd.RunQuery(globalFlags, queryFlags, querier, searchQuery))
+		queriers := make([]*data.Query, 0, len(globalFlags.DBPaths))
+		queriers = append(queriers, querier)
+		for _, dbPath := range globalFlags.DBPaths[1:] {
+			queriers = append(queriers, data.NewQuery(dbPath, VERSION, globalFlags.DBOpts()))
+		}
+
+		exitCode = int(cmd.RunQuery(globalFlags, queryFlags, queriers, queryFs.Args()))
+
+		for _, q := range queriers[1:] {
+			q.Close()
+		}
 	case "index", "i":
 		exitCode = int(cmd.RunIndex(globalFlags, indexFlags, querier))
 	case "server":
 		exitCode = int(cmd.RunServer(globalFlags, serverFlags, querier))
+	case "export":
+		searchQuery := strings.Join(exportFs.Args(), " ")
+		exitCode = int(cmd.RunExport(globalFlags, exportFlags, querier, searchQuery))
+	case "import":
+		exitCode = int(cmd.RunImport(globalFlags, querier, importFs.Args()))
+	case "alias":
+		exitCode = int(cmd.RunAlias(globalFlags, querier, aliasFs.Args()))
+	case "saved":
+		exitCode = int(cmd.RunSaved(globalFlags, querier, savedFs.Args()))
+	case "grep":
+		searchQuery := strings.Join(grepFs.Args()[1:], " ")
+		exitCode = int(cmd.RunGrep(globalFlags, grepFlags, querier, grepFs.Arg(0), searchQuery))
+	case "links":
+		exitCode = int(cmd.RunLinks(globalFlags, linksFlags, querier))
+	case "pin":
+		exitCode = int(cmd.RunPin(globalFlags, pinFlags, querier, pinFs.Args()))
+	case "diff":
+		exitCode = int(cmd.RunDiff(globalFlags, diffFlags, querier, diffFs.Arg(0), diffFs.Arg(1)))
+	case "publish":
+		exitCode = int(cmd.RunPublish(globalFlags, publishFlags, querier))
 	case "completions":
 		lang := completionsFs.Arg(0)
 		switch lang {
@@ -151,8 +246,13 @@ func main() {
 		}
 	case "shell":
 		exitCode = int(cmd.RunShell(globalFlags, querier, VERSION))
+	case "rpc":
+		exitCode = int(cmd.RunRPC(globalFlags, querier))
 	}
 
 	querier.Close()
+	if err := stopProfiling(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
 	os.Exit(exitCode)
 }
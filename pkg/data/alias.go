@@ -0,0 +1,158 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jpappel/atlas/pkg/index"
+)
+
+// Insert an author unless it is an alias of another author
+const insertAuthorQuery = `
+	INSERT OR IGNORE INTO Authors(author)
+	SELECT ?1
+	WHERE NOT EXISTS (SELECT 1 FROM Aliases WHERE alias = ?1)
+	`
+
+// Get the id of an author, resolving aliases
+const authorIdQuery = `
+	SELECT COALESCE(
+		(SELECT authorId FROM Aliases WHERE alias = ?1),
+		(SELECT id FROM Authors WHERE author = ?1)
+	)`
+
+// Set the email of an author by id
+const setAuthorEmailQuery = "UPDATE Authors SET email = ? WHERE id = ?"
+
+type Alias struct {
+	Alias  string
+	Author string
+}
+
+// Add aliases for an author and reassign documents using an alias to the author.
+// If author is itself an alias, its aliased author is used instead.
+func (q Query) AddAliases(ctx context.Context, author string, aliases ...string) error {
+	defer q.changed()
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, insertAuthorQuery, author); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	var authorId int64
+	if err := tx.QueryRowContext(ctx, authorIdQuery, author).Scan(&authorId); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, alias := range aliases {
+		var aliasId sql.NullInt64
+		row := tx.QueryRowContext(ctx, "SELECT id FROM Authors WHERE author = ?", alias)
+		if err := row.Scan(&aliasId); err != nil && err != sql.ErrNoRows {
+			tx.Rollback()
+			return err
+		}
+
+		if aliasId.Valid && aliasId.Int64 == authorId {
+			tx.Rollback()
+			return fmt.Errorf("Cannot alias %s to itself", alias)
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			"INSERT OR REPLACE INTO Aliases(alias, authorId) VALUES (?,?)",
+			alias, authorId,
+		); err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if !aliasId.Valid {
+			continue
+		}
+
+		// existing aliases of the alias now point to the author
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE Aliases SET authorId = ? WHERE authorId = ?",
+			authorId, aliasId.Int64,
+		); err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		// avoid duplicate authors for documents listing both spellings
+		if _, err := tx.ExecContext(ctx, `
+		DELETE FROM DocumentAuthors
+		WHERE authorId = ?1 AND docId IN (
+			SELECT docId FROM DocumentAuthors WHERE authorId = ?2
+		)`, aliasId.Int64, authorId); err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE DocumentAuthors SET authorId = ? WHERE authorId = ?",
+			authorId, aliasId.Int64,
+		); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Remove aliases. Documents keep their current author until they are reindexed.
+func (q Query) RemoveAliases(ctx context.Context, aliases ...string) error {
+	defer q.changed()
+	if len(aliases) == 0 {
+		return nil
+	}
+
+	query, args := BatchQuery("DELETE FROM Aliases WHERE alias IN", "(", "?", ",", ")", len(aliases), aliases)
+	_, err := q.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// Get all aliases ordered by author
+func (q Query) Aliases(ctx context.Context) ([]Alias, error) {
+	rows, err := q.db.QueryContext(ctx, `
+	SELECT alias, author
+	FROM Aliases
+	JOIN Authors ON Aliases.authorId = Authors.id
+	ORDER BY author, alias
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	aliases := make([]Alias, 0)
+	for rows.Next() {
+		var a Alias
+		if err := rows.Scan(&a.Alias, &a.Author); err != nil {
+			return nil, err
+		}
+		aliases = append(aliases, a)
+	}
+
+	return aliases, rows.Err()
+}
+
+// Replace author aliases in idx with their authors, as they would be stored
+func (q Query) ResolveAliases(ctx context.Context, idx index.Index) (index.Index, error) {
+	aliases, err := q.Aliases(ctx)
+	if err != nil {
+		return index.Index{}, err
+	}
+
+	authors := make(map[string]string, len(aliases))
+	for _, a := range aliases {
+		authors[a.Alias] = a.Author
+	}
+	return idx.ResolveAliases(authors), nil
+}
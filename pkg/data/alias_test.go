@@ -0,0 +1,159 @@
+package data_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jpappel/atlas/pkg/data"
+	"github.com/jpappel/atlas/pkg/index"
+)
+
+func TestQuery_AddAliases(t *testing.T) {
+	tests := []struct {
+		name        string
+		docs        map[string]*index.Document
+		author      string
+		aliases     []string
+		wantAuthors map[string][]string
+		wantAliases []data.Alias
+		wantErr     bool
+	}{
+		{
+			"unify spellings",
+			map[string]*index.Document{
+				"/a": {Path: "/a", FileTime: time.Unix(1, 0), Authors: []string{"JP Appel"}},
+				"/b": {Path: "/b", FileTime: time.Unix(1, 0), Authors: []string{"jp"}},
+				"/c": {Path: "/c", FileTime: time.Unix(1, 0), Authors: []string{"jp", "JP Appel"}},
+			},
+			"JP Appel",
+			[]string{"jp", "J. Appel"},
+			map[string][]string{
+				"/a": {"JP Appel"},
+				"/b": {"JP Appel"},
+				"/c": {"JP Appel"},
+			},
+			[]data.Alias{{"J. Appel", "JP Appel"}, {"jp", "JP Appel"}},
+			false,
+		},
+		{
+			"alias of an alias",
+			map[string]*index.Document{
+				"/a": {Path: "/a", FileTime: time.Unix(1, 0), Authors: []string{"jp"}},
+			},
+			"jp",
+			[]string{"jp"},
+			nil,
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := t.Context()
+			q := data.NewMemQuery("test")
+			defer q.Close()
+
+			if err := q.Put(ctx, index.Index{Documents: tt.docs}); err != nil {
+				t.Fatal("Unexpected error while inserting documents:", err)
+			}
+
+			gotErr := q.AddAliases(ctx, tt.author, tt.aliases...)
+			if (gotErr != nil) != tt.wantErr {
+				t.Fatalf("Unexpected error from AddAliases(): %v", gotErr)
+			} else if gotErr != nil {
+				return
+			}
+
+			for path, wantAuthors := range tt.wantAuthors {
+				doc, err := q.GetDocument(ctx, path)
+				if err != nil {
+					t.Fatal("Unexpected error while getting document:", err)
+				}
+				if !slices.Equal(doc.Authors, wantAuthors) {
+					t.Errorf("%s has authors %v, want %v", path, doc.Authors, wantAuthors)
+				}
+			}
+
+			gotAliases, err := q.Aliases(ctx)
+			if err != nil {
+				t.Fatal("Unexpected error while listing aliases:", err)
+			}
+			if !slices.Equal(gotAliases, tt.wantAliases) {
+				t.Errorf("Aliases() = %v, want %v", gotAliases, tt.wantAliases)
+			}
+		})
+	}
+}
+
+func TestQuery_Aliases_Indexing(t *testing.T) {
+	ctx := t.Context()
+	q := data.NewMemQuery("test")
+	defer q.Close()
+
+	if err := q.AddAliases(ctx, "JP Appel", "jp"); err != nil {
+		t.Fatal("Unexpected error from AddAliases():", err)
+	}
+
+	docs := map[string]*index.Document{
+		"/a": {Path: "/a", FileTime: time.Unix(1, 0), Authors: []string{"jp"}},
+	}
+	if err := q.Put(ctx, index.Index{Documents: docs}); err != nil {
+		t.Fatal("Unexpected error while inserting documents:", err)
+	}
+
+	doc, err := q.GetDocument(ctx, "/a")
+	if err != nil {
+		t.Fatal("Unexpected error while getting document:", err)
+	}
+	if want := []string{"JP Appel"}; !slices.Equal(doc.Authors, want) {
+		t.Errorf("Indexed authors %v, want %v", doc.Authors, want)
+	}
+
+	if _, err := q.Tidy(ctx); err != nil {
+		t.Fatal("Unexpected error while tidying:", err)
+	}
+	if err := q.RemoveAliases(ctx, "jp"); err != nil {
+		t.Fatal("Unexpected error from RemoveAliases():", err)
+	}
+	if aliases, err := q.Aliases(ctx); err != nil {
+		t.Fatal("Unexpected error while listing aliases:", err)
+	} else if len(aliases) != 0 {
+		t.Errorf("Expected no aliases after removal, got %v", aliases)
+	}
+}
+
+func TestQuery_ResolveAliases_Diff(t *testing.T) {
+	ctx := t.Context()
+	q := data.NewMemQuery("test")
+	defer q.Close()
+
+	if err := q.AddAliases(ctx, "JP Appel", "jp"); err != nil {
+		t.Fatal("Unexpected error from AddAliases():", err)
+	}
+
+	doc := func(fileTime int64) *index.Document {
+		return &index.Document{
+			Path:     "/a",
+			FileTime: time.Unix(fileTime, 0),
+			Authors:  []string{"Rob Pike", "jp", "Ken Thompson"},
+			Emails:   map[string]string{"jp": "jp@example.com"},
+		}
+	}
+	if err := q.Put(ctx, index.Index{Root: "/", Documents: map[string]*index.Document{"/a": doc(1)}}); err != nil {
+		t.Fatal("Unexpected error while inserting documents:", err)
+	}
+	prev, err := q.Get(ctx, "/")
+	if err != nil {
+		t.Fatal("Unexpected error while getting index:", err)
+	}
+
+	next, err := q.ResolveAliases(ctx, index.Index{Root: "/", Documents: map[string]*index.Document{"/a": doc(2)}})
+	if err != nil {
+		t.Fatal("Unexpected error from ResolveAliases():", err)
+	}
+	diff := prev.Diff(next)
+	if want := []string{"filetime"}; !slices.Equal(diff.Modified["/a"], want) {
+		t.Errorf("Changed fields = %v, want %v", diff.Modified["/a"], want)
+	}
+}
@@ -0,0 +1,128 @@
+package data
+
+import (
+	"container/list"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jpappel/atlas/pkg/index"
+)
+
+type cacheKey struct {
+	path     string
+	fileTime int64
+}
+
+// Key in Info counting writes which may change documents
+const changesKey = "changes"
+
+// A least recently used cache of documents with their relations filled.
+// Entries are keyed by path and filetime so a reindexed file misses, and
+// the cache is emptied when the database changes count differs.
+// Documents are copied in and out so callers may modify them.
+type docCache struct {
+	mu       sync.Mutex
+	capacity int
+	changes  string // changes count the entries were read at
+	entries  map[cacheKey]*list.Element
+	order    *list.List // most recently used at the front
+}
+
+func newDocCache(capacity int) *docCache {
+	return &docCache{
+		capacity: capacity,
+		entries:  make(map[cacheKey]*list.Element, capacity),
+		order:    list.New(),
+	}
+}
+
+func keyOf(path string, fileTime time.Time) cacheKey {
+	return cacheKey{path, fileTime.Unix()}
+}
+
+func (c *docCache) Get(path string, fileTime time.Time) (*index.Document, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[keyOf(path, fileTime)]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return cloneDocument(elem.Value.(*index.Document)), true
+}
+
+func (c *docCache) Add(doc *index.Document) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := keyOf(doc.Path, doc.FileTime)
+	cached := cloneDocument(doc)
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = cached
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(cached)
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		old := c.order.Remove(oldest).(*index.Document)
+		delete(c.entries, keyOf(old.Path, old.FileTime))
+	}
+}
+
+// Copy a document along with its slices and maps
+func cloneDocument(doc *index.Document) *index.Document {
+	clone := *doc
+	clone.Authors = slices.Clone(doc.Authors)
+	clone.Emails = maps.Clone(doc.Emails)
+	clone.Tags = slices.Clone(doc.Tags)
+	clone.Links = slices.Clone(doc.Links)
+	clone.Citations = slices.Clone(doc.Citations)
+	// meta field values are scalars
+	clone.MetaFields = slices.Clone(doc.MetaFields)
+	return &clone
+}
+
+func (c *docCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Remove every entry if changes differs from the count entries were read at
+func (c *docCache) Sync(changes string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if changes != c.changes {
+		clear(c.entries)
+		c.order.Init()
+		c.changes = changes
+	}
+}
+
+// Remove every entry, used after writes which may change relations
+func (c *docCache) Clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+	c.order.Init()
+}
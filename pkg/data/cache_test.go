@@ -0,0 +1,138 @@
+package data_test
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jpappel/atlas/pkg/data"
+	"github.com/jpappel/atlas/pkg/index"
+	"github.com/jpappel/atlas/pkg/query"
+)
+
+func TestQuery_UseCache(t *testing.T) {
+	ctx := t.Context()
+	q := data.NewMemQuery("test")
+	defer q.Close()
+	q.UseCache(8)
+
+	docs := map[string]*index.Document{
+		"/a": {
+			Path:       "/a",
+			Title:      "Cached document",
+			FileTime:   time.Unix(1, 0),
+			Authors:    []string{"jp"},
+			Emails:     map[string]string{"jp": "jp@example.com"},
+			Tags:       []string{"go"},
+			Links:      []string{"/b"},
+			MetaFields: []index.MetaField{{Key: "draft", Value: true}},
+		},
+		"/b": {Path: "/b", Title: "Cached document", FileTime: time.Unix(2, 0), Authors: []string{"pj"}},
+	}
+	if err := q.Put(ctx, index.Index{Documents: docs}); err != nil {
+		t.Fatal(err)
+	}
+
+	artifact, err := query.Compile("T:cached", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := q.Execute(ctx, artifact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// callers modifying results must not change cached documents
+	first["/a"].Authors = nil
+	first["/a"].Database = "modified"
+	first["/a"].Emails["jp"] = "modified"
+	first["/a"].Tags[0] = "modified"
+	first["/a"].Links[0] = "modified"
+	first["/a"].MetaFields[0].Value = "modified"
+
+	second, err := q.Execute(ctx, artifact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second) != 2 {
+		t.Fatalf("Expected 2 documents, got %d", len(second))
+	}
+	for path, doc := range docs {
+		if !doc.Equal(*second[path]) {
+			t.Errorf("Cached %s = %+v, want %+v", path, second[path], doc)
+		}
+	}
+	if second["/a"].Database != "" {
+		t.Error("Expected cached document to be unmodified")
+	}
+
+	doc, err := q.GetDocument(ctx, "/b")
+	if err != nil {
+		t.Fatal(err)
+	} else if !doc.Equal(*docs["/b"]) {
+		t.Errorf("GetDocument() = %+v, want %+v", doc, docs["/b"])
+	}
+
+	// aliasing changes authors without changing filetimes
+	if err := q.AddAliases(ctx, "JP Appel", "jp"); err != nil {
+		t.Fatal(err)
+	}
+	third, err := q.Execute(ctx, artifact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"JP Appel"}; !slices.Equal(third["/a"].Authors, want) {
+		t.Errorf("Authors after aliasing = %v, want %v", third["/a"].Authors, want)
+	}
+}
+
+func TestQuery_UseCache_OtherWriter(t *testing.T) {
+	ctx := t.Context()
+	path := filepath.Join(t.TempDir(), "test.db")
+	q := data.NewQuery(path, "test", data.DefaultDBOpts)
+	defer q.Close()
+	q.UseCache(8)
+
+	docs := map[string]*index.Document{
+		"/a": {Path: "/a", Title: "Cached document", FileTime: time.Unix(1, 0), Authors: []string{"jp"}},
+	}
+	if err := q.Put(ctx, index.Index{Documents: docs}); err != nil {
+		t.Fatal(err)
+	}
+	artifact, err := query.Compile("T:cached", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Execute(ctx, artifact); err != nil {
+		t.Fatal(err)
+	}
+
+	// another process writing to the same database
+	other := data.NewQuery(path, "test", data.DefaultDBOpts)
+	defer other.Close()
+	if err := other.SetPinned(ctx, true, "/a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.AddAliases(ctx, "JP Appel", "jp"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := q.Execute(ctx, artifact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got["/a"].Pinned {
+		t.Error("Expected document pinned by another writer to be pinned")
+	}
+	if want := []string{"JP Appel"}; !slices.Equal(got["/a"].Authors, want) {
+		t.Errorf("Authors after aliasing by another writer = %v, want %v", got["/a"].Authors, want)
+	}
+
+	doc, err := q.GetDocument(ctx, "/a")
+	if err != nil {
+		t.Fatal(err)
+	} else if !doc.Pinned {
+		t.Error("Expected GetDocument() to return the pinned document")
+	}
+}
@@ -3,6 +3,7 @@ package data
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"log/slog"
 	"regexp"
@@ -29,7 +30,20 @@ import (
 // `
 
 type Query struct {
-	db *sql.DB
+	db    *sql.DB
+	cache *docCache // nil unless enabled with UseCache
+}
+
+// Columns results can be ordered by, keyed by document field
+var sortColumns = map[string]string{
+	"path":     "d.path",
+	"id":       "d.zettelId",
+	"title":    "d.title",
+	"lang":     "d.lang",
+	"date":     "d.date",
+	"filetime": "d.fileTime",
+	"meta":     "d.meta",
+	"headings": "d.headings",
 }
 
 // Append n copies of val to query
@@ -59,21 +73,50 @@ func BatchQuery[T any](query string, start string, val string, delim string, sto
 	return b.String(), args
 }
 
-func NewQuery(filename string, version string) *Query {
-	query := &Query{NewDB(filename, version)}
+// Connection settings for file backed databases
+type DBOpts struct {
+	// time to wait on locks held by other connections before failing with SQLITE_BUSY
+	BusyTimeout time.Duration
+	// WAL size in pages after which a commit checkpoints, 0 disables automatic checkpoints
+	WalAutocheckpoint int
+	// maximum edit distance of values matched by typo tolerant statements
+	FuzzyDistance int
+}
+
+var DefaultDBOpts = DBOpts{BusyTimeout: 5 * time.Second, WalAutocheckpoint: 1000, FuzzyDistance: 2}
+
+func NewQuery(filename string, version string, opts DBOpts) *Query {
+	query := &Query{db: NewDB(filename, version, opts)}
 	return query
 }
 
-func NewDB(filename string, version string) *sql.DB {
-	connStr := "file:" + filename + "?_fk=true&_journal=WAL"
-	db, err := sql.Open("sqlite3_regex", connStr)
-	if err != nil {
-		panic(err)
-	}
+// Create a query backed by an in memory database
+func NewMemQuery(version string) *Query {
+	return &Query{db: NewMemDB(version)}
+}
 
-	var dbVersion string
-	row := db.QueryRow("SELECT key, value FROM Info WHERE key='version'")
-	if err := row.Scan(&dbVersion); err == nil {
+func NewDB(filename string, version string, opts DBOpts) *sql.DB {
+	connStr := fmt.Sprintf("file:%s?_fk=true&_journal=WAL&_busy_timeout=%d",
+		filename, opts.BusyTimeout.Milliseconds(),
+	)
+	// wal_autocheckpoint is per connection so it must be set as each is opened
+	walPragma := fmt.Sprintf("PRAGMA wal_autocheckpoint = %d", max(opts.WalAutocheckpoint, 0))
+	db := sql.OpenDB(connector{
+		dsn: connStr,
+		driver: &sqlite3.SQLiteDriver{
+			ConnectHook: func(sc *sqlite3.SQLiteConn) error {
+				if err := registerFuncs(sc, opts.FuzzyDistance); err != nil {
+					return err
+				}
+				_, err := sc.Exec(walPragma, nil)
+				return err
+			},
+		},
+	})
+
+	var dbSchema int
+	row := db.QueryRow("SELECT value FROM Info WHERE key='schema'")
+	if err := row.Scan(&dbSchema); err == nil && dbSchema == schemaVersion {
 		return db
 	}
 
@@ -89,6 +132,8 @@ func NewMemDB(version string) *sql.DB {
 	if err != nil {
 		panic(err)
 	}
+	// each connection would otherwise get its own empty database
+	db.SetMaxOpenConns(1)
 
 	if err := createSchema(db, version); err != nil {
 		panic(err)
@@ -114,14 +159,23 @@ func createSchema(db *sql.DB, version string) error {
 		return err
 	}
 
+	rebuildFts, err := migrateSchema(tx)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("Cannot migrate database schema: %w", err)
+	}
+
 	_, err = tx.Exec(`
 	CREATE TABLE IF NOT EXISTS Documents(
 		id INTEGER PRIMARY KEY,
 		path TEXT UNIQUE NOT NULL,
+		zettelId TEXT,
+		lang TEXT,
 		headings TEXT,
 		title TEXT,
 		date INT,
 		fileTime INT,
+		pinned INT NOT NULL DEFAULT 0,
 		meta BLOB
 	)`)
 	if err != nil {
@@ -132,7 +186,8 @@ func createSchema(db *sql.DB, version string) error {
 	_, err = tx.Exec(`
 	CREATE TABLE IF NOT EXISTS Authors(
 		id INTEGER PRIMARY KEY,
-		author TEXT UNIQUE NOT NULL
+		author TEXT UNIQUE NOT NULL,
+		email TEXT
 	)`)
 	if err != nil {
 		tx.Rollback()
@@ -161,6 +216,31 @@ func createSchema(db *sql.DB, version string) error {
 		return err
 	}
 
+	_, err = tx.Exec(`
+	CREATE TABLE IF NOT EXISTS Citations(
+		docId INT,
+		citation TEXT NOT NULL,
+		FOREIGN KEY (docId) REFERENCES Documents(id) ON DELETE CASCADE,
+		UNIQUE(docId, citation)
+	)`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec(`
+	CREATE TABLE IF NOT EXISTS Meta(
+		docId INT NOT NULL,
+		key TEXT NOT NULL,
+		value,
+		type TEXT NOT NULL,
+		FOREIGN KEY (docId) REFERENCES Documents(id) ON DELETE CASCADE
+	)`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	_, err = tx.Exec(`
 	CREATE TABLE IF NOT EXISTS DocumentAuthors(
 		docId INT NOT NULL,
@@ -186,6 +266,27 @@ func createSchema(db *sql.DB, version string) error {
 		return err
 	}
 
+	_, err = tx.Exec(`
+	CREATE TABLE IF NOT EXISTS Aliases(
+		alias TEXT PRIMARY KEY NOT NULL,
+		authorId INT NOT NULL,
+		FOREIGN KEY (authorId) REFERENCES Authors(id) ON DELETE CASCADE
+	)`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec(`
+	CREATE TABLE IF NOT EXISTS SavedQueries(
+		name TEXT PRIMARY KEY NOT NULL,
+		query TEXT NOT NULL
+	)`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	_, err = tx.Exec("CREATE INDEX IF NOT EXISTS idx_doc_paths ON Documents (path)")
 	if err != nil {
 		tx.Rollback()
@@ -204,12 +305,42 @@ func createSchema(db *sql.DB, version string) error {
 		return err
 	}
 
+	_, err = tx.Exec("CREATE INDEX IF NOT EXISTS idx_doc_zettelids ON Documents (zettelId)")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec("CREATE INDEX IF NOT EXISTS idx_doc_langs ON Documents (lang)")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	_, err = tx.Exec("CREATE INDEX IF NOT EXISTS idx_links_link ON Links(link)")
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 
+	_, err = tx.Exec("CREATE INDEX IF NOT EXISTS idx_citations_citation ON Citations(citation)")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec("CREATE INDEX IF NOT EXISTS idx_meta_key ON Meta(key, docId)")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec("CREATE INDEX IF NOT EXISTS idx_authors_email ON Authors (email COLLATE NOCASE)")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	_, err = tx.Exec("CREATE INDEX IF NOT EXISTS idx_doctags_tagid ON DocumentTags (tagId)")
 	if err != nil {
 		tx.Rollback()
@@ -219,7 +350,7 @@ func createSchema(db *sql.DB, version string) error {
 	_, err = tx.Exec(`
 	CREATE VIRTUAL TABLE IF NOT EXISTS Documents_fts
 	USING fts5 (
-		path, headings, title, meta, content=Documents, content_rowid=id, tokenize="trigram"
+		path, headings, title, meta, zettelId, content=Documents, content_rowid=id, tokenize="trigram"
 	)
 	`)
 	if err != nil {
@@ -255,6 +386,21 @@ func createSchema(db *sql.DB, version string) error {
 		link, docId UNINDEXED,content=Links, tokenize="trigram"
 	)
 	`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec(`
+	CREATE VIRTUAL TABLE IF NOT EXISTS Citations_fts
+	USING fts5 (
+		citation, docId UNINDEXED, content=Citations, tokenize="trigram"
+	)
+	`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
 
 	_, err = tx.Exec(`
 	CREATE TRIGGER IF NOT EXISTS trig_ai_authors
@@ -379,12 +525,53 @@ func createSchema(db *sql.DB, version string) error {
 		return err
 	}
 
+	_, err = tx.Exec(`
+	CREATE TRIGGER IF NOT EXISTS trig_ai_citations
+	AFTER INSERT ON Citations
+	BEGIN
+		INSERT INTO Citations_fts(rowid, citation, docId)
+		VALUES (new.rowid, new.citation, new.docId);
+	END
+	`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec(`
+	CREATE TRIGGER IF NOT EXISTS trig_ad_citations
+	AFTER DELETE ON Citations
+	BEGIN
+		INSERT INTO Citations_fts(Citations_fts, rowid, citation, docId)
+		VALUES ('delete', old.rowid, old.citation, old.docId);
+	END
+	`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec(`
+	CREATE TRIGGER IF NOT EXISTS trig_au_citations
+	AFTER UPDATE ON Citations
+	BEGIN
+		INSERT INTO Citations_fts(Citations_fts, rowid, citation, docId)
+		VALUES ('delete', old.rowid, old.citation, old.docId);
+		INSERT INTO Citations_fts(rowid, citation, docId)
+		VALUES (new.rowid, new.citation, new.docId);
+	END
+	`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	_, err = tx.Exec(`
 	CREATE TRIGGER IF NOT EXISTS trig_ai_doc
 	AFTER INSERT ON Documents
 	BEGIN
-		INSERT INTO Documents_fts(rowid, path, headings, title, meta)
-		VALUES (new.id, new.path, new.headings, new.title, new.meta);
+		INSERT INTO Documents_fts(rowid, path, headings, title, meta, zettelId)
+		VALUES (new.id, new.path, new.headings, new.title, new.meta, new.zettelId);
 	END
 	`)
 	if err != nil {
@@ -396,8 +583,8 @@ func createSchema(db *sql.DB, version string) error {
 	CREATE TRIGGER IF NOT EXISTS trig_ad_doc
 	AFTER DELETE ON Documents
 	BEGIN
-		INSERT INTO Documents_fts(Documents_fts, rowid, path, headings, title, meta)
-		VALUES ('delete', old.id, old.path, old.headings, old.title, old.meta);
+		INSERT INTO Documents_fts(Documents_fts, rowid, path, headings, title, meta, zettelId)
+		VALUES ('delete', old.id, old.path, old.headings, old.title, old.meta, old.zettelId);
 	END
 	`)
 	if err != nil {
@@ -409,13 +596,14 @@ func createSchema(db *sql.DB, version string) error {
 	CREATE TRIGGER IF NOT EXISTS trig_au_doc
 	AFTER UPDATE ON Documents
 	BEGIN
-		INSERT INTO Documents_fts(Documents_fts, rowid, path, headings, title, meta)
-		VALUES ('delete', old.id, old.path, old.headings, old.title, old.meta);
-		INSERT INTO Documents_fts(rowid, path, headings, title, meta)
-		VALUES (new.id, new.path, new.headings, new.title, new.meta);
+		INSERT INTO Documents_fts(Documents_fts, rowid, path, headings, title, meta, zettelId)
+		VALUES ('delete', old.id, old.path, old.headings, old.title, old.meta, old.zettelId);
+		INSERT INTO Documents_fts(rowid, path, headings, title, meta, zettelId)
+		VALUES (new.id, new.path, new.headings, new.title, new.meta, new.zettelId);
 	END
 	`)
 	if err != nil {
+		tx.Rollback()
 		return err
 	}
 
@@ -429,9 +617,13 @@ func createSchema(db *sql.DB, version string) error {
 		d.fileTime,
 		d_fts.headings,
 		d_fts.meta,
+		d_fts.zettelId,
+		d.lang,
+		d.pinned,
 		a_fts.author,
 		t_fts.tag,
-		l_fts.link
+		l_fts.link,
+		c_fts.citation
 	FROM Documents d
 	JOIN Documents_fts as d_fts ON d.id = d_fts.rowid
 	LEFT JOIN DocumentAuthors da ON d.id = da.docId
@@ -439,12 +631,20 @@ func createSchema(db *sql.DB, version string) error {
 	LEFT JOIN DocumentTags dt ON d.id = dt.docId
 	LEFT JOIN Tags_fts t_fts ON dt.tagId = t_fts.rowid
 	LEFT JOIN Links_fts l_fts ON d.id = l_fts.docId
+	LEFT JOIN Citations_fts c_fts ON d.id = c_fts.docId
 	`)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 
+	if rebuildFts {
+		if _, err = tx.Exec("INSERT INTO Documents_fts(Documents_fts) VALUES('rebuild')"); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
 	if _, err = tx.Exec("PRAGMA OPTIMIZE"); err != nil {
 		tx.Rollback()
 		return err
@@ -458,15 +658,37 @@ func createSchema(db *sql.DB, version string) error {
 		tx.Rollback()
 		return err
 	}
+	if _, err = tx.Exec("INSERT OR REPLACE INTO Info (key, value, updated) VALUES (?,?,?)",
+		"schema", schemaVersion, t,
+	); err != nil {
+		tx.Rollback()
+		return err
+	}
 
 	return tx.Commit()
 }
 
 func (q Query) Close() error {
 	q.db.Exec("PRAGMA OPTIMIZE")
+	if err := q.Checkpoint(context.Background()); err != nil {
+		slog.Warn("Failed to checkpoint database on close", slog.String("err", err.Error()))
+	}
 	return q.db.Close()
 }
 
+// Copy the write ahead log into the database and truncate it
+func (q Query) Checkpoint(ctx context.Context) error {
+	var busy, logPages, checkpointed int
+	row := q.db.QueryRowContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)")
+	if err := row.Scan(&busy, &logPages, &checkpointed); err != nil {
+		return err
+	}
+	if busy != 0 {
+		return fmt.Errorf("Checkpoint blocked by another connection, %d of %d pages copied", checkpointed, logPages)
+	}
+	return nil
+}
+
 // Create an index
 func (q Query) Get(ctx context.Context, indexRoot string) (*index.Index, error) {
 	f := FillMany{Db: q.db}
@@ -484,95 +706,205 @@ func (q Query) Get(ctx context.Context, indexRoot string) (*index.Index, error)
 	return idx, nil
 }
 
+// Cache up to size documents returned by GetDocument and Execute,
+// a size of 0 disables the cache. Writes to the database from any process
+// empty the cache.
+func (q *Query) UseCache(size int) {
+	if size <= 0 {
+		q.cache = nil
+		return
+	}
+	q.cache = newDocCache(size)
+}
+
+// Count a write which may change documents so the caches of every process
+// using the database are emptied
+func (q Query) changed() {
+	q.cache.Clear()
+	if _, err := q.db.Exec(`
+	INSERT INTO Info (key, value, updated) VALUES (?, 1, ?)
+	ON CONFLICT (key) DO UPDATE SET value = value + 1, updated = excluded.updated
+	`, changesKey, time.Now().Unix()); err != nil {
+		slog.Warn("Cannot record database change", slog.String("err", err.Error()))
+	}
+}
+
+// Empty the cache if the database changed since its entries were read
+func (q Query) syncCache(ctx context.Context) {
+	var changes string
+	row := q.db.QueryRowContext(ctx, "SELECT value FROM Info WHERE key = ?", changesKey)
+	if err := row.Scan(&changes); err != nil && err != sql.ErrNoRows {
+		q.cache.Clear()
+		return
+	}
+	q.cache.Sync(changes)
+}
+
 // Write from index to database
 func (q Query) Put(ctx context.Context, idx index.Index) error {
+	defer q.changed()
 	p, err := NewPutMany(ctx, q.db, idx.Documents)
 	if err != nil {
 		return err
 	}
+	p.progress = idx.Progress
 
 	return p.Insert()
 }
 
 // Update database with values from index, removes entries for deleted files
 func (q Query) Update(ctx context.Context, idx index.Index) error {
-	u := UpdateMany{Db: q.db, PathDocs: idx.Documents}
+	defer q.changed()
+	u := UpdateMany{Db: q.db, PathDocs: idx.Documents, Progress: idx.Progress}
 	return u.Update(ctx)
 }
 
 func (q Query) GetDocument(ctx context.Context, path string) (*index.Document, error) {
+	if q.cache != nil {
+		q.syncCache(ctx)
+		var fileTime sql.NullInt64
+		row := q.db.QueryRowContext(ctx, "SELECT fileTime FROM Documents WHERE path = ?", path)
+		if err := row.Scan(&fileTime); err == nil && fileTime.Valid {
+			if doc, ok := q.cache.Get(path, time.Unix(fileTime.Int64, 0)); ok {
+				return doc, nil
+			}
+		}
+	}
+
 	f := Fill{Path: path, Db: q.db}
-	return f.Get(ctx)
+	doc, err := f.Get(ctx)
+	if err == nil {
+		q.cache.Add(doc)
+	}
+	return doc, err
 }
 
-// Shrink database by removing unused authors and tags and VACUUM-ing
-func (q Query) Tidy() error {
-	if _, err := q.db.Exec(`
+// Changes made while tidying a database
+type TidyReport struct {
+	AuthorsRemoved int64 `json:"authorsRemoved"`
+	TagsRemoved    int64 `json:"tagsRemoved"`
+	BytesReclaimed int64 `json:"bytesReclaimed"` // decrease in database size from VACUUM
+	SegmentsMerged int64 `json:"segmentsMerged"` // full text index segments merged by optimizing
+}
+
+// Full text indexes optimized while tidying
+var tidyFtsTables = []string{"Documents_fts", "Authors_fts", "Tags_fts"}
+
+// Shrink database by removing unused authors and tags, VACUUM-ing,
+// and merging full text index segments.
+// Authors with aliases are kept.
+func (q Query) Tidy(ctx context.Context) (TidyReport, error) {
+	var report TidyReport
+	res, err := q.db.ExecContext(ctx, `
 	DELETE FROM Authors
 	WHERE id NOT IN (
 		SELECT authorId FROM DocumentAuthors
-	)`); err != nil {
-		return err
+	) AND id NOT IN (
+		SELECT authorId FROM Aliases
+	)`)
+	if err != nil {
+		return report, err
+	}
+	if report.AuthorsRemoved, err = res.RowsAffected(); err != nil {
+		return report, err
 	}
 
-	if _, err := q.db.Exec(`
+	res, err = q.db.ExecContext(ctx, `
 	DELETE FROM Tags
 	WHERE id NOT IN (
 		SELECT tagId FROM DocumentTags
 	)
-	`); err != nil {
-		return err
+	`)
+	if err != nil {
+		return report, err
 	}
-
-	if _, err := q.db.Exec("VACUUM"); err != nil {
-		return err
+	if report.TagsRemoved, err = res.RowsAffected(); err != nil {
+		return report, err
 	}
 
-	if _, err := q.db.Exec("INSERT INTO Documents_fts(Documents_fts) VALUES('optimize')"); err != nil {
-		return err
+	sizeBefore, err := q.size(ctx)
+	if err != nil {
+		return report, err
 	}
-	if _, err := q.db.Exec("INSERT INTO Authors_fts(Authors_fts) VALUES('optimize')"); err != nil {
-		return err
+	if _, err := q.db.ExecContext(ctx, "VACUUM"); err != nil {
+		return report, err
 	}
-	if _, err := q.db.Exec("INSERT INTO Tags_fts(Tags_fts) VALUES('optimize')"); err != nil {
-		return err
+	sizeAfter, err := q.size(ctx)
+	if err != nil {
+		return report, err
 	}
+	report.BytesReclaimed = sizeBefore - sizeAfter
 
-	return nil
+	for _, table := range tidyFtsTables {
+		before, err := q.ftsSegments(ctx, table)
+		if err != nil {
+			return report, err
+		}
+		if _, err := q.db.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s(%s) VALUES('optimize')", table, table)); err != nil {
+			return report, err
+		}
+		after, err := q.ftsSegments(ctx, table)
+		if err != nil {
+			return report, err
+		}
+		report.SegmentsMerged += before - after
+	}
+
+	return report, q.Checkpoint(ctx)
 }
 
-func (q Query) PeriodicOptimize(ctx context.Context, d time.Duration) {
-	_, err := q.db.ExecContext(ctx, "PRAGMA OPTIMIZE optimize=0x10002")
-	if err != nil {
-		return
-	}
+// Size of the database in bytes
+func (q Query) size(ctx context.Context) (int64, error) {
+	var size int64
+	err := q.db.QueryRowContext(ctx,
+		"SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()",
+	).Scan(&size)
+	return size, err
+}
 
-	ticker := time.NewTicker(d)
+// Number of segments in a full text index, each segment has its own rows in the index's _idx table
+func (q Query) ftsSegments(ctx context.Context, table string) (int64, error) {
+	var n int64
+	err := q.db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(DISTINCT segid) FROM %s_idx", table)).Scan(&n)
+	return n, err
+}
+
+// Run a compiled artifact returning rows of (id, path, title, date, fileTime, headings, meta, zettelId, lang, pinned)
+// Pinned documents are ordered first.
+func (q Query) executeRows(ctx context.Context, artifact query.CompilationArtifact) (*sql.Rows, error) {
+	return q.executeColumns(ctx, artifact, "id, d.path, d.title, d.date, d.fileTime, d.headings, d.meta, d.zettelId, d.lang, d.pinned")
+}
 
-	for {
-		select {
-		case <-ticker.C:
-			slog.Debug("Running periodic db optimization",
-				slog.Int64("next", time.Now().Unix()+int64(d)),
-			)
-			if _, err := q.db.ExecContext(ctx, "PRAGMA OPTIMIZE"); err != nil {
-				return
+// Run a compiled artifact returning rows of columns from Documents d
+func (q Query) executeColumns(ctx context.Context, artifact query.CompilationArtifact, columns string) (*sql.Rows, error) {
+	var limit string
+	orderBy := "ORDER BY d.pinned DESC"
+	if artifact.SortBy != "" {
+		fields := strings.Split(artifact.SortBy, ",")
+		cols := make([]string, len(fields))
+		for i, field := range fields {
+			col, ok := sortColumns[strings.TrimSpace(field)]
+			if !ok {
+				return nil, fmt.Errorf("Cannot sort by unrecognized field %s", field)
 			}
-		case <-ctx.Done():
-			return
+			if artifact.SortDesc {
+				col += " DESC"
+			}
+			cols[i] = col
 		}
+		orderBy += ", " + strings.Join(cols, ", ")
 	}
-}
-
-func (q Query) Execute(ctx context.Context, artifact query.CompilationArtifact) (map[string]*index.Document, error) {
-	f := FillMany{
-		Db:   q.db,
-		docs: make(map[string]*index.Document),
-		ids:  make(map[string]int),
+	if artifact.Limit > 0 || artifact.Offset > 0 {
+		// sqlite requires a LIMIT to use OFFSET, negative limits are unbounded
+		n := artifact.Limit
+		if n <= 0 {
+			n = -1
+		}
+		limit = fmt.Sprintf("LIMIT %d OFFSET %d", n, max(artifact.Offset, 0))
 	}
 
 	compiledQuery := fmt.Sprintf(`
-	SELECT id, d.path, d.title, d.date, d.fileTime, d.headings, d.meta
+	SELECT %s
 	FROM Documents d
 	JOIN (
 		SELECT DISTINCT docId
@@ -580,9 +912,21 @@ func (q Query) Execute(ctx context.Context, artifact query.CompilationArtifact)
 		WHERE %s
 	) s
 	ON d.id = s.docId
-	`, artifact.Query)
+	%s
+	%s
+	`, columns, artifact.Query, orderBy, limit)
+
+	return q.db.QueryContext(ctx, compiledQuery, artifact.Args...)
+}
+
+func (q Query) Execute(ctx context.Context, artifact query.CompilationArtifact) (map[string]*index.Document, error) {
+	f := FillMany{
+		Db:   q.db,
+		docs: make(map[string]*index.Document),
+		ids:  make(map[string]int),
+	}
 
-	rows, err := q.db.QueryContext(ctx, compiledQuery, artifact.Args...)
+	rows, err := q.executeRows(ctx, artifact)
 	if err != nil {
 		return nil, err
 	}
@@ -593,28 +937,119 @@ func (q Query) Execute(ctx context.Context, artifact query.CompilationArtifact)
 	}
 	rows.Close()
 
+	// only fill relations for documents missing from the cache
+	if q.cache != nil {
+		q.syncCache(ctx)
+		for path, doc := range f.docs {
+			if cached, ok := q.cache.Get(path, doc.FileTime); ok {
+				f.docs[path] = cached
+				delete(f.ids, path)
+			}
+		}
+	}
+
 	if err := f.tags(ctx); err != nil {
 		return nil, err
 	}
 	if err := f.links(ctx); err != nil {
 		return nil, err
 	}
+	if err := f.citations(ctx); err != nil {
+		return nil, err
+	}
+	if err := f.metaFields(ctx); err != nil {
+		return nil, err
+	}
 	if err := f.authors(ctx); err != nil {
 		return nil, err
 	}
 
+	for path := range f.ids {
+		q.cache.Add(f.docs[path])
+	}
+
 	return f.docs, nil
 }
 
+// Execute an artifact calling fn for each document as its row is read.
+// Documents are passed in the artifact's sort order without authors, tags,
+// or links filled and are not retained, so output can begin before the
+// query finishes. Stops at and returns the first error from fn.
+func (q Query) ExecuteFunc(ctx context.Context, artifact query.CompilationArtifact, fn func(*index.Document) error) error {
+	rows, err := q.executeRows(ctx, artifact)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		_, doc, err := scanDocument(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// Run a compiled artifact returning only the paths of matching documents
+func (q Query) ExecutePaths(ctx context.Context, artifact query.CompilationArtifact) ([]string, error) {
+	rows, err := q.executeColumns(ctx, artifact, "d.path")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	paths := make([]string, 0)
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, rows.Err()
+}
+
+// Register the functions compiled queries use
+func registerFuncs(sc *sqlite3.SQLiteConn, fuzzyDistance int) error {
+	if err := sc.RegisterFunc("regexp", regex, true); err != nil {
+		return err
+	}
+	fuzzyDistance = max(fuzzyDistance, 0)
+	if err := sc.RegisterFunc("fuzzy", fuzzyMatcher(fuzzyDistance), true); err != nil {
+		return err
+	}
+	return sc.RegisterFunc("fuzzy_scan", fuzzyScanner(fuzzyDistance), true)
+}
+
 func regex(re, s string) (bool, error) {
 	return regexp.MatchString(re, s)
 }
 
+// Opens connections from driver, used to give a database its own connect hook
+type connector struct {
+	dsn    string
+	driver *sqlite3.SQLiteDriver
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c connector) Driver() driver.Driver {
+	return c.driver
+}
+
 func init() {
 	sql.Register("sqlite3_regex",
 		&sqlite3.SQLiteDriver{
 			ConnectHook: func(sc *sqlite3.SQLiteConn) error {
-				return sc.RegisterFunc("regexp", regex, true)
+				return registerFuncs(sc, DefaultDBOpts.FuzzyDistance)
 			},
 		},
 	)
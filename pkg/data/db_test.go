@@ -1,10 +1,18 @@
 package data_test
 
 import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
 	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/jpappel/atlas/pkg/data"
+	"github.com/jpappel/atlas/pkg/index"
+	"github.com/jpappel/atlas/pkg/query"
 )
 
 func TestBatchQuery(t *testing.T) {
@@ -63,3 +71,516 @@ func TestBatchQuery(t *testing.T) {
 		})
 	}
 }
+
+func TestQuery_ExecuteFunc(t *testing.T) {
+	q := data.NewMemQuery("test")
+	defer q.Close()
+	docs := benchDocuments(20)
+	if err := q.Put(t.Context(), index.Index{Documents: docs}); err != nil {
+		t.Fatal(err)
+	}
+
+	artifact, err := query.Compile(`a="Rob Pike"`, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	artifact.SortBy = "path"
+	artifact.SortDesc = true
+
+	want, err := q.Execute(t.Context(), artifact)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	err = q.ExecuteFunc(t.Context(), artifact, func(doc *index.Document) error {
+		if doc.Authors != nil || doc.Tags != nil || doc.Links != nil {
+			t.Errorf("Expected relations of %s to be unfilled", doc.Path)
+		}
+		if wantDoc := want[doc.Path]; wantDoc == nil || wantDoc.Title != doc.Title {
+			t.Errorf("Unexpected document %s", doc.Path)
+		}
+		got = append(got, doc.Path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	if len(got) != len(want) {
+		t.Errorf("Streamed %d documents, want %d", len(got), len(want))
+	}
+	if !slices.IsSortedFunc(got, func(a, b string) int { return -strings.Compare(a, b) }) {
+		t.Error("Expected documents in descending path order, got", got)
+	}
+
+	errStop := errors.New("stop")
+	n := 0
+	err = q.ExecuteFunc(t.Context(), artifact, func(*index.Document) error {
+		n++
+		return errStop
+	})
+	if !errors.Is(err, errStop) || n != 1 {
+		t.Errorf("Expected to stop after first error, got %v after %d documents", err, n)
+	}
+}
+
+func TestQuery_Checkpoint(t *testing.T) {
+	path := t.TempDir() + "/test.db"
+	q := data.NewQuery(path, "test", data.DBOpts{BusyTimeout: time.Second})
+	defer q.Close()
+
+	if err := q.Put(t.Context(), index.Index{Documents: benchDocuments(50)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// automatic checkpoints are disabled so the log keeps every write
+	info, err := os.Stat(path + "-wal")
+	if err != nil {
+		t.Fatal(err)
+	} else if info.Size() == 0 {
+		t.Fatal("Expected a non-empty write ahead log")
+	}
+
+	if err := q.Checkpoint(t.Context()); err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	if info, err := os.Stat(path + "-wal"); err != nil {
+		t.Fatal(err)
+	} else if info.Size() != 0 {
+		t.Errorf("Expected checkpoint to truncate write ahead log, got %d bytes", info.Size())
+	}
+}
+
+func TestQuery_Tidy(t *testing.T) {
+	ctx := t.Context()
+	q := data.NewMemQuery("test")
+	defer q.Close()
+
+	docs := map[string]*index.Document{
+		"/a": {Path: "/a", FileTime: time.Unix(1, 0), Authors: []string{"Rob Pike"}, Tags: []string{"go", "plan9"}},
+		"/b": {Path: "/b", FileTime: time.Unix(1, 0), Authors: []string{"Ken Thompson"}, Tags: []string{"unix", "c", "go"}},
+	}
+	if err := q.Put(ctx, index.Index{Documents: docs}); err != nil {
+		t.Fatal(err)
+	}
+	delete(docs, "/b")
+	if err := q.Update(ctx, index.Index{Documents: docs}); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := q.Tidy(ctx)
+	if err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	if report.AuthorsRemoved != 1 {
+		t.Errorf("Removed %d authors, want 1", report.AuthorsRemoved)
+	}
+	if report.TagsRemoved != 2 {
+		t.Errorf("Removed %d tags, want 2", report.TagsRemoved)
+	}
+	if report.SegmentsMerged < 0 {
+		t.Errorf("Merged %d segments, want at least 0", report.SegmentsMerged)
+	}
+
+	// a tidy database has nothing left to remove
+	report, err = q.Tidy(ctx)
+	if err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	if report.AuthorsRemoved != 0 || report.TagsRemoved != 0 || report.SegmentsMerged != 0 {
+		t.Errorf("Expected an empty report from a second tidy, got %+v", report)
+	}
+}
+
+// Generate n documents sharing a small pool of authors and tags
+func benchDocuments(n int) map[string]*index.Document {
+	authors := []string{"Ken Thompson", "Rob Pike", "Robert Griesemer", "Dennis Ritchie"}
+	tags := []string{"go", "plan9", "unix", "c", "notes", "draft"}
+
+	docs := make(map[string]*index.Document, n)
+	for i := range n {
+		path := fmt.Sprintf("/notes/%04d.md", i)
+		docs[path] = &index.Document{
+			Path:     path,
+			Title:    fmt.Sprintf("Weekly notes %d", i),
+			Date:     time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, i),
+			FileTime: time.Unix(int64(i), 0),
+			Authors:  []string{authors[i%len(authors)], authors[(i+1)%len(authors)]},
+			Tags:     []string{tags[i%len(tags)], tags[(i+3)%len(tags)]},
+			Links:    []string{fmt.Sprintf("/notes/%04d.md", (i+1)%n)},
+			Headings: "# Agenda\n# Action Items\n",
+		}
+	}
+	return docs
+}
+
+func BenchmarkQuery_Put(b *testing.B) {
+	docs := benchDocuments(1000)
+	for b.Loop() {
+		b.StopTimer()
+		q := data.NewMemQuery("bench")
+		b.StartTimer()
+
+		if err := q.Put(b.Context(), index.Index{Documents: docs}); err != nil {
+			b.Fatal(err)
+		}
+
+		b.StopTimer()
+		q.Close()
+		b.StartTimer()
+	}
+}
+
+func BenchmarkQuery_Execute(b *testing.B) {
+	q := data.NewMemQuery("bench")
+	defer q.Close()
+	if err := q.Put(b.Context(), index.Index{Documents: benchDocuments(1000)}); err != nil {
+		b.Fatal(err)
+	}
+
+	artifact, err := query.Compile(`T:notes (or a="Rob Pike" a="Ken Thompson") d>="January 1, 2021" -t=draft`, 0, 4)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for b.Loop() {
+		if _, err := q.Execute(b.Context(), artifact); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkQuery_ExecuteFunc(b *testing.B) {
+	q := data.NewMemQuery("bench")
+	defer q.Close()
+	if err := q.Put(b.Context(), index.Index{Documents: benchDocuments(1000)}); err != nil {
+		b.Fatal(err)
+	}
+
+	artifact, err := query.Compile(`T:notes (or a="Rob Pike" a="Ken Thompson") d>="January 1, 2021" -t=draft`, 0, 4)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for b.Loop() {
+		if err := q.ExecuteFunc(b.Context(), artifact, func(*index.Document) error { return nil }); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+type queryPathsTest struct {
+	query string
+	want  []string
+}
+
+// Put docs into an in memory database then check the paths matched by each
+// query. If check is not nil it is called for every matched document.
+func assertQueryPaths(t *testing.T, docs map[string]*index.Document, tests []queryPathsTest, check func(t *testing.T, got, want *index.Document)) {
+	t.Helper()
+	q := data.NewMemQuery("test")
+	defer q.Close()
+	if err := q.Put(t.Context(), index.Index{Documents: docs}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			artifact, err := query.Compile(tt.query, 0, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := q.Execute(t.Context(), artifact)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotPaths := slices.Sorted(maps.Keys(got))
+			if !slices.Equal(gotPaths, tt.want) {
+				t.Errorf("Got %v, want %v", gotPaths, tt.want)
+			}
+			if check == nil {
+				return
+			}
+			for _, p := range gotPaths {
+				check(t, got[p], docs[p])
+			}
+		})
+	}
+}
+
+func TestQuery_Execute_ZettelID(t *testing.T) {
+	docs := map[string]*index.Document{
+		"/notes/a.md": {Path: "/notes/a.md", ID: "202401121230", Title: "A"},
+		"/notes/b.md": {Path: "/notes/b.md", ID: "202401121231", Title: "B"},
+		"/notes/c.md": {Path: "/notes/c.md", Title: "C"},
+	}
+
+	tests := []queryPathsTest{
+		{"id=202401121230", []string{"/notes/a.md"}},
+		{"id:2024011212", []string{"/notes/a.md", "/notes/b.md"}},
+		{"-id=202401121230 id:2024", []string{"/notes/b.md"}},
+	}
+	assertQueryPaths(t, docs, tests, func(t *testing.T, got, want *index.Document) {
+		if got.ID != want.ID {
+			t.Errorf("Got id %q for %s, want %q", got.ID, got.Path, want.ID)
+		}
+	})
+}
+
+func TestQuery_Execute_TagHierarchy(t *testing.T) {
+	docs := map[string]*index.Document{
+		"/notes/a.md": {Path: "/notes/a.md", Tags: []string{"work"}},
+		"/notes/b.md": {Path: "/notes/b.md", Tags: []string{"work/project/atlas"}},
+		"/notes/c.md": {Path: "/notes/c.md", Tags: []string{"workshop", "home"}},
+		"/notes/d.md": {Path: "/notes/d.md"},
+	}
+
+	tests := []queryPathsTest{
+		{"t=work", []string{"/notes/a.md", "/notes/b.md"}},
+		{"t=work/", []string{"/notes/a.md", "/notes/b.md"}},
+		{"t=work/project", []string{"/notes/b.md"}},
+		{"t=work/project/atlas", []string{"/notes/b.md"}},
+		{"t=work/proj", []string{}},
+		{"-t=work p:notes", []string{"/notes/c.md", "/notes/d.md"}},
+		{"t!=work/project p:notes", []string{"/notes/a.md", "/notes/c.md", "/notes/d.md"}},
+		{"(or t=home t=work/project)", []string{"/notes/b.md", "/notes/c.md"}},
+		{"t=work t=home", []string{}},
+	}
+	assertQueryPaths(t, docs, tests, nil)
+}
+
+func TestQuery_Execute_AuthorEmails(t *testing.T) {
+	docs := map[string]*index.Document{
+		"/notes/a.md": {
+			Path:    "/notes/a.md",
+			Authors: []string{"Rob Pike", "Ken Thompson"},
+			Emails:  map[string]string{"Rob Pike": "r@golang.org"},
+		},
+		"/notes/b.md": {Path: "/notes/b.md", Authors: []string{"Ken Thompson"}},
+		"/notes/c.md": {Path: "/notes/c.md"},
+	}
+
+	tests := []queryPathsTest{
+		{`a="Rob Pike"`, []string{"/notes/a.md"}},
+		{"a=r@golang.org", []string{"/notes/a.md"}},
+		{"a=R@GoLang.org", []string{"/notes/a.md"}},
+		{`a="Robert Pike <r@golang.org>"`, []string{"/notes/a.md"}},
+		{`a="Ken Thompson" -a=r@golang.org`, []string{"/notes/b.md"}},
+		{`a!="Ken Thompson" p:notes`, []string{"/notes/c.md"}},
+	}
+	assertQueryPaths(t, docs, tests, func(t *testing.T, got, want *index.Document) {
+		if !maps.Equal(got.Emails, want.Emails) {
+			t.Errorf("Got emails %v for %s, want %v", got.Emails, got.Path, want.Emails)
+		}
+	})
+}
+
+func TestQuery_Execute_Language(t *testing.T) {
+	docs := map[string]*index.Document{
+		"/notes/a.md": {Path: "/notes/a.md", Language: "en"},
+		"/notes/b.md": {Path: "/notes/b.md", Language: "de"},
+		"/notes/c.md": {Path: "/notes/c.md"},
+	}
+
+	tests := []queryPathsTest{
+		{"lang=de", []string{"/notes/b.md"}},
+		{"lang:DE", []string{"/notes/b.md"}},
+		{"lang=DE", []string{"/notes/b.md"}},
+		{"lang=en-US", []string{"/notes/a.md"}},
+		{"lang!=en-GB", []string{"/notes/b.md"}},
+		{"lang!=de", []string{"/notes/a.md"}},
+		{"(or lang=en lang=de)", []string{"/notes/a.md", "/notes/b.md"}},
+		{"lang/^e", []string{"/notes/a.md"}},
+		{"(or lang:EN lang:de)", []string{"/notes/a.md", "/notes/b.md"}},
+	}
+	assertQueryPaths(t, docs, tests, func(t *testing.T, got, want *index.Document) {
+		if got.Language != want.Language {
+			t.Errorf("Got language %q for %s, want %q", got.Language, got.Path, want.Language)
+		}
+	})
+}
+
+func TestQuery_Execute_Citations(t *testing.T) {
+	docs := map[string]*index.Document{
+		"/notes/a.md": {Path: "/notes/a.md", Citations: []string{"knuth1974", "pike1984"}},
+		"/notes/b.md": {Path: "/notes/b.md", Citations: []string{"knuth1974"}},
+		"/notes/c.md": {Path: "/notes/c.md"},
+	}
+
+	tests := []queryPathsTest{
+		{"cite=knuth1974", []string{"/notes/a.md", "/notes/b.md"}},
+		{"c:pike", []string{"/notes/a.md"}},
+	}
+	assertQueryPaths(t, docs, tests, func(t *testing.T, got, want *index.Document) {
+		if !slices.Equal(slices.Sorted(slices.Values(got.Citations)), want.Citations) {
+			t.Errorf("Got citations %v for %s, want %v", got.Citations, got.Path, want.Citations)
+		}
+	})
+}
+
+func TestQuery_Execute_MetaFields(t *testing.T) {
+	docs := map[string]*index.Document{
+		"/notes/a.md": {Path: "/notes/a.md", MetaFields: []index.MetaField{
+			{Key: "draft", Value: true}, {Key: "status", Value: "in progress"},
+			{Key: "rating", Value: 4.0}, {Key: "priority", Value: 1.0},
+			{Key: "project.name", Value: "atlas"},
+		}},
+		"/notes/b.md": {Path: "/notes/b.md", MetaFields: []index.MetaField{
+			{Key: "draft", Value: false}, {Key: "status", Value: "true"},
+			{Key: "rating", Value: 2.5}, {Key: "priority", Value: 3.0},
+		}},
+		"/notes/c.md": {Path: "/notes/c.md"},
+	}
+
+	tests := []queryPathsTest{
+		{"m.draft=true", []string{"/notes/a.md"}},
+		{"m.draft!=true", []string{"/notes/b.md", "/notes/c.md"}},
+		{"-m.draft=false p:notes", []string{"/notes/a.md", "/notes/c.md"}},
+		{"m.status=true", []string{}},
+		{"m.status:progress m.draft=true", []string{"/notes/a.md"}},
+		{"m.status/^tr", []string{"/notes/b.md"}},
+		{"m.rating>=4", []string{"/notes/a.md"}},
+		{"m.rating>2", []string{"/notes/a.md", "/notes/b.md"}},
+		{"m.priority<2", []string{"/notes/a.md"}},
+		{"m.rating=2.5", []string{"/notes/b.md"}},
+		{"-m.rating<=2.5", []string{"/notes/a.md", "/notes/c.md"}},
+		{`m.rating="4"`, []string{}},
+		{"m.project.name=atlas", []string{"/notes/a.md"}},
+		{"m.project=atlas", []string{}},
+	}
+	assertQueryPaths(t, docs, tests, func(t *testing.T, got, want *index.Document) {
+		if !slices.Equal(got.MetaFields, want.MetaFields) {
+			t.Errorf("Got meta fields %v for %s, want %v", got.MetaFields, got.Path, want.MetaFields)
+		}
+	})
+}
+
+func TestQuery_Execute_TypoTolerant(t *testing.T) {
+	docs := map[string]*index.Document{
+		"/notes/a.md": {
+			Path:     "/notes/a.md",
+			Title:    "Meeting Minutes",
+			Authors:  []string{"John Smith"},
+			Tags:     []string{"work"},
+			Headings: "# Agenda\n",
+		},
+		"/notes/b.md": {
+			Path:    "/notes/b.md",
+			Title:   "Reading List",
+			Authors: []string{"Jane Smythe", "Ken Thompson"},
+			Tags:    []string{"reading"},
+		},
+		"/notes/c.md": {Path: "/notes/c.md", Title: "Plan 9", Tags: []string{"naïveté"}},
+	}
+
+	tests := []queryPathsTest{
+		{`a?="jon smth"`, []string{"/notes/a.md"}},
+		{"t?=naivete", []string{"/notes/c.md"}},
+		{"t?=naïvetés", []string{"/notes/c.md"}},
+		{`a?="jane smith"`, []string{"/notes/b.md"}},
+		{"a?=smith", []string{}},
+		{`a?="ken thomsonn"`, []string{"/notes/b.md"}},
+		{"t?=wrok", []string{"/notes/a.md"}},
+		{"t?=works", []string{"/notes/a.md"}},
+		{"T?=metting", []string{"/notes/a.md"}},
+		{`T?="reeding lst"`, []string{"/notes/b.md"}},
+		{"h?=agneda", []string{"/notes/a.md"}},
+		{"-T?=metting", []string{"/notes/b.md", "/notes/c.md"}},
+		{`(or t?=wrok T?="plan 9")`, []string{"/notes/a.md", "/notes/c.md"}},
+	}
+	assertQueryPaths(t, docs, tests, nil)
+
+	t.Run("distance", func(t *testing.T) {
+		exact := data.NewQuery(t.TempDir()+"/test.db", "test", data.DBOpts{BusyTimeout: time.Second})
+		defer exact.Close()
+		if err := exact.Put(t.Context(), index.Index{Documents: docs}); err != nil {
+			t.Fatal(err)
+		}
+
+		for query, want := range map[string]int{`a?="jon smth"`: 0, `a?="john smith"`: 1} {
+			artifact, err := exact.Compile(t.Context(), query, 0, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := exact.Execute(t.Context(), artifact)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != want {
+				t.Errorf("Got %d results for %s with a distance of 0, want %d", len(got), query, want)
+			}
+		}
+	})
+}
+
+func TestQuery_Execute_LimitOffset(t *testing.T) {
+	q := data.NewMemQuery("test")
+	defer q.Close()
+	docs := map[string]*index.Document{}
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		p := "/notes/" + name + ".md"
+		docs[p] = &index.Document{Path: p, Title: "Note " + name}
+	}
+	if err := q.Put(t.Context(), index.Index{Documents: docs}); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.SetPinned(t.Context(), true, "/notes/e.md"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		limit  int
+		offset int
+		desc   bool
+		want   []string
+	}{
+		{"limit", 2, 0, false, []string{"/notes/e.md", "/notes/a.md"}},
+		{"limit and offset", 2, 1, false, []string{"/notes/a.md", "/notes/b.md"}},
+		{"descending", 2, 1, true, []string{"/notes/d.md", "/notes/c.md"}},
+		{"offset only", 0, 3, false, []string{"/notes/c.md", "/notes/d.md"}},
+		{"offset past end", 2, 10, false, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			artifact, err := query.Compile("T:note", 0, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			artifact.SortBy = "path"
+			artifact.SortDesc = tt.desc
+			artifact.Limit = tt.limit
+			artifact.Offset = tt.offset
+
+			got := []string{}
+			err = q.ExecuteFunc(t.Context(), artifact, func(doc *index.Document) error {
+				got = append(got, doc.Path)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Got %v, want %v", got, tt.want)
+			}
+
+			// only the selected rows are read and filled
+			results, err := q.Execute(t.Context(), artifact)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gotPaths := slices.Sorted(maps.Keys(results)); !slices.Equal(gotPaths, slices.Sorted(slices.Values(tt.want))) {
+				t.Errorf("Execute() returned %v, want %v", gotPaths, tt.want)
+			}
+
+			paths, err := q.ExecutePaths(t.Context(), artifact)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(paths, tt.want) {
+				t.Errorf("ExecutePaths() returned %v, want %v", paths, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package data
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/jpappel/atlas/pkg/index"
+	"github.com/jpappel/atlas/pkg/query"
+)
+
+// Write documents to w as newline delimited JSON ordered by path
+func DumpDocuments(w io.Writer, docs map[string]*index.Document) error {
+	enc := json.NewEncoder(w)
+	for _, path := range slices.Sorted(maps.Keys(docs)) {
+		if err := enc.Encode(docs[path]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Read newline delimited JSON documents from r
+func LoadDocuments(r io.Reader) (map[string]*index.Document, error) {
+	docs := make(map[string]*index.Document)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		doc := &index.Document{}
+		if err := json.Unmarshal([]byte(line), doc); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		} else if doc.Path == "" {
+			return nil, fmt.Errorf("line %d: document is missing a path", lineNum)
+		}
+		docs[doc.Path] = doc
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return docs, nil
+}
+
+// Write all documents, or only those matching artifact when it is non-nil,
+// as newline delimited JSON. Returns the number of exported documents.
+func (q Query) Export(ctx context.Context, w io.Writer, artifact *query.CompilationArtifact) (int, error) {
+	var docs map[string]*index.Document
+	var err error
+	if artifact != nil {
+		docs, err = q.Execute(ctx, *artifact)
+	} else {
+		f := FillMany{Db: q.db}
+		docs, err = f.Get(ctx)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return len(docs), DumpDocuments(w, docs)
+}
+
+// Add documents read as newline delimited JSON from r, replacing existing
+// documents with an older filetime. Returns the number of written documents.
+func (q Query) Import(ctx context.Context, r io.Reader) (int, error) {
+	defer q.changed()
+	docs, err := LoadDocuments(r)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, path := range slices.Sorted(maps.Keys(docs)) {
+		u := NewUpdate(ctx, q.db, *docs[path])
+		if err := u.Update(ctx); err != nil {
+			return n, fmt.Errorf("failed to import %s: %w", path, err)
+		}
+		if u.Written {
+			n++
+		}
+	}
+
+	return n, nil
+}
@@ -0,0 +1,134 @@
+package data_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jpappel/atlas/pkg/data"
+	"github.com/jpappel/atlas/pkg/index"
+)
+
+func TestDumpLoadDocuments(t *testing.T) {
+	tests := []struct {
+		name string
+		docs map[string]*index.Document
+	}{
+		{"empty", map[string]*index.Document{}},
+		{"multiple docs", map[string]*index.Document{
+			"/file": {
+				Path:     "/file",
+				Title:    "A file",
+				Date:     time.Unix(1, 0).UTC(),
+				FileTime: time.Unix(2, 0).UTC(),
+				Authors:  []string{"jp"},
+				Tags:     []string{"foo", "bar"},
+				Links:    []string{"link_1"},
+				Headings: "# A Heading\n",
+			},
+			"/file2": {
+				Path:      "/file2",
+				Title:     "Another file",
+				FileTime:  time.Unix(4, 0).UTC(),
+				OtherMeta: "draft: true\n",
+			},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			if err := data.DumpDocuments(buf, tt.docs); err != nil {
+				t.Fatal("Unexpected error while dumping:", err)
+			}
+
+			if lines := strings.Count(buf.String(), "\n"); lines != len(tt.docs) {
+				t.Errorf("Expected one line per document: got %d want %d", lines, len(tt.docs))
+			}
+
+			got, err := data.LoadDocuments(buf)
+			if err != nil {
+				t.Fatal("Unexpected error while loading:", err)
+			}
+
+			if len(got) != len(tt.docs) {
+				t.Fatalf("Recieved incorrect amount of documents: got %d want %d", len(got), len(tt.docs))
+			}
+			for path, wantDoc := range tt.docs {
+				gotDoc, ok := got[path]
+				if !ok {
+					t.Errorf("Can't find %s in loaded docs", path)
+					continue
+				}
+				if !gotDoc.Equal(*wantDoc) {
+					t.Errorf("%s not equal %+v\nWant %+v", path, gotDoc, wantDoc)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadDocuments_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"malformed json", `{"path": "/file"` + "\n"},
+		{"missing path", `{"title": "no path"}` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := data.LoadDocuments(strings.NewReader(tt.input)); err == nil {
+				t.Error("Expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestQuery_Import(t *testing.T) {
+	q := data.NewMemQuery("test")
+	defer q.Close()
+
+	stored := map[string]*index.Document{
+		"/newer": {Path: "/newer", Title: "Stored newer", FileTime: time.Unix(10, 0).UTC()},
+		"/older": {Path: "/older", Title: "Stored older", FileTime: time.Unix(1, 0).UTC()},
+	}
+	if err := q.Put(t.Context(), index.Index{Root: "/", Documents: stored}); err != nil {
+		t.Fatal("Unexpected error putting documents:", err)
+	}
+
+	imported := map[string]*index.Document{
+		"/newer": {Path: "/newer", Title: "Imported older", FileTime: time.Unix(5, 0).UTC()},
+		"/older": {Path: "/older", Title: "Imported newer", FileTime: time.Unix(5, 0).UTC()},
+		"/added": {Path: "/added", Title: "Imported added", FileTime: time.Unix(5, 0).UTC()},
+	}
+	buf := &bytes.Buffer{}
+	if err := data.DumpDocuments(buf, imported); err != nil {
+		t.Fatal("Unexpected error while dumping:", err)
+	}
+
+	n, err := q.Import(t.Context(), buf)
+	if err != nil {
+		t.Fatal("Unexpected error while importing:", err)
+	} else if n != 2 {
+		t.Errorf("Imported %d documents, want 2", n)
+	}
+
+	idx, err := q.Get(t.Context(), "/")
+	if err != nil {
+		t.Fatal("Unexpected error getting documents:", err)
+	}
+	wantTitles := map[string]string{
+		"/newer": "Stored newer",
+		"/older": "Imported newer",
+		"/added": "Imported added",
+	}
+	for path, want := range wantTitles {
+		doc, ok := idx.Documents[path]
+		if !ok {
+			t.Errorf("Missing %s after import", path)
+		} else if doc.Title != want {
+			t.Errorf("%s has title %q, want %q", path, doc.Title, want)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package data
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jpappel/atlas/pkg/util"
+)
+
+// Create the SQL function fuzzy(s, pattern, substring) which reports if s is
+// within distance edits of pattern ignoring case. Substring matches compare
+// pattern against the closest part of s instead of the whole value.
+func fuzzyMatcher(distance int) func(any, string, bool) bool {
+	return func(v any, pattern string, substring bool) bool {
+		s, ok := v.(string)
+		if !ok {
+			return false
+		}
+
+		s, pattern = strings.ToLower(s), strings.ToLower(pattern)
+		if substring {
+			return util.SubstringDistance(s, pattern) <= distance
+		}
+		return util.LevensteinDistanceCeil(s, pattern, distance+1) <= distance
+	}
+}
+
+// Create the SQL function fuzzy_scan(pattern) which reports if pattern is too
+// short to find every match by its trigrams.
+//
+// An edit changes at most three trigrams, so a value within distance edits of a
+// pattern shares one of its trigrams only when the pattern has more than
+// 3*distance of them.
+func fuzzyScanner(distance int) func(string) bool {
+	return func(pattern string) bool {
+		return utf8.RuneCountInString(pattern)-2 <= 3*distance
+	}
+}
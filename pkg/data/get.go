@@ -38,6 +38,12 @@ func (f Fill) Get(ctx context.Context) (*index.Document, error) {
 	if err := f.links(ctx); err != nil {
 		return nil, err
 	}
+	if err := f.citations(ctx); err != nil {
+		return nil, err
+	}
+	if err := f.metaFields(ctx); err != nil {
+		return nil, err
+	}
 
 	return f.doc, nil
 }
@@ -55,6 +61,12 @@ func (f *FillMany) Get(ctx context.Context) (map[string]*index.Document, error)
 	if err := f.links(ctx); err != nil {
 		return nil, err
 	}
+	if err := f.citations(ctx); err != nil {
+		return nil, err
+	}
+	if err := f.metaFields(ctx); err != nil {
+		return nil, err
+	}
 	if err := f.authors(ctx); err != nil {
 		return nil, err
 	}
@@ -68,13 +80,15 @@ func (f *Fill) document(ctx context.Context) error {
 	var fileTimeEpoch sql.NullInt64
 	var headings sql.NullString
 	var meta sql.NullString
+	var zettelId sql.NullString
+	var lang sql.NullString
 
 	row := f.Db.QueryRowContext(ctx, `
-	SELECT id, title, date, fileTime, headings, meta
+	SELECT id, title, date, fileTime, headings, meta, zettelId, lang, pinned
 	FROM Documents
 	WHERE path = ?
 	`, f.Path)
-	if err := row.Scan(&f.id, &title, &dateEpoch, &fileTimeEpoch, &headings, &meta); err != nil {
+	if err := row.Scan(&f.id, &title, &dateEpoch, &fileTimeEpoch, &headings, &meta, &zettelId, &lang, &f.doc.Pinned); err != nil {
 		return err
 	}
 
@@ -93,16 +107,22 @@ func (f *Fill) document(ctx context.Context) error {
 	if meta.Valid {
 		f.doc.OtherMeta = meta.String
 	}
+	if zettelId.Valid {
+		f.doc.ID = zettelId.String
+	}
+	if lang.Valid {
+		f.doc.Language = lang.String
+	}
 	return nil
 }
 
-// Fill document info for documents provided by rows (id, path, title, date, fileTime, meta)
+// Fill document info for documents provided by rows (id, path, title, date, fileTime, headings, meta, zettelId, lang, pinned)
 // pass nil rows to get all documents in the database.
 func (f *FillMany) documents(ctx context.Context, rows *sql.Rows) error {
 	if rows == nil {
 		var err error
 		rows, err = f.Db.QueryContext(ctx, `
-	SELECT id, path, title, date, fileTime, headings, meta
+	SELECT id, path, title, date, fileTime, headings, meta, zettelId, lang, pinned
 	FROM Documents
 	`)
 		if err != nil {
@@ -111,7 +131,7 @@ func (f *FillMany) documents(ctx context.Context, rows *sql.Rows) error {
 		defer rows.Close()
 	} else if cols, err := rows.ColumnTypes(); err != nil {
 		return err
-	} else if len(cols) != 7 {
+	} else if len(cols) != 10 {
 		return fmt.Errorf("Not enough columns to fill documents with")
 	} else if t := cols[0].DatabaseTypeName(); t != "INTEGER" {
 		return fmt.Errorf("Expected integer for id column fill, got %s", t)
@@ -127,47 +147,71 @@ func (f *FillMany) documents(ctx context.Context, rows *sql.Rows) error {
 		return fmt.Errorf("Expected text for headings column fill, got %s", t)
 	} else if t := cols[6].DatabaseTypeName(); t != "BLOB" {
 		return fmt.Errorf("Expected text for meta column fill, got %s", t)
+	} else if t := cols[7].DatabaseTypeName(); t != "TEXT" {
+		return fmt.Errorf("Expected text for zettelId column fill, got %s", t)
+	} else if t := cols[8].DatabaseTypeName(); t != "TEXT" {
+		return fmt.Errorf("Expected text for lang column fill, got %s", t)
+	} else if t := cols[9].DatabaseTypeName(); t != "INT" {
+		return fmt.Errorf("Expected integer for pinned column fill, got %s", t)
 	}
 
-	var id int
-	var docPath string
-	var title, headings, meta sql.NullString
-	var dateEpoch, filetimeEpoch sql.NullInt64
-
 	for rows.Next() {
-		if err := rows.Scan(&id, &docPath, &title, &dateEpoch, &filetimeEpoch, &headings, &meta); err != nil {
+		id, doc, err := scanDocument(rows)
+		if err != nil {
 			return err
 		}
 
-		doc := &index.Document{
-			Path: docPath,
-		}
+		f.docs[doc.Path] = doc
+		f.ids[doc.Path] = id
+	}
 
-		if title.Valid {
-			doc.Title = title.String
-		}
-		if dateEpoch.Valid {
-			doc.Date = time.Unix(dateEpoch.Int64, 0)
-		}
-		if filetimeEpoch.Valid {
-			doc.FileTime = time.Unix(filetimeEpoch.Int64, 0)
-		}
-		if headings.Valid {
-			doc.Headings = headings.String
-		}
-		if meta.Valid {
-			doc.OtherMeta = meta.String
-		}
+	return nil
+}
+
+// Scan the current row of (id, path, title, date, fileTime, headings, meta, zettelId, lang, pinned) into a document
+func scanDocument(rows *sql.Rows) (int, *index.Document, error) {
+	var id int
+	var docPath string
+	var title, headings, meta, zettelId, lang sql.NullString
+	var dateEpoch, filetimeEpoch sql.NullInt64
+	var pinned bool
 
-		f.docs[docPath] = doc
-		f.ids[docPath] = id
+	if err := rows.Scan(&id, &docPath, &title, &dateEpoch, &filetimeEpoch, &headings, &meta, &zettelId, &lang, &pinned); err != nil {
+		return 0, nil, err
 	}
 
-	return nil
+	doc := &index.Document{
+		Path:   docPath,
+		Pinned: pinned,
+	}
+
+	if title.Valid {
+		doc.Title = title.String
+	}
+	if dateEpoch.Valid {
+		doc.Date = time.Unix(dateEpoch.Int64, 0)
+	}
+	if filetimeEpoch.Valid {
+		doc.FileTime = time.Unix(filetimeEpoch.Int64, 0)
+	}
+	if headings.Valid {
+		doc.Headings = headings.String
+	}
+	if meta.Valid {
+		doc.OtherMeta = meta.String
+	}
+	if zettelId.Valid {
+		doc.ID = zettelId.String
+	}
+	if lang.Valid {
+		doc.Language = lang.String
+	}
+
+	return id, doc, nil
 }
 func (f Fill) authors(ctx context.Context) error {
 	rows, err := f.Db.QueryContext(ctx, `
-	SELECT author
+	SELECT author, email
 	FROM Authors
 	JOIN DocumentAuthors
 	ON Authors.id = DocumentAuthors.authorId
@@ -179,12 +223,19 @@ func (f Fill) authors(ctx context.Context) error {
 	defer rows.Close()
 
 	var author string
+	var email sql.NullString
 	authors := make([]string, 0, 4)
 	for rows.Next() {
-		if err := rows.Scan(&author); err != nil {
+		if err := rows.Scan(&author, &email); err != nil {
 			return err
 		}
 		authors = append(authors, author)
+		if email.Valid {
+			if f.doc.Emails == nil {
+				f.doc.Emails = make(map[string]string)
+			}
+			f.doc.Emails[author] = email.String
+		}
 	}
 
 	f.doc.Authors = authors
@@ -194,7 +245,7 @@ func (f Fill) authors(ctx context.Context) error {
 
 func (f FillMany) authors(ctx context.Context) error {
 	stmt, err := f.Db.PrepareContext(ctx, `
-	SELECT author
+	SELECT author, email
 	FROM Authors
 	JOIN DocumentAuthors
 	ON Authors.id = DocumentAuthors.authorId
@@ -207,6 +258,7 @@ func (f FillMany) authors(ctx context.Context) error {
 
 	// PERF: parallelize
 	var author string
+	var email sql.NullString
 	for path, id := range f.ids {
 		rows, err := stmt.QueryContext(ctx, id)
 		if err != nil {
@@ -215,12 +267,18 @@ func (f FillMany) authors(ctx context.Context) error {
 
 		doc := f.docs[path]
 		for rows.Next() {
-			if err := rows.Scan(&author); err != nil {
+			if err := rows.Scan(&author, &email); err != nil {
 				rows.Close()
 				return err
 			}
 
 			doc.Authors = append(doc.Authors, author)
+			if email.Valid {
+				if doc.Emails == nil {
+					doc.Emails = make(map[string]string)
+				}
+				doc.Emails[author] = email.String
+			}
 		}
 
 		rows.Close()
@@ -350,3 +408,60 @@ func (f FillMany) links(ctx context.Context) error {
 
 	return nil
 }
+
+func (f Fill) citations(ctx context.Context) error {
+	rows, err := f.Db.QueryContext(ctx, `
+	SELECT citation
+	FROM Citations
+	WHERE Citations.docId = ?
+	`, f.id)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var citation string
+	citations := make([]string, 0)
+	for rows.Next() {
+		if err := rows.Scan(&citation); err != nil {
+			return err
+		}
+		citations = append(citations, citation)
+	}
+	f.doc.Citations = citations
+
+	return nil
+}
+
+func (f FillMany) citations(ctx context.Context) error {
+	stmt, err := f.Db.PrepareContext(ctx, `
+	SELECT citation
+	FROM Citations
+	WHERE Citations.docId = ?
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	var citation string
+	for path, id := range f.ids {
+		rows, err := stmt.QueryContext(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		doc := f.docs[path]
+		for rows.Next() {
+			if err := rows.Scan(&citation); err != nil {
+				rows.Close()
+				return err
+			}
+			doc.Citations = append(doc.Citations, citation)
+		}
+
+		rows.Close()
+	}
+
+	return nil
+}
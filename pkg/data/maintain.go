@@ -0,0 +1,109 @@
+package data
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jpappel/atlas/pkg/util"
+)
+
+// Schedules for periodic database maintenance
+type MaintenanceOpts struct {
+	Optimize util.Schedule // when to run PRAGMA OPTIMIZE, nil to disable
+	Tidy     util.Schedule // when to run Tidy, nil to disable
+	Jitter   time.Duration // maximum random delay added to each run, spreads work between instances
+}
+
+type maintenanceTask struct {
+	name     string
+	schedule util.Schedule
+	run      func(context.Context) error
+	next     time.Time // zero when the task is not scheduled
+}
+
+// Run maintenance tasks on their schedules until ctx is done.
+// Failed tasks are logged and run again at their next scheduled time.
+func (q Query) Maintain(ctx context.Context, opts MaintenanceOpts) {
+	if _, err := q.db.ExecContext(ctx, "PRAGMA optimize(0x10002)"); err != nil {
+		slog.Error("Error optimizing database", slog.String("err", err.Error()))
+	}
+
+	tasks := []*maintenanceTask{
+		{name: "optimize", schedule: opts.Optimize, run: func(ctx context.Context) error {
+			_, err := q.db.ExecContext(ctx, "PRAGMA OPTIMIZE")
+			return err
+		}},
+		{name: "tidy", schedule: opts.Tidy, run: func(ctx context.Context) error {
+			report, err := q.Tidy(ctx)
+			if err == nil {
+				slog.Info("Tidied database",
+					slog.Int64("authorsRemoved", report.AuthorsRemoved),
+					slog.Int64("tagsRemoved", report.TagsRemoved),
+					slog.Int64("bytesReclaimed", report.BytesReclaimed),
+					slog.Int64("segmentsMerged", report.SegmentsMerged),
+				)
+			}
+			return err
+		}},
+	}
+	scheduleNext := func(task *maintenanceTask, now time.Time) {
+		task.next = time.Time{}
+		if task.schedule == nil {
+			return
+		}
+		if next := task.schedule.Next(now); !next.IsZero() {
+			if opts.Jitter > 0 {
+				next = next.Add(rand.N(opts.Jitter))
+			}
+			task.next = next
+		}
+		slog.Debug("Scheduled database maintenance",
+			slog.String("task", task.name),
+			slog.Time("next", task.next),
+		)
+	}
+	now := time.Now()
+	for _, task := range tasks {
+		scheduleNext(task, now)
+	}
+
+	for {
+		var task *maintenanceTask
+		for _, t := range tasks {
+			if !t.next.IsZero() && (task == nil || t.next.Before(task.next)) {
+				task = t
+			}
+		}
+		if task == nil {
+			<-ctx.Done()
+			return
+		}
+
+		timer := time.NewTimer(time.Until(task.next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		slog.Info("Running database maintenance", slog.String("task", task.name))
+		start := time.Now()
+		if err := task.run(ctx); ctx.Err() != nil {
+			return
+		} else if err != nil {
+			slog.Error("Error during database maintenance",
+				slog.String("task", task.name),
+				slog.String("err", err.Error()),
+			)
+		} else {
+			slog.Info("Finished database maintenance",
+				slog.String("task", task.name),
+				slog.Duration("elapsed", time.Since(start)),
+			)
+		}
+		scheduleNext(task, time.Now())
+	}
+}
@@ -0,0 +1,71 @@
+package data_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jpappel/atlas/pkg/data"
+)
+
+// Schedules a run shortly after each call to Next, reporting calls until its buffer is full
+type countingSchedule chan time.Time
+
+func (s countingSchedule) Next(t time.Time) time.Time {
+	select {
+	case s <- t:
+	default:
+	}
+	return t.Add(time.Millisecond)
+}
+
+func TestQuery_Maintain(t *testing.T) {
+	q := data.NewMemQuery("test")
+	defer q.Close()
+
+	optimize := make(countingSchedule, 16)
+	tidy := make(countingSchedule, 16)
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		q.Maintain(ctx, data.MaintenanceOpts{Optimize: optimize, Tidy: tidy, Jitter: time.Millisecond})
+		close(done)
+	}()
+
+	// tasks are rescheduled after each run
+	for _, sched := range []countingSchedule{optimize, tidy} {
+		for range 3 {
+			select {
+			case <-sched:
+			case <-time.After(5 * time.Second):
+				t.Fatal("Timed out waiting for scheduled maintenance")
+			}
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Maintain did not return after its context was done")
+	}
+}
+
+func TestQuery_Maintain_Unscheduled(t *testing.T) {
+	q := data.NewMemQuery("test")
+	defer q.Close()
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		q.Maintain(ctx, data.MaintenanceOpts{})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Maintain did not return after its context was done")
+	}
+}
@@ -0,0 +1,216 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jpappel/atlas/pkg/index"
+)
+
+// Names stored in the type column of Meta
+const (
+	META_TYPE_STRING = "string"
+	META_TYPE_BOOL   = "bool"
+	META_TYPE_NUMBER = "number"
+)
+
+// Convert a field value to its stored value and type
+func metaFieldValue(value any) (any, string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, META_TYPE_STRING, nil
+	case bool:
+		if v {
+			return 1, META_TYPE_BOOL, nil
+		}
+		return 0, META_TYPE_BOOL, nil
+	case float64:
+		return v, META_TYPE_NUMBER, nil
+	case int64:
+		return float64(v), META_TYPE_NUMBER, nil
+	case int:
+		return float64(v), META_TYPE_NUMBER, nil
+	default:
+		return nil, "", fmt.Errorf("Unsupported meta field value %T", value)
+	}
+}
+
+// Convert a stored value and type to a field value
+func scanMetaFieldValue(value any, valueType string) (any, error) {
+	switch valueType {
+	case META_TYPE_STRING:
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case []byte:
+			return string(v), nil
+		}
+	case META_TYPE_BOOL:
+		if v, ok := value.(int64); ok {
+			return v != 0, nil
+		}
+	case META_TYPE_NUMBER:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case int64:
+			return float64(v), nil
+		}
+	}
+	return nil, fmt.Errorf("Unexpected %T for meta field of type %s", value, valueType)
+}
+
+func insertMetaFields(stmt *sql.Stmt, id int64, fields []index.MetaField) error {
+	for _, field := range fields {
+		value, valueType, err := metaFieldValue(field.Value)
+		if err != nil {
+			return err
+		}
+		if _, err := stmt.Exec(id, field.Key, value, valueType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const insertMetaFieldQuery = "INSERT INTO Meta (docId, key, value, type) VALUES (?,?,?,?)"
+
+func (p Put) metaFields() error {
+	if len(p.Doc.MetaFields) == 0 {
+		return nil
+	}
+
+	stmt, err := p.tx.Prepare(insertMetaFieldQuery)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	return insertMetaFields(stmt, p.Id, p.Doc.MetaFields)
+}
+
+func (p PutMany) metaFields(ctx context.Context) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, insertMetaFieldQuery)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for id, doc := range p.Docs {
+		if err := insertMetaFields(stmt, id, doc.MetaFields); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (u Update) metaFields() error {
+	if _, err := u.tx.Exec("DELETE FROM Meta WHERE docId = ?", u.Id); err != nil {
+		return err
+	}
+
+	if len(u.Doc.MetaFields) == 0 {
+		return nil
+	}
+
+	stmt, err := u.tx.Prepare(insertMetaFieldQuery)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	return insertMetaFields(stmt, u.Id, u.Doc.MetaFields)
+}
+
+func (u UpdateMany) metaFields() error {
+	deleteStmt, err := u.tx.Prepare("DELETE FROM Meta WHERE docId = ?")
+	if err != nil {
+		return err
+	}
+	defer deleteStmt.Close()
+	insertStmt, err := u.tx.Prepare(insertMetaFieldQuery)
+	if err != nil {
+		return err
+	}
+	defer insertStmt.Close()
+
+	for id, doc := range u.Docs {
+		if _, err := deleteStmt.Exec(id); err != nil {
+			return err
+		}
+
+		if err := insertMetaFields(insertStmt, id, doc.MetaFields); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func scanMetaFields(rows *sql.Rows) ([]index.MetaField, error) {
+	var fields []index.MetaField
+	for rows.Next() {
+		var key, valueType string
+		var value any
+		if err := rows.Scan(&key, &value, &valueType); err != nil {
+			return nil, err
+		}
+
+		fieldValue, err := scanMetaFieldValue(value, valueType)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, index.MetaField{Key: key, Value: fieldValue})
+	}
+	return fields, rows.Err()
+}
+
+const selectMetaFieldsQuery = `
+	SELECT key, value, type
+	FROM Meta
+	WHERE docId = ?
+	ORDER BY rowid
+	`
+
+func (f Fill) metaFields(ctx context.Context) error {
+	rows, err := f.Db.QueryContext(ctx, selectMetaFieldsQuery, f.id)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	f.doc.MetaFields, err = scanMetaFields(rows)
+	return err
+}
+
+func (f FillMany) metaFields(ctx context.Context) error {
+	stmt, err := f.Db.PrepareContext(ctx, selectMetaFieldsQuery)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for path, id := range f.ids {
+		rows, err := stmt.QueryContext(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		f.docs[path].MetaFields, err = scanMetaFields(rows)
+		rows.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
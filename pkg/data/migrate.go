@@ -0,0 +1,93 @@
+package data
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+)
+
+// Version of the database schema, stored in Info under the key schema.
+// Increment when createSchema changes the definition of an existing table,
+// view, or trigger and add the migration to migrateSchema.
+const schemaVersion = 2
+
+// Columns added to tables after their creation
+var addedColumns = []struct {
+	table, column, definition string
+}{
+	{"Documents", "zettelId", "TEXT"},
+	{"Documents", "lang", "TEXT"},
+	{"Documents", "pinned", "INT NOT NULL DEFAULT 0"},
+	{"Authors", "email", "TEXT"},
+}
+
+func tableColumns(tx *sql.Tx, table string) ([]string, error) {
+	rows, err := tx.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+// Bring tables created by an older schema up to date before createSchema
+// creates anything missing. Objects whose definitions changed are dropped
+// so that they are recreated, rebuildFts reports if the document full text
+// index must be repopulated afterwards.
+func migrateSchema(tx *sql.Tx) (rebuildFts bool, err error) {
+	docColumns, err := tableColumns(tx, "Documents")
+	if err != nil {
+		return false, err
+	} else if len(docColumns) == 0 {
+		// new database
+		return false, nil
+	}
+
+	for _, added := range addedColumns {
+		columns, err := tableColumns(tx, added.table)
+		if err != nil {
+			return false, err
+		}
+		if slices.Contains(columns, added.column) {
+			continue
+		}
+		stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", added.table, added.column, added.definition)
+		if _, err := tx.Exec(stmt); err != nil {
+			return false, err
+		}
+	}
+
+	// the view selects columns added since it was created
+	if _, err := tx.Exec("DROP VIEW IF EXISTS Search"); err != nil {
+		return false, err
+	}
+
+	ftsColumns, err := tableColumns(tx, "Documents_fts")
+	if err != nil {
+		return false, err
+	}
+	if !slices.Contains(ftsColumns, "zettelId") {
+		for _, stmt := range []string{
+			"DROP TRIGGER IF EXISTS trig_ai_doc",
+			"DROP TRIGGER IF EXISTS trig_ad_doc",
+			"DROP TRIGGER IF EXISTS trig_au_doc",
+			"DROP TABLE IF EXISTS Documents_fts",
+		} {
+			if _, err := tx.Exec(stmt); err != nil {
+				return false, err
+			}
+		}
+		rebuildFts = true
+	}
+
+	return rebuildFts, nil
+}
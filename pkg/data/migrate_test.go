@@ -0,0 +1,94 @@
+package data_test
+
+import (
+	"database/sql"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jpappel/atlas/pkg/data"
+	"github.com/jpappel/atlas/pkg/index"
+	"github.com/jpappel/atlas/pkg/query"
+)
+
+// Create a database at path with the schema written before schema versions were recorded
+func newBaselineDB(t *testing.T, path string) {
+	t.Helper()
+	ddl, err := os.ReadFile("testdata/baseline_schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(string(ddl)); err != nil {
+		t.Fatal("Cannot create baseline database:", err)
+	}
+}
+
+func TestNewDB_Migrate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.db")
+	newBaselineDB(t, path)
+
+	execute := func(q *data.Query, queryStr string) []string {
+		t.Helper()
+		artifact, err := query.Compile(queryStr, 0, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := q.Execute(t.Context(), artifact)
+		if err != nil {
+			t.Fatalf("Unexpected error executing %s: %v", queryStr, err)
+		}
+		return slices.Sorted(maps.Keys(got))
+	}
+
+	q := data.NewQuery(path, "test", data.DefaultDBOpts)
+	// existing documents are searchable after the full text index is rebuilt
+	if got := execute(q, `T:Shopping a="Rob Pike" t=errands`); !slices.Equal(got, []string{"/notes/old.md"}) {
+		t.Errorf("Got %v, want [/notes/old.md]", got)
+	}
+
+	docs := map[string]*index.Document{
+		"/notes/old.md": {Path: "/notes/old.md", Title: "Shopping list", FileTime: time.Unix(1, 0)},
+		"/notes/new.md": {
+			Path:     "/notes/new.md",
+			ID:       "202401121230",
+			Title:    "New",
+			Language: "en",
+			FileTime: time.Unix(2, 0),
+			Authors:  []string{"Rob Pike"},
+			Emails:   map[string]string{"Rob Pike": "r@golang.org"},
+		},
+	}
+	if err := q.Update(t.Context(), index.Index{Documents: docs}); err != nil {
+		t.Fatal("Unexpected error updating migrated database:", err)
+	}
+	if err := q.SetPinned(t.Context(), true, "/notes/old.md"); err != nil {
+		t.Fatal("Unexpected error pinning in migrated database:", err)
+	}
+	q.Close()
+
+	// reopening a current database skips schema creation
+	q = data.NewQuery(path, "test", data.DefaultDBOpts)
+	defer q.Close()
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"id=202401121230", []string{"/notes/new.md"}},
+		{"lang=en", []string{"/notes/new.md"}},
+		{"a=r@golang.org", []string{"/notes/new.md"}},
+		{"pinned=true", []string{"/notes/old.md"}},
+	}
+	for _, tt := range tests {
+		if got := execute(q, tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Pin or unpin documents by path. Pins are kept in the database and survive
+// updates, but not rebuilding the index.
+func (q Query) SetPinned(ctx context.Context, pinned bool, paths ...string) error {
+	defer q.changed()
+	if len(paths) == 0 {
+		return nil
+	}
+
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, "UPDATE Documents SET pinned = ? WHERE path = ?")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	missing := make([]string, 0)
+	for _, path := range paths {
+		res, err := stmt.ExecContext(ctx, pinned, path)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			tx.Rollback()
+			return err
+		} else if n == 0 {
+			missing = append(missing, path)
+		}
+	}
+
+	if len(missing) > 0 {
+		tx.Rollback()
+		return fmt.Errorf("Documents not in index: %s", strings.Join(missing, ", "))
+	}
+
+	return tx.Commit()
+}
+
+// Get the paths of pinned documents in order
+func (q Query) Pinned(ctx context.Context) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, "SELECT path FROM Documents WHERE pinned ORDER BY path")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	paths := make([]string, 0)
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, rows.Err()
+}
@@ -0,0 +1,152 @@
+package data_test
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jpappel/atlas/pkg/data"
+	"github.com/jpappel/atlas/pkg/index"
+	"github.com/jpappel/atlas/pkg/query"
+)
+
+func TestQuery_SetPinned(t *testing.T) {
+	q := data.NewMemQuery("test")
+	defer q.Close()
+	docs := map[string]*index.Document{
+		"/notes/a.md": {Path: "/notes/a.md", Title: "a", FileTime: time.Unix(1, 0)},
+		"/notes/b.md": {Path: "/notes/b.md", Title: "b", FileTime: time.Unix(1, 0)},
+		"/notes/c.md": {Path: "/notes/c.md", Title: "c", FileTime: time.Unix(1, 0)},
+	}
+	if err := q.Put(t.Context(), index.Index{Documents: docs}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := q.SetPinned(t.Context(), true, "/notes/c.md", "/notes/b.md"); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.SetPinned(t.Context(), true, "/notes/a.md", "/notes/missing.md"); err == nil {
+		t.Error("Expected error pinning a document not in the index")
+	}
+	if err := q.SetPinned(t.Context(), false, "/notes/b.md"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := q.Pinned(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"/notes/c.md"}; !slices.Equal(got, want) {
+		t.Errorf("Got pinned %v, want %v", got, want)
+	}
+
+	doc, err := q.GetDocument(t.Context(), "/notes/c.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !doc.Pinned {
+		t.Error("Expected /notes/c.md to be pinned")
+	}
+
+	// pins are kept in the database, not the file
+	updated := maps.Clone(docs)
+	updated["/notes/c.md"] = &index.Document{Path: "/notes/c.md", Title: "c2", FileTime: time.Unix(2, 0)}
+	if err := q.Update(t.Context(), index.Index{Documents: updated}); err != nil {
+		t.Fatal(err)
+	}
+	got, err = q.Pinned(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"/notes/c.md"}; !slices.Equal(got, want) {
+		t.Errorf("Got pinned %v after update, want %v", got, want)
+	}
+}
+
+func TestQuery_Execute_Pinned(t *testing.T) {
+	q := data.NewMemQuery("test")
+	defer q.Close()
+	docs := map[string]*index.Document{
+		"/notes/a.md": {Path: "/notes/a.md", Title: "a"},
+		"/notes/b.md": {Path: "/notes/b.md", Title: "b"},
+		"/notes/c.md": {Path: "/notes/c.md", Title: "c", Pinned: true},
+	}
+	if err := q.Put(t.Context(), index.Index{Documents: docs}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"pinned=true", []string{"/notes/c.md"}},
+		{"pinned:true", []string{"/notes/c.md"}},
+		{"-pinned=true", []string{"/notes/a.md", "/notes/b.md"}},
+		{"pinned!=false", []string{"/notes/c.md"}},
+		{"(or pinned=1 T=a)", []string{"/notes/a.md", "/notes/c.md"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			artifact, err := query.Compile(tt.query, 0, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := q.Execute(t.Context(), artifact)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotPaths := slices.Sorted(maps.Keys(got))
+			if !slices.Equal(gotPaths, tt.want) {
+				t.Errorf("Got %v, want %v", gotPaths, tt.want)
+			}
+		})
+	}
+
+	// go side sorts order documents the same as the database
+	for _, desc := range []bool{false, true} {
+		t.Run(fmt.Sprint("pinned first desc=", desc), func(t *testing.T) {
+			artifact, err := query.Compile("-T=missing", 0, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			artifact.SortBy = "path"
+			artifact.SortDesc = desc
+
+			got := []string{}
+			err = q.ExecuteFunc(t.Context(), artifact, func(doc *index.Document) error {
+				got = append(got, doc.Path)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []string{"/notes/c.md", "/notes/a.md", "/notes/b.md"}
+			if desc {
+				want = []string{"/notes/c.md", "/notes/b.md", "/notes/a.md"}
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("Got %v, want %v", got, want)
+			}
+
+			results, err := q.Execute(t.Context(), artifact)
+			if err != nil {
+				t.Fatal(err)
+			}
+			docCmp, ok := index.NewDocCmp(artifact.SortBy, artifact.SortDesc)
+			if !ok {
+				t.Fatal("Cannot create comparison for", artifact.SortBy)
+			}
+			sorted := slices.SortedFunc(maps.Values(results), docCmp)
+			gotSorted := make([]string, len(sorted))
+			for i, doc := range sorted {
+				gotSorted[i] = doc.Path
+			}
+			if !slices.Equal(gotSorted, want) {
+				t.Errorf("Got %v from sorting results, want %v", gotSorted, want)
+			}
+		})
+	}
+}
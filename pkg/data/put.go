@@ -21,6 +21,7 @@ type PutMany struct {
 	pathDocs map[string]*index.Document
 	db       *sql.DB
 	ctx      context.Context
+	progress index.ProgressFunc
 }
 
 func NewPut(db *sql.DB, doc index.Document) Put {
@@ -60,6 +61,16 @@ func (p *Put) Insert(ctx context.Context) error {
 		return err
 	}
 
+	if err := p.citations(); err != nil {
+		p.tx.Rollback()
+		return err
+	}
+
+	if err := p.metaFields(); err != nil {
+		p.tx.Rollback()
+		return err
+	}
+
 	if err := p.authors(); err != nil {
 		p.tx.Rollback()
 		return err
@@ -88,6 +99,14 @@ func (p PutMany) Insert() error {
 		return fmt.Errorf("failed to insert links: %v", err)
 	}
 
+	if err := p.citations(p.ctx); err != nil {
+		return fmt.Errorf("failed to insert citations: %v", err)
+	}
+
+	if err := p.metaFields(p.ctx); err != nil {
+		return fmt.Errorf("failed to insert meta fields: %v", err)
+	}
+
 	if err := p.authors(p.ctx); err != nil {
 		return fmt.Errorf("failed to insert authors: %v", err)
 	}
@@ -107,11 +126,13 @@ func (p *Put) document() error {
 	filetime := sql.NullInt64{Int64: p.Doc.FileTime.Unix(), Valid: !p.Doc.FileTime.IsZero()}
 	headings := sql.NullString{String: p.Doc.Headings, Valid: p.Doc.Headings != ""}
 	meta := sql.NullString{String: p.Doc.OtherMeta, Valid: p.Doc.OtherMeta != ""}
+	zettelId := sql.NullString{String: p.Doc.ID, Valid: p.Doc.ID != ""}
+	lang := sql.NullString{String: p.Doc.Language, Valid: p.Doc.Language != ""}
 
 	result, err := p.tx.Exec(`
-	INSERT INTO Documents(path, title, date, fileTime, headings, meta)
-	VALUES (?,?,?,?,?,?)
-	`, p.Doc.Path, title, date, filetime, headings, meta)
+	INSERT INTO Documents(path, title, date, fileTime, headings, meta, zettelId, lang, pinned)
+	VALUES (?,?,?,?,?,?,?,?,?)
+	`, p.Doc.Path, title, date, filetime, headings, meta, zettelId, lang, p.Doc.Pinned)
 	if err != nil {
 		return err
 	}
@@ -131,8 +152,8 @@ func (p *PutMany) documents(ctx context.Context) error {
 	}
 
 	stmt, err := tx.PrepareContext(ctx, `
-	INSERT INTO Documents(path, title, date, fileTime, headings, meta)
-	VALUES (?,?,?,?,?,?)
+	INSERT INTO Documents(path, title, date, fileTime, headings, meta, zettelId, lang, pinned)
+	VALUES (?,?,?,?,?,?,?,?,?)
 	`)
 	if err != nil {
 		return err
@@ -142,13 +163,18 @@ func (p *PutMany) documents(ctx context.Context) error {
 	// PERF: profile this, grabbing the docId here might save time by simpliyfying
 	//       future inserts
 	for _, doc := range p.pathDocs {
+		if p.progress != nil {
+			p.progress("write", len(p.Docs), len(p.pathDocs), 0)
+		}
 		title := sql.NullString{String: doc.Title, Valid: doc.Title != ""}
 		date := sql.NullInt64{Int64: doc.Date.Unix(), Valid: !doc.Date.IsZero()}
 		filetime := sql.NullInt64{Int64: doc.FileTime.Unix(), Valid: !doc.FileTime.IsZero()}
 		headings := sql.NullString{String: doc.Headings, Valid: doc.Headings != ""}
 		meta := sql.NullString{String: doc.OtherMeta, Valid: doc.OtherMeta != ""}
+		zettelId := sql.NullString{String: doc.ID, Valid: doc.ID != ""}
+		lang := sql.NullString{String: doc.Language, Valid: doc.Language != ""}
 
-		res, err := stmt.ExecContext(ctx, doc.Path, title, date, filetime, headings, meta)
+		res, err := stmt.ExecContext(ctx, doc.Path, title, date, filetime, headings, meta, zettelId, lang, doc.Pinned)
 		if err != nil {
 			tx.Rollback()
 			return err
@@ -197,35 +223,48 @@ func (p PutMany) tags(ctx context.Context) error {
 		return err
 	}
 
-	txNewTagStmt, err := tx.Prepare("INSERT OR IGNORE INTO Tags (tag) VALUES (?)")
+	if _, err := tx.Exec("CREATE TEMPORARY TABLE putTags (docId INT, tag TEXT)"); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	tempInsertStmt, err := tx.Prepare("INSERT INTO temp.putTags VALUES (?,?)")
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
-	defer txNewTagStmt.Close()
+	defer tempInsertStmt.Close()
 
 	for id, doc := range p.Docs {
-		if len(doc.Tags) == 0 {
-			continue
-		}
 		for _, tag := range doc.Tags {
-			if _, err := txNewTagStmt.ExecContext(ctx, tag); err != nil {
+			if _, err := tempInsertStmt.ExecContext(ctx, id, tag); err != nil {
 				tx.Rollback()
 				return err
 			}
 		}
+	}
 
-		preQuery := fmt.Sprintf(`
-		INSERT INTO DocumentTags (docId, tagId)
-			SELECT %d, Tags.id
-			FROM Tags
-			WHERE tag IN
-		`, id)
-		query, args := BatchQuery(preQuery, "(", "?", ",", ")", len(doc.Tags), doc.Tags)
-		if _, err := tx.Exec(query, args...); err != nil {
-			tx.Rollback()
-			return err
-		}
+	if _, err := tx.ExecContext(ctx, `
+	INSERT OR IGNORE INTO Tags (tag)
+	SELECT DISTINCT tag FROM temp.putTags
+	`); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+	INSERT OR IGNORE INTO DocumentTags (docId, tagId)
+	SELECT putTags.docId, Tags.id
+	FROM temp.putTags
+	JOIN Tags ON Tags.tag = putTags.tag
+	`); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.Exec("DROP TABLE temp.putTags"); err != nil {
+		tx.Rollback()
+		return err
 	}
 
 	return tx.Commit()
@@ -275,23 +314,73 @@ func (p PutMany) links(ctx context.Context) error {
 	return tx.Commit()
 }
 
+func (p Put) citations() error {
+	if len(p.Doc.Citations) == 0 {
+		return nil
+	}
+
+	preQuery := `
+		INSERT INTO Citations (docId, citation)
+		VALUES
+	`
+	valueStr := fmt.Sprintf("(%d,?)", p.Id)
+	query, args := BatchQuery(preQuery, "", valueStr, ",", "", len(p.Doc.Citations), p.Doc.Citations)
+	if _, err := p.tx.Exec(query+"\n ON CONFLICT DO NOTHING", args...); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (p PutMany) citations(ctx context.Context) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for id, doc := range p.Docs {
+		if len(doc.Citations) == 0 {
+			continue
+		}
+
+		preQuery := `
+		INSERT INTO Citations (docId, citation)
+		VALUES
+	`
+		valueStr := fmt.Sprintf("(%d,?)", id)
+		query, args := BatchQuery(preQuery, "", valueStr, ",", "", len(doc.Citations), doc.Citations)
+		if _, err := tx.Exec(query+"\n ON CONFLICT DO NOTHING", args...); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
 func (p Put) authors() error {
 	if len(p.Doc.Authors) == 0 {
 		return nil
 	}
 
-	authStmt, err := p.tx.Prepare("INSERT OR IGNORE INTO Authors(author) VALUES(?)")
+	authStmt, err := p.tx.Prepare(insertAuthorQuery)
 	if err != nil {
 		return err
 	}
 	defer authStmt.Close()
 
-	idStmt, err := p.tx.Prepare("SELECT id FROM Authors WHERE author = ?")
+	idStmt, err := p.tx.Prepare(authorIdQuery)
 	if err != nil {
 		return err
 	}
 	defer idStmt.Close()
 
+	emailStmt, err := p.tx.Prepare(setAuthorEmailQuery)
+	if err != nil {
+		return err
+	}
+	defer emailStmt.Close()
+
 	docAuthStmt, err := p.tx.Prepare(
 		fmt.Sprintf("INSERT INTO DocumentAuthors(docId,authorId) VALUES (%d,?)", p.Id),
 	)
@@ -309,6 +398,11 @@ func (p Put) authors() error {
 		if err := idStmt.QueryRow(author).Scan(&authId); err != nil {
 			return err
 		}
+		if email := p.Doc.Emails[author]; email != "" {
+			if _, err := emailStmt.Exec(email, authId); err != nil {
+				return err
+			}
+		}
 		if _, err := docAuthStmt.Exec(authId); err != nil {
 			return err
 		}
@@ -323,38 +417,67 @@ func (p PutMany) authors(ctx context.Context) error {
 		return err
 	}
 
-	authStmt, err := tx.Prepare("INSERT OR IGNORE INTO Authors(author) VALUES(?)")
-	if err != nil {
-		return err
-	}
-	defer authStmt.Close()
-
-	idStmt, err := tx.Prepare("SELECT id FROM Authors WHERE author = ?")
-	if err != nil {
+	if _, err := tx.Exec("CREATE TEMPORARY TABLE putAuthors (docId INT, author TEXT, email TEXT)"); err != nil {
+		tx.Rollback()
 		return err
 	}
-	defer idStmt.Close()
 
-	docAuthStmt, err := tx.Prepare("INSERT INTO DocumentAuthors(docId,authorId) VALUES (?,?)")
+	tempInsertStmt, err := tx.Prepare("INSERT INTO temp.putAuthors VALUES (?,?,NULLIF(?,''))")
 	if err != nil {
+		tx.Rollback()
 		return err
 	}
-	defer docAuthStmt.Close()
+	defer tempInsertStmt.Close()
 
-	var authId int64
-	for docId, doc := range p.Docs {
+	for id, doc := range p.Docs {
 		for _, author := range doc.Authors {
-			if _, err := authStmt.Exec(author); err != nil {
-				return err
-			}
-			if err := idStmt.QueryRow(author).Scan(&authId); err != nil {
-				return err
-			}
-			if _, err := docAuthStmt.Exec(docId, authId); err != nil {
+			if _, err := tempInsertStmt.ExecContext(ctx, id, author, doc.Emails[author]); err != nil {
+				tx.Rollback()
 				return err
 			}
 		}
+	}
 
+	// aliases are resolved to their author rather than inserted
+	if _, err := tx.ExecContext(ctx, `
+	INSERT OR IGNORE INTO Authors (author)
+	SELECT DISTINCT author FROM temp.putAuthors
+	WHERE author NOT IN (SELECT alias FROM Aliases)
+	`); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+	INSERT INTO DocumentAuthors (docId, authorId)
+	SELECT putAuthors.docId, COALESCE(Aliases.authorId, Authors.id)
+	FROM temp.putAuthors
+	LEFT JOIN Aliases ON Aliases.alias = putAuthors.author
+	LEFT JOIN Authors ON Authors.author = putAuthors.author
+	ORDER BY putAuthors.rowid
+	`); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+	UPDATE Authors SET email = e.email
+	FROM (
+		SELECT COALESCE(al.authorId, au.id) AS id, pa.email
+		FROM temp.putAuthors pa
+		LEFT JOIN Aliases al ON al.alias = pa.author
+		LEFT JOIN Authors au ON au.author = pa.author
+		WHERE pa.email IS NOT NULL
+	) AS e
+	WHERE Authors.id = e.id
+	`); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.Exec("DROP TABLE temp.putAuthors"); err != nil {
+		tx.Rollback()
+		return err
 	}
 
 	return tx.Commit()
@@ -98,6 +98,32 @@ func TestPutMany_Insert(t *testing.T) {
 			},
 			wantErr: nil,
 		},
+		{
+			name: "shared authors and tags",
+			newDb: func(t *testing.T) *sql.DB {
+				t.Helper()
+				return data.NewMemDB("test")
+			},
+			documents: map[string]*index.Document{
+				"/file": {
+					Path:     "/file",
+					FileTime: time.Unix(2, 0),
+					Authors:  []string{"Rob Pike", "Ken Thompson", "Robert Griesemer"},
+					Tags:     []string{"go", "plan9"},
+				},
+				"/file2": {
+					Path:     "/file2",
+					FileTime: time.Unix(4, 0),
+					Authors:  []string{"Ken Thompson", "Dennis Ritchie"},
+					Tags:     []string{"unix", "plan9", "c"},
+				},
+				"/file3": {
+					Path:     "/file3",
+					FileTime: time.Unix(4, 0),
+				},
+			},
+			wantErr: nil,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
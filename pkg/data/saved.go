@@ -0,0 +1,112 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jpappel/atlas/pkg/query"
+)
+
+type SavedQuery struct {
+	Name  string
+	Query string
+}
+
+// Save a query under a name, replacing any query already saved with that name.
+// The query must parse once saved queries it references are expanded.
+func (q Query) SaveQuery(ctx context.Context, name string, userQuery string) error {
+	if !query.SavedNameRegex.MatchString(name) {
+		return fmt.Errorf("Invalid saved query name `%s`, names may contain letters, digits, _, and -", name)
+	}
+
+	tokens, err := query.ExpandSaved(query.Lex(userQuery), func(ref string) (string, error) {
+		if ref == name {
+			return "", fmt.Errorf("Saved query @%s references itself", name)
+		}
+		return q.SavedQuery(ctx, ref)
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := query.Parse(tokens); err != nil {
+		return err
+	}
+
+	_, err = q.db.ExecContext(ctx,
+		"INSERT OR REPLACE INTO SavedQueries(name, query) VALUES (?,?)",
+		name, userQuery,
+	)
+	return err
+}
+
+// Remove saved queries by name
+func (q Query) RemoveSavedQueries(ctx context.Context, names ...string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	stmt, args := BatchQuery("DELETE FROM SavedQueries WHERE name IN", "(", "?", ",", ")", len(names), names)
+	_, err := q.db.ExecContext(ctx, stmt, args...)
+	return err
+}
+
+// Get a saved query by name
+func (q Query) SavedQuery(ctx context.Context, name string) (string, error) {
+	var userQuery string
+	row := q.db.QueryRowContext(ctx, "SELECT query FROM SavedQueries WHERE name = ?", name)
+	if err := row.Scan(&userQuery); errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("No saved query named @%s", name)
+	} else if err != nil {
+		return "", err
+	}
+	return userQuery, nil
+}
+
+// Get all saved queries ordered by name
+func (q Query) SavedQueries(ctx context.Context) ([]SavedQuery, error) {
+	rows, err := q.db.QueryContext(ctx, "SELECT name, query FROM SavedQueries ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	saved := make([]SavedQuery, 0)
+	for rows.Next() {
+		var s SavedQuery
+		if err := rows.Scan(&s.Name, &s.Query); err != nil {
+			return nil, err
+		}
+		saved = append(saved, s)
+	}
+
+	return saved, rows.Err()
+}
+
+// Parse a query, expanding references to saved queries
+func (q Query) Parse(ctx context.Context, userQuery string) (*query.Clause, error) {
+	tokens, err := query.ExpandSaved(query.Lex(userQuery), func(name string) (string, error) {
+		return q.SavedQuery(ctx, name)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return query.Parse(tokens)
+}
+
+// Compile a query, expanding references to saved queries
+func (q Query) Compile(ctx context.Context, userQuery string, optimizationLevel int, numWorkers uint) (query.CompilationArtifact, error) {
+	if numWorkers == 0 {
+		return query.CompilationArtifact{}, fmt.Errorf("Cannot compile with 0 workers")
+	}
+
+	clause, err := q.Parse(ctx, userQuery)
+	if err != nil {
+		return query.CompilationArtifact{}, err
+	}
+
+	query.NewOptimizer(clause, numWorkers).Optimize(optimizationLevel)
+
+	return clause.Compile()
+}
@@ -0,0 +1,105 @@
+package data_test
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/jpappel/atlas/pkg/data"
+	"github.com/jpappel/atlas/pkg/index"
+)
+
+func TestQuery_SaveQuery(t *testing.T) {
+	q := data.NewMemQuery("test")
+	defer q.Close()
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr bool
+	}{
+		{"inbox", "t=inbox", false},
+		{"reading", "t=reading -t=done", false},
+		{"both", "union(@inbox,@reading)", false},
+		{"inbox", "t=inbox -t=archived", false},
+		{"bad name", "t=inbox", true},
+		{"missing", "@nothing", true},
+		{"loop", "(or T:a @loop)", true},
+		{"unparsable", "pinned=maybe", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := q.SaveQuery(t.Context(), tt.name, tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SaveQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	saved, err := q.SavedQueries(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []data.SavedQuery{
+		{"both", "union(@inbox,@reading)"},
+		{"inbox", "t=inbox -t=archived"},
+		{"reading", "t=reading -t=done"},
+	}
+	if !slices.Equal(saved, want) {
+		t.Errorf("Got saved queries %v, want %v", saved, want)
+	}
+
+	if err := q.RemoveSavedQueries(t.Context(), "both", "reading"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.SavedQuery(t.Context(), "both"); err == nil {
+		t.Error("Expected removed query to be missing")
+	}
+}
+
+func TestQuery_Compile_Saved(t *testing.T) {
+	q := data.NewMemQuery("test")
+	defer q.Close()
+	docs := map[string]*index.Document{
+		"/notes/a.md": {Path: "/notes/a.md", Tags: []string{"inbox"}},
+		"/notes/b.md": {Path: "/notes/b.md", Tags: []string{"inbox", "done"}},
+		"/notes/c.md": {Path: "/notes/c.md", Tags: []string{"reading"}},
+		"/notes/d.md": {Path: "/notes/d.md", Tags: []string{"reading", "inbox"}},
+	}
+	if err := q.Put(t.Context(), index.Index{Documents: docs}); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.SaveQuery(t.Context(), "inbox", "t=inbox"); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.SaveQuery(t.Context(), "reading", "t=reading"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"@inbox", []string{"/notes/a.md", "/notes/b.md", "/notes/d.md"}},
+		{"@inbox -t=done", []string{"/notes/a.md", "/notes/d.md"}},
+		{"union(@inbox,@reading)", []string{"/notes/a.md", "/notes/b.md", "/notes/c.md", "/notes/d.md"}},
+		{"intersect(@inbox,@reading)", []string{"/notes/d.md"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			artifact, err := q.Compile(t.Context(), tt.query, 0, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := q.Execute(t.Context(), artifact)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotPaths := slices.Sorted(maps.Keys(got))
+			if !slices.Equal(gotPaths, tt.want) {
+				t.Errorf("Got %v, want %v", gotPaths, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package data
+
+import "context"
+
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// Get all tags used by documents and their number of documents, ordered by tag
+func (q Query) Tags(ctx context.Context) ([]TagCount, error) {
+	rows, err := q.db.QueryContext(ctx, `
+	SELECT tag, COUNT(docId)
+	FROM Tags
+	JOIN DocumentTags ON Tags.id = DocumentTags.tagId
+	GROUP BY Tags.id
+	ORDER BY tag
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make([]TagCount, 0)
+	for rows.Next() {
+		var t TagCount
+		if err := rows.Scan(&t.Tag, &t.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+
+	return tags, rows.Err()
+}
@@ -0,0 +1,34 @@
+package data_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jpappel/atlas/pkg/data"
+	"github.com/jpappel/atlas/pkg/index"
+)
+
+func TestQuery_Tags(t *testing.T) {
+	ctx := t.Context()
+	q := data.NewMemQuery("test")
+	defer q.Close()
+
+	docs := map[string]*index.Document{
+		"/a": {Path: "/a", FileTime: time.Unix(1, 0), Tags: []string{"go", "notes"}},
+		"/b": {Path: "/b", FileTime: time.Unix(2, 0), Tags: []string{"go"}},
+		"/c": {Path: "/c", FileTime: time.Unix(3, 0)},
+	}
+	if err := q.Put(ctx, index.Index{Documents: docs}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := q.Tags(ctx)
+	if err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	want := []data.TagCount{{"go", 2}, {"notes", 1}}
+	if !slices.Equal(got, want) {
+		t.Errorf("Tags() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,169 @@
+-- schema created by atlas before schema versions were recorded
+CREATE TABLE IF NOT EXISTS Info(
+		key TEXT PRIMARY KEY NOT NULL,
+		value TEXT NOT NULL,
+		updated INT NOT NULL
+	);
+CREATE TABLE IF NOT EXISTS Documents(
+		id INTEGER PRIMARY KEY,
+		path TEXT UNIQUE NOT NULL,
+		headings TEXT,
+		title TEXT,
+		date INT,
+		fileTime INT,
+		meta BLOB
+	);
+CREATE TABLE IF NOT EXISTS Authors(
+		id INTEGER PRIMARY KEY,
+		author TEXT UNIQUE NOT NULL
+	);
+CREATE TABLE IF NOT EXISTS Tags(
+		id INTEGER PRIMARY KEY,
+		tag TEXT UNIQUE NOT NULL
+	);
+CREATE TABLE IF NOT EXISTS Links(
+		docId INT,
+		link TEXT NOT NULL,
+		FOREIGN KEY (docId) REFERENCES Documents(id) ON DELETE CASCADE,
+		UNIQUE(docId, link)
+	);
+CREATE TABLE IF NOT EXISTS DocumentAuthors(
+		docId INT NOT NULL,
+		authorId INT NOT NULL,
+		FOREIGN KEY (docId) REFERENCES Documents(id) ON DELETE CASCADE,
+		FOREIGN KEY (authorId) REFERENCES Authors(id)
+	);
+CREATE TABLE IF NOT EXISTS DocumentTags(
+		docId INT NOT NULL,
+		tagId INT NOT NULL,
+		FOREIGN KEY (docId) REFERENCES Documents(id) ON DELETE CASCADE,
+		FOREIGN KEY (tagId) REFERENCES Tags(id),
+		UNIQUE(docId, tagId)
+	);
+CREATE INDEX IF NOT EXISTS idx_doc_paths ON Documents (path);
+CREATE INDEX IF NOT EXISTS idx_doc_dates ON Documents (date);
+CREATE INDEX IF NOT EXISTS idx_doc_titles ON Documents (title);
+CREATE INDEX IF NOT EXISTS idx_links_link ON Links(link);
+CREATE INDEX IF NOT EXISTS idx_doctags_tagid ON DocumentTags (tagId);
+CREATE VIRTUAL TABLE IF NOT EXISTS Documents_fts
+	USING fts5 (
+		path, headings, title, meta, content=Documents, content_rowid=id, tokenize="trigram"
+	);
+CREATE VIRTUAL TABLE IF NOT EXISTS Authors_fts
+	USING fts5 (
+		author, content=Authors, content_rowid=id, tokenize="trigram"
+	);
+CREATE VIRTUAL TABLE IF NOT EXISTS Tags_fts
+	USING fts5 (
+		tag, content=Tags, content_rowid=id, tokenize="trigram"
+	);
+CREATE VIRTUAL TABLE IF NOT EXISTS Links_fts
+	USING fts5 (
+		link, docId UNINDEXED,content=Links, tokenize="trigram"
+	);
+CREATE TRIGGER IF NOT EXISTS trig_ai_authors
+	AFTER INSERT ON Authors
+	BEGIN
+		INSERT INTO Authors_fts(rowid, author)
+		VALUES (new.id, new.author);
+	END;
+CREATE TRIGGER IF NOT EXISTS trig_ad_authors
+	AFTER DELETE ON Authors
+	BEGIN
+		INSERT INTO Authors_fts(Authors_fts, rowid, author)
+		VALUES ('delete', old.id, old.author);
+	END;
+CREATE TRIGGER IF NOT EXISTS trig_au_authors
+	AFTER UPDATE ON Authors
+	BEGIN
+		INSERT INTO Authors_fts(Authors_fts, rowid, author)
+		VALUES ('delete', old.id, old.author);
+		INSERT INTO Authors_fts(rowid, author)
+		VALUES (new.id, new.author);
+	END;
+CREATE TRIGGER IF NOT EXISTS trig_ai_tags
+	AFTER INSERT ON Tags
+	BEGIN
+		INSERT INTO Tags_fts(rowid, tag)
+		VALUES (new.id, new.tag);
+	END;
+CREATE TRIGGER IF NOT EXISTS trig_ad_tags
+	AFTER DELETE ON Tags
+	BEGIN
+		INSERT INTO Tags_fts(Tags_fts, rowid, tag)
+		VALUES ('delete', old.id, old.tag);
+	END;
+CREATE TRIGGER IF NOT EXISTS trig_au_tags
+	AFTER UPDATE ON Tags
+	BEGIN
+		INSERT INTO Tags_fts(Tags_fts, rowid, tag)
+		VALUES ('delete', old.id, old.tag);
+		INSERT INTO Tags_fts(rowid, tag)
+		VALUES (new.id, new.tag);
+	END;
+CREATE TRIGGER IF NOT EXISTS trig_ai_links
+	AFTER INSERT ON Links
+	BEGIN
+		INSERT INTO Links_fts(rowid, link, docId)
+		VALUES (new.rowid, new.link, new.docId);
+	END;
+CREATE TRIGGER IF NOT EXISTS trig_ad_links
+	AFTER DELETE ON Links
+	BEGIN
+		INSERT INTO Links_fts(Links_fts, rowid, link, docId)
+		VALUES ('delete', old.rowid, old.link, old.docId);
+	END;
+CREATE TRIGGER IF NOT EXISTS trig_au_links
+	AFTER UPDATE ON Links
+	BEGIN
+		INSERT INTO Links_fts(Links_fts, rowid, link, docId)
+		VALUES ('delete', old.rowid, old.link, old.docId);
+		INSERT INTO Links_fts(rowid, link, docId)
+		VALUES (new.rowid, new.link, new.docId);
+	END;
+CREATE TRIGGER IF NOT EXISTS trig_ai_doc
+	AFTER INSERT ON Documents
+	BEGIN
+		INSERT INTO Documents_fts(rowid, path, headings, title, meta)
+		VALUES (new.id, new.path, new.headings, new.title, new.meta);
+	END;
+CREATE TRIGGER IF NOT EXISTS trig_ad_doc
+	AFTER DELETE ON Documents
+	BEGIN
+		INSERT INTO Documents_fts(Documents_fts, rowid, path, headings, title, meta)
+		VALUES ('delete', old.id, old.path, old.headings, old.title, old.meta);
+	END;
+CREATE TRIGGER IF NOT EXISTS trig_au_doc
+	AFTER UPDATE ON Documents
+	BEGIN
+		INSERT INTO Documents_fts(Documents_fts, rowid, path, headings, title, meta)
+		VALUES ('delete', old.id, old.path, old.headings, old.title, old.meta);
+		INSERT INTO Documents_fts(rowid, path, headings, title, meta)
+		VALUES (new.id, new.path, new.headings, new.title, new.meta);
+	END;
+CREATE VIEW IF NOT EXISTS Search AS
+	SELECT
+		d.id AS docId,
+		d_fts.path,
+		d_fts.title,
+		d.date,
+		d.fileTime,
+		d_fts.headings,
+		d_fts.meta,
+		a_fts.author,
+		t_fts.tag,
+		l_fts.link
+	FROM Documents d
+	JOIN Documents_fts as d_fts ON d.id = d_fts.rowid
+	LEFT JOIN DocumentAuthors da ON d.id = da.docId
+	LEFT JOIN Authors_fts a_fts ON da.authorId = a_fts.rowid
+	LEFT JOIN DocumentTags dt ON d.id = dt.docId
+	LEFT JOIN Tags_fts t_fts ON dt.tagId = t_fts.rowid
+	LEFT JOIN Links_fts l_fts ON d.id = l_fts.docId;
+INSERT INTO Info (key, value, updated) VALUES ('created', '', 0), ('version', 'baseline', 0);
+INSERT INTO Documents (id, path, headings, title, date, fileTime, meta)
+VALUES (1, '/notes/old.md', '# Groceries', 'Shopping list', 0, 1, 'project: errands');
+INSERT INTO Authors (id, author) VALUES (1, 'Rob Pike');
+INSERT INTO DocumentAuthors (docId, authorId) VALUES (1, 1);
+INSERT INTO Tags (id, tag) VALUES (1, 'errands');
+INSERT INTO DocumentTags (docId, tagId) VALUES (1, 1);
@@ -11,15 +11,17 @@ import (
 )
 
 type Update struct {
-	Id  int64
-	Doc index.Document
-	db  *sql.DB
-	tx  *sql.Tx
+	Id      int64
+	Doc     index.Document
+	Written bool // if Doc was stored by the last call to Update
+	db      *sql.DB
+	tx      *sql.Tx
 }
 
 type UpdateMany struct {
 	Docs     map[int64]*index.Document
 	PathDocs map[string]*index.Document
+	Progress index.ProgressFunc // optional, called as documents are written
 	tx       *sql.Tx
 	Db       *sql.DB
 }
@@ -30,6 +32,7 @@ func NewUpdate(ctx context.Context, db *sql.DB, doc index.Document) Update {
 
 // Replace a document if its filetime is newer than the one in the database.
 func (u *Update) Update(ctx context.Context) error {
+	u.Written = false
 	var err error
 	u.tx, err = u.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -52,6 +55,16 @@ func (u *Update) Update(ctx context.Context) error {
 		return err
 	}
 
+	if err := u.citations(); err != nil {
+		u.tx.Rollback()
+		return err
+	}
+
+	if err := u.metaFields(); err != nil {
+		u.tx.Rollback()
+		return err
+	}
+
 	if err := u.authors(); err != nil {
 		u.tx.Rollback()
 		return err
@@ -60,10 +73,15 @@ func (u *Update) Update(ctx context.Context) error {
 	if _, err := u.tx.Exec("INSERT OR REPLACE INTO Info(key,value,updated) VALUES (?,?,?)",
 		"lastUpdate", "singleUpdate", time.Now().UTC().Unix(),
 	); err != nil {
+		u.tx.Rollback()
 		return err
 	}
 
-	return u.tx.Commit()
+	if err := u.tx.Commit(); err != nil {
+		return err
+	}
+	u.Written = true
+	return nil
 }
 
 func (u *UpdateMany) Update(ctx context.Context) error {
@@ -92,6 +110,18 @@ func (u *UpdateMany) Update(ctx context.Context) error {
 		return err
 	}
 
+	if err := u.citations(); err != nil {
+		slog.Debug("Error updating citations")
+		u.tx.Rollback()
+		return err
+	}
+
+	if err := u.metaFields(); err != nil {
+		slog.Debug("Error updating meta fields")
+		u.tx.Rollback()
+		return err
+	}
+
 	if err := u.authors(); err != nil {
 		slog.Debug("Error updating authors")
 		u.tx.Rollback()
@@ -109,21 +139,18 @@ func (u *UpdateMany) Update(ctx context.Context) error {
 
 // set document fields in db, returns if an update has occured
 func (u *Update) document() (bool, error) {
-	filetime := sql.NullInt64{Int64: u.Doc.FileTime.Unix(), Valid: !u.Doc.FileTime.IsZero()}
-	row := u.tx.QueryRow(`
-	SELECT TRUE
-	FROM Documents
-	WHERE path = ? AND COALESCE(fileTime,0) < COALESCE(?,0)
-	`, u.Doc.Path, filetime)
-
-	isUpdate := false
-	if err := row.Scan(&isUpdate); err == sql.ErrNoRows {
-		isUpdate = true
-	} else if err != nil {
-		return false, err
+	filetime := sql.NullInt64{Valid: !u.Doc.FileTime.IsZero()}
+	if filetime.Valid {
+		filetime.Int64 = u.Doc.FileTime.Unix()
 	}
 
-	if !isUpdate {
+	// documents without a filetime are older than any with one
+	var storedTime sql.NullInt64
+	row := u.tx.QueryRow("SELECT fileTime FROM Documents WHERE path = ?", u.Doc.Path)
+	if err := row.Scan(&storedTime); err != nil && err != sql.ErrNoRows {
+		return false, err
+	} else if err == nil && storedTime.Int64 >= filetime.Int64 {
+		// stored document is at least as new
 		return false, nil
 	}
 
@@ -131,18 +158,22 @@ func (u *Update) document() (bool, error) {
 	date := sql.NullInt64{Int64: u.Doc.Date.Unix(), Valid: !u.Doc.Date.IsZero()}
 	headings := sql.NullString{String: u.Doc.Headings, Valid: u.Doc.Headings != ""}
 	meta := sql.NullString{String: u.Doc.OtherMeta, Valid: u.Doc.OtherMeta != ""}
+	zettelId := sql.NullString{String: u.Doc.ID, Valid: u.Doc.ID != ""}
+	lang := sql.NullString{String: u.Doc.Language, Valid: u.Doc.Language != ""}
 
 	_, err := u.tx.Exec(`
-	INSERT INTO Documents(path, title, date, fileTime, headings, meta)
-	VALUES (?,?,?,?,?,?)
+	INSERT INTO Documents(path, title, date, fileTime, headings, meta, zettelId, lang)
+	VALUES (?,?,?,?,?,?,?,?)
 	ON CONFLICT(path)
 	DO UPDATE SET
 		title=excluded.title,
 		date=excluded.date,
 		fileTime=excluded.fileTime,
 		headings=excluded.headings,
-		meta=excluded.meta
-	`, u.Doc.Path, title, date, filetime, headings, meta)
+		meta=excluded.meta,
+		zettelId=excluded.zettelId,
+		lang=excluded.lang
+	`, u.Doc.Path, title, date, filetime, headings, meta, zettelId, lang)
 	if err != nil {
 		return true, err
 	}
@@ -163,20 +194,27 @@ func (u *UpdateMany) documents() (bool, error) {
 		date INT,
 		fileTime INT,
 		headings TEXT,
-		meta BLOB
+		meta BLOB,
+		zettelId TEXT,
+		lang TEXT
 	)`)
 	if err != nil {
 		return false, err
 	}
 	defer u.tx.Exec("DROP TABLE temp.updateDocs")
 
-	tempInsertStmt, err := u.tx.Prepare("INSERT INTO temp.updateDocs VALUES (?,?,?,?,?,?)")
+	tempInsertStmt, err := u.tx.Prepare("INSERT INTO temp.updateDocs VALUES (?,?,?,?,?,?,?,?)")
 	if err != nil {
 		return false, err
 	}
 	defer tempInsertStmt.Close()
 
+	written := 0
 	for path, doc := range u.PathDocs {
+		if u.Progress != nil {
+			u.Progress("write", written, len(u.PathDocs), 0)
+		}
+		written++
 		filetime := sql.NullInt64{
 			Int64: doc.FileTime.Unix(),
 			Valid: !doc.FileTime.IsZero(),
@@ -197,7 +235,15 @@ func (u *UpdateMany) documents() (bool, error) {
 			String: doc.OtherMeta,
 			Valid:  doc.OtherMeta != "",
 		}
-		if _, err := tempInsertStmt.Exec(path, title, date, filetime, headings, meta); err != nil {
+		zettelId := sql.NullString{
+			String: doc.ID,
+			Valid:  doc.ID != "",
+		}
+		lang := sql.NullString{
+			String: doc.Language,
+			Valid:  doc.Language != "",
+		}
+		if _, err := tempInsertStmt.Exec(path, title, date, filetime, headings, meta, zettelId, lang); err != nil {
 			return false, err
 		}
 	}
@@ -213,14 +259,16 @@ func (u *UpdateMany) documents() (bool, error) {
 	}
 
 	_, err = u.tx.Exec(`
-	INSERT INTO Documents (path, title, date, fileTime, headings, meta)
+	INSERT INTO Documents (path, title, date, fileTime, headings, meta, zettelId, lang)
 	SELECT * FROM updateDocs WHERE TRUE
 	ON CONFLICT(path) DO UPDATE SET
 		title=excluded.title,
 		date=excluded.date,
 		fileTime=excluded.fileTime,
 		headings=excluded.headings,
-		meta=excluded.meta
+		meta=excluded.meta,
+		zettelId=excluded.zettelId,
+		lang=excluded.lang
 	WHERE excluded.fileTime > Documents.fileTime
 	`)
 	if err != nil {
@@ -262,6 +310,10 @@ func (u Update) tags() error {
 		return err
 	}
 
+	if len(u.Doc.Tags) == 0 {
+		return nil
+	}
+
 	query, args := BatchQuery(
 		"INSERT OR IGNORE INTO Tags (tag) VALUES",
 		"", "(?)", ",", "",
@@ -343,6 +395,10 @@ func (u Update) links() error {
 		return err
 	}
 
+	if len(u.Doc.Links) == 0 {
+		return nil
+	}
+
 	query, args := BatchQuery(
 		"INSERT INTO Links VALUES ",
 		"", fmt.Sprintf("(%d,?)", u.Id), ",", "",
@@ -382,6 +438,57 @@ func (u UpdateMany) links() error {
 	return nil
 }
 
+func (u Update) citations() error {
+	if _, err := u.tx.Exec(`
+	DELETE FROM Citations
+	WHERE docId = ?
+	`, u.Id); err != nil {
+		return err
+	}
+
+	if len(u.Doc.Citations) == 0 {
+		return nil
+	}
+
+	query, args := BatchQuery(
+		"INSERT OR IGNORE INTO Citations VALUES ",
+		"", fmt.Sprintf("(%d,?)", u.Id), ",", "",
+		len(u.Doc.Citations), u.Doc.Citations,
+	)
+	if _, err := u.tx.Exec(query, args...); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (u UpdateMany) citations() error {
+	deleteStmt, err := u.tx.Prepare("DELETE FROM Citations WHERE docId = ?")
+	if err != nil {
+		return err
+	}
+	defer deleteStmt.Close()
+	insertStmt, err := u.tx.Prepare("INSERT OR IGNORE INTO Citations VALUES (?,?)")
+	if err != nil {
+		return err
+	}
+	defer insertStmt.Close()
+
+	for id, doc := range u.Docs {
+		if _, err := deleteStmt.Exec(id); err != nil {
+			return err
+		}
+
+		for _, citation := range doc.Citations {
+			if _, err := insertStmt.Exec(id, citation); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 func (u Update) authors() error {
 	if _, err := u.tx.Exec(`
 	DELETE FROM DocumentAuthors
@@ -390,18 +497,24 @@ func (u Update) authors() error {
 		return err
 	}
 
-	authStmt, err := u.tx.Prepare("INSERT OR IGNORE INTO Authors(author) VALUES(?)")
+	authStmt, err := u.tx.Prepare(insertAuthorQuery)
 	if err != nil {
 		return err
 	}
 	defer authStmt.Close()
 
-	idStmt, err := u.tx.Prepare("SELECT id FROM Authors WHERE author = ?")
+	idStmt, err := u.tx.Prepare(authorIdQuery)
 	if err != nil {
 		return err
 	}
 	defer idStmt.Close()
 
+	emailStmt, err := u.tx.Prepare(setAuthorEmailQuery)
+	if err != nil {
+		return err
+	}
+	defer emailStmt.Close()
+
 	docAuthStmt, err := u.tx.Prepare(
 		fmt.Sprintf("INSERT INTO DocumentAuthors(docId,authorId) VALUES (%d,?)", u.Id),
 	)
@@ -418,6 +531,11 @@ func (u Update) authors() error {
 		if err := idStmt.QueryRow(author).Scan(&authId); err != nil {
 			return err
 		}
+		if email := u.Doc.Emails[author]; email != "" {
+			if _, err := emailStmt.Exec(email, authId); err != nil {
+				return err
+			}
+		}
 		if _, err := docAuthStmt.Exec(authId); err != nil {
 			return err
 		}
@@ -433,18 +551,24 @@ func (u UpdateMany) authors() error {
 	}
 	defer deleteStmt.Close()
 
-	authStmt, err := u.tx.Prepare("INSERT OR IGNORE INTO Authors(author) VALUES(?)")
+	authStmt, err := u.tx.Prepare(insertAuthorQuery)
 	if err != nil {
 		return err
 	}
 	defer authStmt.Close()
 
-	idStmt, err := u.tx.Prepare("SELECT id FROM Authors WHERE author = ?")
+	idStmt, err := u.tx.Prepare(authorIdQuery)
 	if err != nil {
 		return err
 	}
 	defer idStmt.Close()
 
+	emailStmt, err := u.tx.Prepare(setAuthorEmailQuery)
+	if err != nil {
+		return err
+	}
+	defer emailStmt.Close()
+
 	docAuthStmt, err := u.tx.Prepare("INSERT INTO DocumentAuthors(docId,authorId) VALUES (?,?)")
 	if err != nil {
 		return err
@@ -463,6 +587,11 @@ func (u UpdateMany) authors() error {
 			if err := idStmt.QueryRow(author).Scan(&authId); err != nil {
 				return err
 			}
+			if email := doc.Emails[author]; email != "" {
+				if _, err := emailStmt.Exec(email, authId); err != nil {
+					return err
+				}
+			}
 			if _, err := docAuthStmt.Exec(docId, authId); err != nil {
 				return err
 			}
@@ -0,0 +1,138 @@
+package index
+
+import (
+	"maps"
+	"slices"
+)
+
+// Changes required to bring one set of documents in line with another
+type Diff struct {
+	Added    []string            // paths of new documents
+	Removed  []string            // paths of documents that no longer exist
+	Modified map[string][]string // paths of newer documents to the names of fields that changed
+}
+
+// Compare documents in idx against those in next.
+// A document is only modified when next has a newer filetime in seconds,
+// mirroring how an index update replaces documents.
+func (idx Index) Diff(next Index) Diff {
+	diff := Diff{Modified: make(map[string][]string)}
+
+	for path, doc := range next.Documents {
+		prev, ok := idx.Documents[path]
+		if !ok {
+			diff.Added = append(diff.Added, path)
+		} else if doc.FileTime.Unix() > prev.FileTime.Unix() {
+			diff.Modified[path] = prev.ChangedFields(*doc)
+		}
+	}
+	for path := range idx.Documents {
+		if _, ok := next.Documents[path]; !ok {
+			diff.Removed = append(diff.Removed, path)
+		}
+	}
+
+	slices.Sort(diff.Added)
+	slices.Sort(diff.Removed)
+
+	return diff
+}
+
+func (d Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0
+}
+
+// Paths of modified documents in sorted order
+func (d Diff) ModifiedPaths() []string {
+	return slices.Sorted(maps.Keys(d.Modified))
+}
+
+// Names of fields which differ between doc and other.
+// Authors, tags, and links are compared ignoring order. Only emails listed
+// by other are compared, since a stored email is kept until it is replaced.
+func (doc Document) ChangedFields(other Document) []string {
+	fields := make([]string, 0, 8)
+	if doc.ID != other.ID {
+		fields = append(fields, "id")
+	}
+	if doc.Title != other.Title {
+		fields = append(fields, "title")
+	}
+	if doc.Language != other.Language {
+		fields = append(fields, "lang")
+	}
+	if !doc.Date.Equal(other.Date) {
+		fields = append(fields, "date")
+	}
+	if !doc.FileTime.Equal(other.FileTime) {
+		fields = append(fields, "filetime")
+	}
+	if !unorderedEqual(doc.Authors, other.Authors) || !emailsKept(doc.Emails, other.Emails) {
+		fields = append(fields, "authors")
+	}
+	if !unorderedEqual(doc.Tags, other.Tags) {
+		fields = append(fields, "tags")
+	}
+	if !unorderedEqual(doc.Links, other.Links) {
+		fields = append(fields, "links")
+	}
+	if !unorderedEqual(doc.Citations, other.Citations) {
+		fields = append(fields, "citations")
+	}
+	if doc.Headings != other.Headings {
+		fields = append(fields, "headings")
+	}
+	if doc.OtherMeta != other.OtherMeta {
+		fields = append(fields, "meta")
+	}
+
+	return fields
+}
+
+func unorderedEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+// Check that every email in next is also in prev
+func emailsKept(prev, next map[string]string) bool {
+	for author, email := range next {
+		if prev[author] != email {
+			return false
+		}
+	}
+	return true
+}
+
+// Copy of idx with author aliases replaced by their authors, matching how
+// documents are stored. aliases maps each alias to its author.
+func (idx Index) ResolveAliases(aliases map[string]string) Index {
+	resolved := idx
+	resolved.Documents = make(map[string]*Document, len(idx.Documents))
+	for path, doc := range idx.Documents {
+		d := *doc
+		d.Authors = make([]string, 0, len(doc.Authors))
+		if doc.Emails != nil {
+			d.Emails = make(map[string]string, len(doc.Emails))
+		}
+		for _, author := range doc.Authors {
+			name := author
+			if a, ok := aliases[author]; ok {
+				name = a
+			}
+			if !slices.Contains(d.Authors, name) {
+				d.Authors = append(d.Authors, name)
+			}
+			if email, ok := doc.Emails[author]; ok {
+				d.Emails[name] = email
+			}
+		}
+		resolved.Documents[path] = &d
+	}
+	return resolved
+}
@@ -0,0 +1,44 @@
+package index_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jpappel/atlas/pkg/index"
+)
+
+func TestIndex_Diff(t *testing.T) {
+	older, newer := time.Unix(1, 0), time.Unix(2, 0)
+	prev := index.Index{Documents: map[string]*index.Document{
+		"/kept":     {Path: "/kept", FileTime: older, Title: "kept"},
+		"/stale":    {Path: "/stale", FileTime: newer, Title: "stale"},
+		"/modified": {Path: "/modified", FileTime: older, Title: "old", Tags: []string{"a", "b"}, Authors: []string{"x", "y"}},
+		"/removed":  {Path: "/removed", FileTime: older},
+	}}
+	next := index.Index{Documents: map[string]*index.Document{
+		"/kept":     {Path: "/kept", FileTime: older, Title: "kept"},
+		"/stale":    {Path: "/stale", FileTime: older, Title: "changed"},
+		"/modified": {Path: "/modified", FileTime: newer, Title: "new", Tags: []string{"b", "a"}, Authors: []string{"y", "x"}},
+		"/added":    {Path: "/added", FileTime: newer},
+	}}
+
+	diff := prev.Diff(next)
+
+	if want := []string{"/added"}; !slices.Equal(diff.Added, want) {
+		t.Errorf("Added = %v, want %v", diff.Added, want)
+	}
+	if want := []string{"/removed"}; !slices.Equal(diff.Removed, want) {
+		t.Errorf("Removed = %v, want %v", diff.Removed, want)
+	}
+	if want := []string{"/modified"}; !slices.Equal(diff.ModifiedPaths(), want) {
+		t.Errorf("Modified = %v, want %v", diff.ModifiedPaths(), want)
+	}
+	if want := []string{"title", "filetime"}; !slices.Equal(diff.Modified["/modified"], want) {
+		t.Errorf("Changed fields = %v, want %v", diff.Modified["/modified"], want)
+	}
+
+	if !prev.Diff(prev).Empty() {
+		t.Error("Expected no differences between an index and itself")
+	}
+}
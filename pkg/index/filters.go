@@ -1,6 +1,7 @@
 package index
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
@@ -152,70 +153,48 @@ var YamlHeaderFilter = DocFilter{
 	},
 }
 
-// Position of the end of a yaml header, negative
+// Position of the newline ending the closing line of a yaml header, negative
+// if r does not start with a header. Headers are closed by --- or ...
 func YamlHeaderPos(r io.ReadSeeker) int64 {
 	const bufSize = 4096
-	buf := make([]byte, bufSize)
-
-	carry := make([]byte, 4)
-	cmp := make([]byte, 4)
-	n, err := r.Read(carry)
-	if err != nil || n < 4 || string(carry) != "---\n" {
+	// the opening line is kept at the start of buf since it may end on the
+	// same newline which begins the closing line
+	buf := make([]byte, 4+bufSize)
+	if _, err := io.ReadFull(r, buf[:4]); err != nil || string(buf[:4]) != "---\n" {
 		return -1
 	}
 
-	pos := int64(3)
-	headerFound := false
-	readMore := true
-	for readMore {
-		buf = buf[:bufSize]
-		n, err := r.Read(buf)
-		if err == io.EOF {
-			readMore = false
-		} else if err != nil {
-			return -1
-		}
-		buf = buf[:n]
-
-		// PERF: the carry doesn't need to be checked on the first loop iteration
-		for i := range min(4, n) {
-			pos++
-			b := carry[i]
-			for j := range 4 {
-				if i+j < 4 {
-					cmp[j] = carry[i+j]
-				} else {
-					cmp[j] = buf[(i+j)%4]
-				}
-			}
-			if b == '\n' && string(cmp) == "\n---\n" {
-				headerFound = true
-				readMore = false
-				break
-			}
+	// offset of buf[0] in r and the length of the previous chunk's tail at the start of buf
+	start := int64(0)
+	tail := 4
+	for {
+		n, err := r.Read(buf[tail:])
+		data := buf[:tail+n]
+		// a closing line never fits entirely within the tail, so matches are new
+		if i := headerCloseIndex(data); i >= 0 {
+			return start + int64(i) + 4
 		}
-		for i := range n - 4 {
-			pos++
-			b := buf[i]
-			if b == '\n' && string(buf[i:i+5]) == "\n---\n" {
-				headerFound = true
-				readMore = false
-				break
-			}
+		if err != nil {
+			return -1
 		}
 
-		if readMore {
-			for i := range 4 {
-				carry[i] = buf[n-4+i]
-			}
-		}
+		// carry the end of data over to check closing lines across reads
+		newTail := min(4, len(data))
+		copy(buf, data[len(data)-newTail:])
+		start += int64(len(data) - newTail)
+		tail = newTail
 	}
+}
 
-	if headerFound {
-		return pos
-	} else {
-		return -1
+// Index of the newline before the first closing line of a yaml header in b
+func headerCloseIndex(b []byte) int {
+	i := -1
+	for _, closing := range []string{"\n---\n", "\n...\n"} {
+		if j := bytes.Index(b, []byte(closing)); j >= 0 && (i < 0 || j < i) {
+			i = j
+		}
 	}
+	return i
 }
 
 func DefaultFilters() []DocFilter {
@@ -2,8 +2,10 @@ package index_test
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/jpappel/atlas/pkg/index"
@@ -29,6 +31,11 @@ func trailingYamlHeader() io.ReadSeeker {
 	return bytes.NewReader(buf)
 }
 
+func pandocYamlHeader() io.ReadSeeker {
+	buf := []byte("---\ntitle: bizbaz\nabstract: |\n  some text\n...\nhere are some content\n")
+	return bytes.NewReader(buf)
+}
+
 func extensionless(t *testing.T) index.InfoPath {
 	root := t.TempDir()
 	path := root + "/" + "afile"
@@ -91,6 +98,7 @@ func TestYamlHeaderFilter(t *testing.T) {
 	}{
 		{"completeYamlHeader", completeYamlHeader(), true},
 		{"trailingYamlHeader", trailingYamlHeader(), true},
+		{"pandocYamlHeader", pandocYamlHeader(), true},
 		{"noYamlHeader", noYamlHeader(), false},
 		{"incompleteYamlHeader", incompleteYamlHeader(), false},
 	}
@@ -104,6 +112,53 @@ func TestYamlHeaderFilter(t *testing.T) {
 	}
 }
 
+// Reads at most one byte at a time
+type oneByteReadSeeker struct {
+	*bytes.Reader
+}
+
+func (r oneByteReadSeeker) Read(p []byte) (int, error) {
+	return r.Reader.Read(p[:min(1, len(p))])
+}
+
+func TestYamlHeaderPos(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int64
+	}{
+		{"no header", "just some text", -1},
+		{"short", "---\nab", -1},
+		{"empty header", "---\n---\nbody", 7},
+		{"dashes", "---\ntitle: a\n---\nbody", 16},
+		{"dots", "---\ntitle: a\n...\nbody", 16},
+		{"first terminator", "---\ntitle: a\n...\nb\n---\n", 16},
+		{"unterminated", "---\ntitle: a\n---", -1},
+	}
+	// closing lines around the end of the first read
+	for _, closing := range []string{"---", "..."} {
+		for offset := 4090; offset < 4106; offset++ {
+			content := "---\na: " + strings.Repeat("x", offset-len("---\na: ")) + "\n" + closing + "\nbody\n"
+			tests = append(tests, struct {
+				name    string
+				content string
+				want    int64
+			}{fmt.Sprintf("%s boundary %d", closing, offset), content, int64(offset + 4)})
+		}
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := index.YamlHeaderPos(strings.NewReader(tt.content)); got != tt.want {
+				t.Errorf("YamlHeaderPos() = %d, want %d", got, tt.want)
+			}
+			r := oneByteReadSeeker{bytes.NewReader([]byte(tt.content))}
+			if got := index.YamlHeaderPos(r); got != tt.want {
+				t.Errorf("YamlHeaderPos() reading one byte at a time = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestExtensionFilter(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -1,11 +1,14 @@
 package index
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"os"
 	"path"
 	"regexp"
@@ -22,27 +25,52 @@ import (
 
 var ErrHeaderParse error = errors.New("Unable to parse YAML header")
 var DocParseRegex *regexp.Regexp
+var CitationGroupRegex *regexp.Regexp
+var CitationKeyRegex *regexp.Regexp
+var AuthorEmailRegex *regexp.Regexp
+
+// Matches 12 or 14 digit zettel timestamps (YYYYMMDDhhmm[ss]) in filenames
+var DefaultIDPattern = regexp.MustCompile(`(?:^|\D)(?P<id>\d{12}(?:\d{2})?)(?:\D|$)`)
 
 type Document struct {
-	Path      string    `yaml:"-" json:"path"`
-	Title     string    `yaml:"title" json:"title"`
-	Date      time.Time `yaml:"-" json:"date"`
-	FileTime  time.Time `yaml:"-" json:"filetime"`
-	Authors   []string  `yaml:"-" json:"authors"`
-	Tags      []string  `yaml:"tags,omitempty" json:"tags"`
-	Links     []string  `yaml:"-" json:"links"`
-	Headings  string    `yaml:"-" json:"headings"`
-	OtherMeta string    `yaml:"-" json:"meta"`
-	parseOpts ParseOpts
+	Path       string            `yaml:"-" json:"path"`
+	ID         string            `yaml:"-" json:"id,omitempty"`
+	Title      string            `yaml:"title" json:"title"`
+	Language   string            `yaml:"-" json:"lang,omitempty"`
+	Date       time.Time         `yaml:"-" json:"date"`
+	FileTime   time.Time         `yaml:"-" json:"filetime"`
+	Authors    []string          `yaml:"-" json:"authors"`
+	Emails     map[string]string `yaml:"-" json:"emails,omitempty"` // author to email
+	Tags       []string          `yaml:"tags,omitempty" json:"tags"`
+	Links      []string          `yaml:"-" json:"links"`
+	Citations  []string          `yaml:"-" json:"citations"`
+	Headings   string            `yaml:"-" json:"headings"`
+	OtherMeta  string            `yaml:"-" json:"meta"`
+	MetaFields []MetaField       `yaml:"-" json:"metaFields,omitempty"`
+	Pinned     bool              `yaml:"-" json:"pinned,omitempty"`   // set in the database rather than the file
+	Database   string            `yaml:"-" json:"database,omitempty"` // source database, only set when querying multiple databases
+	parseOpts  ParseOpts
+}
+
+// A typed scalar from a YAML header, sequences produce a field per element
+// and nested mappings are flattened into dotted keys
+type MetaField struct {
+	Key   string `yaml:"key" json:"key"`
+	Value any    `yaml:"value" json:"value"` // string, bool, or float64
 }
 
 type ParseOpts struct {
 	ParseMeta       bool
 	ParseHeadings   bool
 	ParseLinks      bool
+	ParseCitations  bool
+	DetectLanguage  bool // guess the language of documents without a lang header
 	IgnoreDateError bool
 	IgnoreMetaError bool
 	IgnoreHidden    bool
+	// Pattern for zettel ids in filenames, uses the "id" group if present.
+	// A nil pattern only reads ids from the header.
+	IDPattern *regexp.Regexp
 }
 
 type InfoPath struct {
@@ -54,6 +82,18 @@ type Index struct {
 	Root      string // root directory for searching
 	Documents map[string]*Document
 	Filters   []DocFilter
+	Progress  ProgressFunc // optional, called as files pass through each stage of indexing
+}
+
+// Reports the number of files processed by an indexing stage out of total,
+// a total of 0 is unknown. Errors is the count of files which failed the stage.
+// May be called concurrently and out of order by workers.
+type ProgressFunc func(stage string, processed int, total int, errors uint64)
+
+func (idx Index) report(stage string, processed int, total int, errors uint64) {
+	if idx.Progress != nil {
+		idx.Progress(stage, processed, total, errors)
+	}
 }
 
 func (idx Index) String() string {
@@ -75,17 +115,30 @@ var _ yaml.NodeUnmarshaler = (*Document)(nil)
 var _ yaml.BytesMarshaler = (*Document)(nil)
 
 func (doc *Document) MarshalYAML() ([]byte, error) {
-	return yaml.Marshal(yaml.MapSlice{
+	fields := yaml.MapSlice{
 		{Key: "path", Value: doc.Path},
+		{Key: "id", Value: doc.ID},
 		{Key: "title", Value: doc.Title},
+		{Key: "lang", Value: doc.Language},
 		{Key: "date", Value: doc.Date},
 		{Key: "filetime", Value: doc.FileTime},
 		{Key: "authors", Value: doc.Authors},
+		{Key: "emails", Value: doc.Emails},
 		{Key: "tags", Value: doc.Tags},
 		{Key: "links", Value: doc.Links},
+		{Key: "citations", Value: doc.Citations},
 		{Key: "headings", Value: doc.Headings},
 		{Key: "meta", Value: doc.OtherMeta},
-	})
+		{Key: "metaFields", Value: doc.MetaFields},
+	}
+	if doc.Pinned {
+		fields = append(fields, yaml.MapItem{Key: "pinned", Value: true})
+	}
+	if doc.Database != "" {
+		fields = append(fields, yaml.MapItem{Key: "database", Value: doc.Database})
+	}
+
+	return yaml.Marshal(fields)
 }
 
 func (doc *Document) UnmarshalYAML(node ast.Node) error {
@@ -122,10 +175,33 @@ func (doc *Document) UnmarshalYAML(node ast.Node) error {
 				return err
 			}
 		} else if keyPath == "$.author" {
-			if err := doc.parseAuthor(v); err != nil {
+			detailed, err := doc.parseAuthor(v)
+			if err != nil {
+				return err
+			}
+			// keep affiliations and other author details searchable
+			if detailed && doc.parseOpts.ParseMeta {
+				if field, err := kv.MarshalYAML(); err == nil {
+					buf.Write(field)
+					buf.WriteByte('\n')
+				} else if !doc.parseOpts.IgnoreMetaError {
+					return err
+				}
+			}
+		} else if keyPath == "$.lang" {
+			if n, ok := v.(*ast.StringNode); ok {
+				doc.Language = NormalizeLanguage(n.Value)
+			}
+		} else if keyPath == "$.id" {
+			if err := doc.parseIDNode(v); err != nil {
+				return err
+			}
+		} else if keyPath == "$.keywords" {
+			if err := doc.parseKeywords(v); err != nil {
 				return err
 			}
 		} else if doc.parseOpts.ParseMeta {
+			doc.parseMetaFields(strings.TrimPrefix(keyPath, "$."), v)
 			field, err := kv.MarshalYAML()
 			if err != nil {
 				if doc.parseOpts.IgnoreMetaError {
@@ -144,19 +220,83 @@ func (doc *Document) UnmarshalYAML(node ast.Node) error {
 	return nil
 }
 
+func (doc *Document) parseIDNode(node ast.Node) error {
+	switch n := node.(type) {
+	case *ast.StringNode:
+		doc.ID = n.Value
+	case *ast.IntegerNode:
+		// unquoted timestamps are decoded as integers
+		doc.ID = n.GetToken().Value
+	default:
+		return fmt.Errorf("%w: expected a string id", ErrHeaderParse)
+	}
+	return nil
+}
+
+// Add typed fields for scalar values of a header key
+func (doc *Document) parseMetaFields(key string, node ast.Node) {
+	switch n := node.(type) {
+	case *ast.BoolNode:
+		doc.MetaFields = append(doc.MetaFields, MetaField{key, n.Value})
+	case *ast.StringNode:
+		doc.MetaFields = append(doc.MetaFields, MetaField{key, n.Value})
+	case *ast.LiteralNode:
+		doc.MetaFields = append(doc.MetaFields, MetaField{key, n.Value.Value})
+	case *ast.IntegerNode:
+		switch v := n.Value.(type) {
+		case int64:
+			doc.MetaFields = append(doc.MetaFields, MetaField{key, float64(v)})
+		case uint64:
+			doc.MetaFields = append(doc.MetaFields, MetaField{key, float64(v)})
+		}
+	case *ast.FloatNode:
+		doc.MetaFields = append(doc.MetaFields, MetaField{key, n.Value})
+	case *ast.SequenceNode:
+		for _, elem := range n.Values {
+			if _, ok := elem.(*ast.SequenceNode); !ok {
+				doc.parseMetaFields(key, elem)
+			}
+		}
+	case *ast.MappingNode:
+		for _, kv := range n.Values {
+			doc.parseMetaFields(key+"."+kv.Key.GetToken().Value, kv.Value)
+		}
+	case *ast.MappingValueNode:
+		doc.parseMetaFields(key+"."+n.Key.GetToken().Value, n.Value)
+	}
+}
+
+// Find a zettel id in the filename of docPath
+func parseIDFromPath(docPath string, pattern *regexp.Regexp) string {
+	name := path.Base(docPath)
+	match := pattern.FindStringSubmatch(name)
+	if match == nil {
+		return ""
+	}
+	if i := pattern.SubexpIndex("id"); i > 0 {
+		return match[i]
+	}
+	return match[0]
+}
+
 func (doc *Document) parseDateNode(node ast.Node) error {
-	dateNode, ok := node.(*ast.StringNode)
-	if !ok {
+	var dateStr string
+	switch dateNode := node.(type) {
+	case *ast.StringNode:
+		dateStr = dateNode.Value
+	case *ast.IntegerNode:
+		// year, compact date, or unix timestamp
+		dateStr = dateNode.String()
+	default:
 		return ErrHeaderParse
 	}
-	dateStr := dateNode.Value
 
 	if dateStr == "" {
 		return nil
 	}
 
 	if date, err := util.ParseDateTime(dateStr); err != nil {
-		return fmt.Errorf("Unable to parse date: %s", dateNode.Value)
+		return fmt.Errorf("Unable to parse date: %s", dateStr)
 	} else {
 		doc.Date = date
 	}
@@ -164,35 +304,113 @@ func (doc *Document) parseDateNode(node ast.Node) error {
 	return nil
 }
 
-func (doc *Document) parseAuthor(node ast.Node) error {
-	authorsNode, ok := node.(*ast.SequenceNode)
-	if ok {
-		doc.Authors = make([]string, 0, len(authorsNode.Values))
-		for _, authorNode := range authorsNode.Values {
-			authorStrNode, ok := authorNode.(*ast.StringNode)
+// Parse an author or list of authors. Authors may be Pandoc style mappings
+// with a name and other details such as an affiliation, in which case
+// detailed is true.
+func (doc *Document) parseAuthor(node ast.Node) (detailed bool, err error) {
+	var nodes []ast.Node
+	if authorsNode, ok := node.(*ast.SequenceNode); ok {
+		nodes = authorsNode.Values
+	} else {
+		nodes = []ast.Node{node}
+	}
+
+	doc.Authors = make([]string, 0, len(nodes))
+	for _, authorNode := range nodes {
+		switch authorNode := authorNode.(type) {
+		case *ast.StringNode:
+			doc.addAuthor(SplitAuthorEmail(authorNode.Value))
+		case *ast.MappingNode:
+			name, ok := mappingString(authorNode.Values, "name")
 			if !ok {
-				return ErrHeaderParse
+				return false, ErrHeaderParse
+			}
+			email, _ := mappingString(authorNode.Values, "email")
+			doc.addAuthor(name, email)
+			detailed = true
+		case *ast.MappingValueNode:
+			name, ok := mappingString([]*ast.MappingValueNode{authorNode}, "name")
+			if !ok {
+				return false, ErrHeaderParse
 			}
-			doc.Authors = append(doc.Authors, authorStrNode.Value)
+			doc.addAuthor(name, "")
+			detailed = true
+		default:
+			return false, ErrHeaderParse
 		}
-	} else {
-		authorNode, ok := node.(*ast.StringNode)
-		if ok {
-			doc.Authors = []string{authorNode.Value}
-		} else {
-			return ErrHeaderParse
+	}
+
+	return detailed, nil
+}
+
+func (doc *Document) addAuthor(name, email string) {
+	doc.Authors = append(doc.Authors, name)
+	if email == "" {
+		return
+	}
+	if doc.Emails == nil {
+		doc.Emails = make(map[string]string)
+	}
+	doc.Emails[name] = email
+}
+
+// Split an author of the form `Name <email>` into its name and email.
+// Authors without an email are returned unchanged.
+func SplitAuthorEmail(author string) (name string, email string) {
+	m := AuthorEmailRegex.FindStringSubmatch(author)
+	if m == nil {
+		return author, ""
+	}
+	return m[1], m[2]
+}
+
+// Get the string value of key in a mapping
+func mappingString(values []*ast.MappingValueNode, key string) (string, bool) {
+	for _, kv := range values {
+		if kv.Key.GetToken().Value != key {
+			continue
+		}
+		if v, ok := kv.Value.(*ast.StringNode); ok {
+			return v.Value, true
+		}
+		return "", false
+	}
+	return "", false
+}
+
+// Add Pandoc keywords, a list or comma separated string, as tags
+func (doc *Document) parseKeywords(node ast.Node) error {
+	var keywords []string
+	switch node := node.(type) {
+	case *ast.StringNode:
+		keywords = strings.Split(node.Value, ",")
+	case *ast.SequenceNode:
+		for _, keywordNode := range node.Values {
+			keywordStrNode, ok := keywordNode.(*ast.StringNode)
+			if !ok {
+				return ErrHeaderParse
+			}
+			keywords = append(keywords, keywordStrNode.Value)
 		}
+	default:
+		return ErrHeaderParse
 	}
 
+	for _, keyword := range keywords {
+		keyword = strings.TrimSpace(keyword)
+		if keyword != "" && !slices.Contains(doc.Tags, keyword) {
+			doc.Tags = append(doc.Tags, keyword)
+		}
+	}
 	return nil
 }
 
 func (doc Document) Equal(other Document) bool {
-	if len(doc.Authors) != len(other.Authors) || len(doc.Tags) != len(other.Tags) || len(doc.Links) != len(other.Links) || doc.Path != other.Path || doc.Title != other.Title || doc.OtherMeta != other.OtherMeta || doc.Headings != other.Headings || !doc.Date.Equal(other.Date) {
+	if len(doc.Authors) != len(other.Authors) || len(doc.Tags) != len(other.Tags) || len(doc.Links) != len(other.Links) || len(doc.Citations) != len(other.Citations) || doc.Path != other.Path || doc.ID != other.ID || doc.Title != other.Title || doc.Language != other.Language || doc.OtherMeta != other.OtherMeta || doc.Headings != other.Headings || !doc.Date.Equal(other.Date) {
 		return false
 	}
 
-	if !slices.Equal(doc.Authors, other.Authors) {
+	if !slices.Equal(doc.Authors, other.Authors) || !maps.Equal(doc.Emails, other.Emails) {
 		return false
 	}
 
@@ -212,44 +430,19 @@ func (doc Document) Equal(other Document) bool {
 		}
 	}
 
-	return true
-}
-
-func visit(file InfoPath, visitQueue chan<- InfoPath, filterQueue chan<- InfoPath, ignoreHidden bool, wg *sync.WaitGroup) {
-	// TODO: extract error out of function
-
-	if ignoreHidden && path.Base(file.Path)[0] == '.' {
-		wg.Done()
-		return
-	}
-
-	if file.Info.IsDir() {
-		entries, err := os.ReadDir(file.Path)
-		if err != nil {
-			panic(err)
-		}
-		wg.Add(len(entries))
-		for _, entry := range entries {
-			entryInfo, err := entry.Info()
-			if err != nil {
-				panic(err)
-			}
-			// PERF: prevents deadlock but introduces an additional goroutine overhead per file
-			go func(path string) {
-				visitQueue <- InfoPath{Path: path, Info: entryInfo}
-			}(file.Path + "/" + entry.Name())
+	slices.Sort(doc.Citations)
+	slices.Sort(other.Citations)
+	for i := range doc.Citations {
+		if doc.Citations[i] != other.Citations[i] {
+			return false
 		}
-	} else if file.Info.Mode().IsRegular() {
-		filterQueue <- file
 	}
 
-	wg.Done()
-}
-
-func workerTraverse(wg *sync.WaitGroup, ignoreHidden bool, visitQueue chan InfoPath, filterQueue chan<- InfoPath) {
-	for work := range visitQueue {
-		visit(work, visitQueue, filterQueue, ignoreHidden, wg)
+	if !slices.Equal(doc.MetaFields, other.MetaFields) {
+		return false
 	}
+
+	return true
 }
 
 func (idx Index) Traverse(numWorkers uint, ignoreHidden bool) []string {
@@ -257,32 +450,48 @@ func (idx Index) Traverse(numWorkers uint, ignoreHidden bool) []string {
 		panic(fmt.Sprint("Invalid number of workers: ", numWorkers))
 	}
 	docs := make([]string, 0)
+	mu := &sync.Mutex{}
+	crawled := &atomic.Int64{}
 
 	rootInfo, err := os.Stat(idx.Root)
 	if err != nil {
 		panic(err)
 	}
 
-	jobs := make(chan InfoPath, numWorkers)
-	filterQueue := make(chan InfoPath, numWorkers)
-
-	activeJobs := &sync.WaitGroup{}
-
-	for range numWorkers {
-		go workerTraverse(activeJobs, ignoreHidden, jobs, filterQueue)
-	}
+	var pool *util.Pool[InfoPath]
+	pool = util.NewPool(context.Background(), numWorkers, func(_ context.Context, file InfoPath) error {
+		if ignoreHidden && path.Base(file.Path)[0] == '.' {
+			return nil
+		}
 
-	activeJobs.Add(1)
-	jobs <- InfoPath{Path: idx.Root, Info: rootInfo}
+		if file.Info.IsDir() {
+			entries, err := os.ReadDir(file.Path)
+			if err != nil {
+				return err
+			}
+			var errs []error
+			for _, entry := range entries {
+				entryInfo, err := entry.Info()
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				pool.Submit(InfoPath{Path: file.Path + "/" + entry.Name(), Info: entryInfo})
+			}
+			return errors.Join(errs...)
+		} else if file.Info.Mode().IsRegular() {
+			mu.Lock()
+			docs = append(docs, file.Path)
+			mu.Unlock()
+			idx.report("crawl", int(crawled.Add(1)), 0, 0)
+		}
 
-	go func() {
-		activeJobs.Wait()
-		close(jobs)
-		close(filterQueue)
-	}()
+		return nil
+	})
+	pool.Submit(InfoPath{Path: idx.Root, Info: rootInfo})
 
-	for doc := range filterQueue {
-		docs = append(docs, doc.Path)
+	if err := pool.Wait(); err != nil {
+		slog.Warn("Errors occured while traversing", slog.String("err", err.Error()))
 	}
 
 	return docs
@@ -313,44 +522,60 @@ func (idx Index) FilterOne(path string) bool {
 
 func (idx Index) Filter(paths []string, numWorkers uint) []string {
 	fPaths := make([]string, 0, len(paths))
-	jobs := make(chan string, numWorkers)
-	accepted := make(chan string, numWorkers)
-	wg := &sync.WaitGroup{}
-
-	wg.Add(int(numWorkers))
-	for range numWorkers {
-		go func(jobs <-chan string, accepted chan<- string, wg *sync.WaitGroup) {
-			for path := range jobs {
-				if idx.FilterOne(path) {
-					accepted <- path
-				}
-			}
-			wg.Done()
-		}(jobs, accepted, wg)
-	}
-
-	go func(jobs chan<- string) {
-		for _, path := range paths {
-			jobs <- path
+	mu := &sync.Mutex{}
+	filtered := &atomic.Int64{}
+
+	pool := util.NewPool(context.Background(), numWorkers, func(_ context.Context, path string) error {
+		if idx.FilterOne(path) {
+			mu.Lock()
+			fPaths = append(fPaths, path)
+			mu.Unlock()
 		}
-		close(jobs)
-	}(jobs)
+		idx.report("filter", int(filtered.Add(1)), len(paths), 0)
+		return nil
+	})
+	pool.Submit(paths...)
+	pool.Wait()
 
-	go func() {
-		wg.Wait()
-		close(accepted)
-	}()
+	return fPaths
+}
 
-	for path := range accepted {
-		fPaths = append(fPaths, path)
+// Order pinned documents before unpinned documents
+func PinnedCmp(a, b *Document) int {
+	if a.Pinned == b.Pinned {
+		return 0
+	} else if a.Pinned {
+		return -1
 	}
+	return 1
+}
 
-	return fPaths
+// Create a comparison function for documents by comma separated fields.
+// Ties on a field are broken by the following fields.
+// Pinned documents are always ordered first.
+// Allowed fields: path,id,title,lang,date,filetime,meta,headings
+func NewDocCmp(fields string, reverse bool) (func(*Document, *Document) int, bool) {
+	cmps := make([]func(*Document, *Document) int, 1, strings.Count(fields, ",")+2)
+	cmps[0] = PinnedCmp
+	for field := range strings.SplitSeq(fields, ",") {
+		docCmp, ok := newFieldCmp(strings.TrimSpace(field), reverse)
+		if !ok {
+			return nil, false
+		}
+		cmps = append(cmps, docCmp)
+	}
+
+	return func(a, b *Document) int {
+		for _, docCmp := range cmps {
+			if c := docCmp(a, b); c != 0 {
+				return c
+			}
+		}
+		return 0
+	}, true
 }
 
-// Create a comparison function for documents by field.
-// Allowed fields: path,title,date,filetime,meta
-func NewDocCmp(field string, reverse bool) (func(*Document, *Document) int, bool) {
+func newFieldCmp(field string, reverse bool) (func(*Document, *Document) int, bool) {
 	descMod := 1
 	if reverse {
 		descMod = -1
@@ -360,10 +585,18 @@ func NewDocCmp(field string, reverse bool) (func(*Document, *Document) int, bool
 		return func(a, b *Document) int {
 			return descMod * strings.Compare(a.Path, b.Path)
 		}, true
+	case "id":
+		return func(a, b *Document) int {
+			return descMod * strings.Compare(a.ID, b.ID)
+		}, true
 	case "title":
 		return func(a, b *Document) int {
 			return descMod * strings.Compare(a.Title, b.Title)
 		}, true
+	case "lang":
+		return func(a, b *Document) int {
+			return descMod * strings.Compare(a.Language, b.Language)
+		}, true
 	case "date":
 		return func(a, b *Document) int {
 			return descMod * a.Date.Compare(b.Date)
@@ -385,6 +618,50 @@ func NewDocCmp(field string, reverse bool) (func(*Document, *Document) int, bool
 	return nil, false
 }
 
+const (
+	maxHeaderSize    = 1 << 20  // largest YAML header read while parsing
+	maxBodySize      = 16 << 20 // largest document body searched for links and headings
+	maxPooledBufSize = 1 << 20
+)
+
+var readerPool = sync.Pool{
+	New: func() any { return bufio.NewReaderSize(nil, 4096) },
+}
+
+var bufPool = sync.Pool{
+	New: func() any { return &bytes.Buffer{} },
+}
+
+var errNoYamlHeader = errors.New("Can't find YAML header")
+
+// Read a YAML header, including its opening delimiter, into buf.
+// The header may be closed with "---" or Pandoc's "...".
+// r is left positioned after the closing delimiter.
+func readYamlHeader(r *bufio.Reader, buf *bytes.Buffer) error {
+	line, err := r.ReadSlice('\n')
+	if err != nil || string(line) != "---\n" {
+		return errNoYamlHeader
+	}
+	buf.Write(line)
+
+	lineStart := true
+	for {
+		line, err := r.ReadSlice('\n')
+		if lineStart && (string(line) == "---\n" || string(line) == "...\n") {
+			return nil
+		} else if err != nil && err != bufio.ErrBufferFull {
+			return errNoYamlHeader
+		}
+
+		buf.Write(line)
+		if buf.Len() > maxHeaderSize {
+			return fmt.Errorf("YAML header larger than %d bytes", maxHeaderSize)
+		}
+		// long lines are returned in pieces
+		lineStart = err == nil
+	}
+}
+
 func ParseDoc(path string, opts ParseOpts) (*Document, error) {
 	doc := &Document{Path: path, parseOpts: opts}
 
@@ -400,21 +677,44 @@ func ParseDoc(path string, opts ParseOpts) (*Document, error) {
 	}
 	doc.FileTime = info.ModTime()
 
-	pos := YamlHeaderPos(f)
-	f.Seek(0, io.SeekStart)
-	if pos < 0 {
-		return nil, fmt.Errorf("Can't find YAML header in %s", path)
+	r, ok := readerPool.Get().(*bufio.Reader)
+	if !ok {
+		panic("Expected *bufio.Reader in pool")
 	}
-	header := io.NewSectionReader(f, 0, pos)
+	r.Reset(f)
+	defer func() {
+		r.Reset(nil)
+		readerPool.Put(r)
+	}()
 
-	if err := yaml.NewDecoder(header).Decode(doc); err != nil {
+	buf, ok := bufPool.Get().(*bytes.Buffer)
+	if !ok {
+		panic("Expected *bytes.Buffer in pool")
+	}
+	buf.Reset()
+	defer func() {
+		// don't keep buffers from unusually large files alive
+		if buf.Cap() <= maxPooledBufSize {
+			bufPool.Put(buf)
+		}
+	}()
+
+	if err := readYamlHeader(r, buf); err != nil {
+		return nil, fmt.Errorf("%w in %s", err, path)
+	}
+
+	if err := yaml.NewDecoder(bytes.NewReader(buf.Bytes())).Decode(doc); err != nil {
 		return nil, errors.Join(ErrHeaderParse, err)
 	}
 
-	if opts.ParseLinks || opts.ParseHeadings {
-		var buf bytes.Buffer
-		f.Seek(pos, io.SeekStart)
-		if _, err := io.Copy(&buf, f); err != nil {
+	if doc.ID == "" && opts.IDPattern != nil {
+		doc.ID = parseIDFromPath(path, opts.IDPattern)
+	}
+
+	detectLanguage := opts.DetectLanguage && doc.Language == ""
+	if opts.ParseLinks || opts.ParseHeadings || opts.ParseCitations || detectLanguage {
+		buf.Reset()
+		if _, err := buf.ReadFrom(io.LimitReader(r, maxBodySize)); err != nil {
 			return nil, err
 		}
 
@@ -426,7 +726,8 @@ func ParseDoc(path string, opts ParseOpts) (*Document, error) {
 			LINK
 		)
 
-		matches := DocParseRegex.FindAllSubmatch(buf.Bytes(), -1)
+		body := stripComments(buf.Bytes())
+		matches := DocParseRegex.FindAllSubmatch(body, -1)
 		b := strings.Builder{}
 		for _, match := range matches {
 			if opts.ParseHeadings {
@@ -449,52 +750,149 @@ func ParseDoc(path string, opts ParseOpts) (*Document, error) {
 		}
 
 		doc.Headings = b.String()
+
+		if opts.ParseCitations {
+			doc.Citations = parseCitations(body)
+		}
+
+		if detectLanguage {
+			doc.Language = DetectLanguage(body)
+		}
 	}
 
 	return doc, nil
 }
 
-func ParseDocs(paths []string, numWorkers uint, opts ParseOpts) (map[string]*Document, uint64) {
-	jobs := make(chan string, numWorkers)
-	results := make(chan *Document, numWorkers)
-	docs := make(map[string]*Document, len(paths))
-	wg := &sync.WaitGroup{}
-
-	errCnt := &atomic.Uint64{}
-	wg.Add(int(numWorkers))
-	for range numWorkers {
-		go func(jobs <-chan string, results chan<- *Document, wg *sync.WaitGroup) {
-			for path := range jobs {
-				doc, err := ParseDoc(path, opts)
-				if err != nil {
-					slog.Warn("Error occured while parsing file",
-						slog.String("path", path), slog.String("err", err.Error()),
-					)
-					errCnt.Add(1)
-					continue
-				}
+// Remove html comments from body, an unterminated comment runs to the end of body.
+// Comments within fenced code blocks and inline code are kept.
+func stripComments(body []byte) []byte {
+	const commentStart, commentEnd = "<!--", "-->"
+
+	out := make([]byte, 0, len(body))
+	var fence []byte // opening marker of the fenced code block being copied
+	lineStart := true
+	for i := 0; i < len(body); {
+		if lineStart {
+			line := body[i:]
+			if n := bytes.IndexByte(line, '\n'); n >= 0 {
+				line = line[:n+1]
+			}
+			marker := codeFence(line)
+			if fence == nil && marker != nil {
+				fence = marker
+			} else if fence != nil && marker != nil && marker[0] == fence[0] &&
+				len(marker) >= len(fence) && len(bytes.TrimSpace(line)) == len(marker) {
+				fence = nil
+			} else if fence == nil {
+				lineStart = false
+				continue
+			}
+			out = append(out, line...)
+			i += len(line)
+			continue
+		}
 
-				results <- doc
+		switch {
+		case body[i] == '\n':
+			out = append(out, '\n')
+			i++
+			lineStart = true
+		case body[i] == '`':
+			run := len(body[i:]) - len(bytes.TrimLeft(body[i:], "`"))
+			rest := body[i+run:]
+			if n := bytes.IndexByte(rest, '\n'); n >= 0 {
+				rest = rest[:n]
+			}
+			end := i + run + max(codeSpanEnd(rest, run), 0)
+			out = append(out, body[i:end]...)
+			i = end
+		case bytes.HasPrefix(body[i:], []byte(commentStart)):
+			end := bytes.Index(body[i+len(commentStart):], []byte(commentEnd))
+			if end < 0 {
+				return out
 			}
-			wg.Done()
-		}(jobs, results, wg)
+			i += len(commentStart) + end + len(commentEnd)
+		default:
+			out = append(out, body[i])
+			i++
+		}
 	}
 
-	go func(jobs chan<- string, paths []string) {
-		for _, path := range paths {
-			jobs <- path
+	return out
+}
+
+// Get the run of backticks or tildes opening a fenced code block on line, or nil
+func codeFence(line []byte) []byte {
+	trimmed := bytes.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 || len(trimmed) == 0 || (trimmed[0] != '`' && trimmed[0] != '~') {
+		return nil
+	}
+	n := len(trimmed) - len(bytes.TrimLeft(trimmed, string(trimmed[:1])))
+	if n < 3 {
+		return nil
+	}
+	return trimmed[:n]
+}
+
+// Get the offset in s just past a run of exactly n backticks, or -1
+func codeSpanEnd(s []byte, n int) int {
+	for i := 0; i < len(s); {
+		if s[i] != '`' {
+			i++
+			continue
 		}
-		close(jobs)
-	}(jobs, paths)
+		run := len(s[i:]) - len(bytes.TrimLeft(s[i:], "`"))
+		if run == n {
+			return i + run
+		}
+		i += run
+	}
+	return -1
+}
 
-	go func(results chan *Document, wg *sync.WaitGroup) {
-		wg.Wait()
-		close(results)
-	}(results, wg)
+// Find unique pandoc style citation keys within brackets, ie [see @knuth1974, p. 33; @pike1984]
+func parseCitations(body []byte) []string {
+	var citations []string
+	for _, group := range CitationGroupRegex.FindAll(body, -1) {
+		for _, match := range CitationKeyRegex.FindAllSubmatch(group, -1) {
+			key := string(match[1])
+			if !slices.Contains(citations, key) {
+				citations = append(citations, key)
+			}
+		}
+	}
+	return citations
+}
 
-	for doc := range results {
+func ParseDocs(paths []string, numWorkers uint, opts ParseOpts) (map[string]*Document, uint64) {
+	return Index{}.ParseDocs(paths, numWorkers, opts)
+}
+
+// Parse documents at paths, reporting progress to idx.Progress
+func (idx Index) ParseDocs(paths []string, numWorkers uint, opts ParseOpts) (map[string]*Document, uint64) {
+	docs := make(map[string]*Document, len(paths))
+	mu := &sync.Mutex{}
+
+	parsed := &atomic.Int64{}
+	errCnt := &atomic.Uint64{}
+	pool := util.NewPool(context.Background(), numWorkers, func(_ context.Context, path string) error {
+		doc, err := ParseDoc(path, opts)
+		if err != nil {
+			slog.Warn("Error occured while parsing file",
+				slog.String("path", path), slog.String("err", err.Error()),
+			)
+			idx.report("parse", int(parsed.Add(1)), len(paths), errCnt.Add(1))
+			return nil
+		}
+
+		mu.Lock()
 		docs[doc.Path] = doc
-	}
+		mu.Unlock()
+		idx.report("parse", int(parsed.Add(1)), len(paths), errCnt.Load())
+		return nil
+	})
+	pool.Submit(paths...)
+	pool.Wait()
 
 	return docs, errCnt.Load()
 }
@@ -508,4 +906,9 @@ func init() {
 			headingPattern + "|" +
 			linkPattern,
 	)
+
+	CitationGroupRegex = regexp.MustCompile(`\[[^\[\]\n]*@[^\[\]\n]*\]`)
+	// keys start with a letter, digit or underscore and may contain internal punctuation
+	CitationKeyRegex = regexp.MustCompile(`(?:^|[\s;\[-])-?@([\pL\pN_](?:[\pL\pN_:.#$%&+?<>~/-]*[\pL\pN_])?)`)
+	AuthorEmailRegex = regexp.MustCompile(`^\s*([^<>]*?)\s*<([^<>\s@]+@[^<>\s@]+)>\s*$`)
 }
@@ -3,8 +3,14 @@ package index_test
 import (
 	"errors"
 	"fmt"
+	"io/fs"
+	"maps"
 	"os"
+	"path/filepath"
+	"regexp"
 	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -102,6 +108,110 @@ func TestIndex_Traverse(t *testing.T) {
 	}
 }
 
+// Create a tree of 6 visible files, 4 of which pass the default filters and 1 fails to parse
+func newWalkTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		"a.md":          "---\ntitle: A\n---\n",
+		"sub/b.md":      "---\ntitle: B\n---\n",
+		"sub/c.txt":     "---\ntitle: C\n---\n",
+		"sub/d.md":      "no header\n",
+		".hidden/e.md":  "---\ntitle: E\n---\n",
+		"sub/.f.md":     "---\ntitle: F\n---\n",
+		"sub/bad.md":    "---\ntitle: [\n---\n",
+		"sub/deep/g.md": "---\ntitle: G\n---\n",
+	}
+	for name, contents := range files {
+		path := root + "/" + name
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// Records the furthest progress of each stage
+type progressRecorder struct {
+	mu     sync.Mutex
+	stages map[string][3]int // processed, total, errors
+}
+
+func (r *progressRecorder) record(stage string, processed int, total int, errors uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stages == nil {
+		r.stages = make(map[string][3]int)
+	}
+	last := r.stages[stage]
+	r.stages[stage] = [3]int{max(last[0], processed), max(last[1], total), max(last[2], int(errors))}
+}
+
+func TestIndex_Walk(t *testing.T) {
+	root := newWalkTree(t)
+	progress := &progressRecorder{}
+	idx := index.Index{Root: root, Filters: index.DefaultFilters(), Progress: progress.record}
+	docs, stats, err := idx.Walk(2, true, index.ParseOpts{})
+	if err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+
+	wantStats := index.WalkStats{Crawled: 6, Filtered: 4, Errors: 1}
+	if stats != wantStats {
+		t.Errorf("Got stats %+v, want %+v", stats, wantStats)
+	}
+
+	got := slices.Sorted(maps.Keys(docs))
+	want := []string{root + "/a.md", root + "/sub/b.md", root + "/sub/deep/g.md"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Got documents %v, want %v", got, want)
+	}
+
+	// totals are unknown while walking
+	wantProgress := map[string][3]int{"crawl": {6, 0, 0}, "filter": {6, 0, 0}, "parse": {4, 0, 1}}
+	if !maps.Equal(progress.stages, wantProgress) {
+		t.Errorf("Got progress %v, want %v", progress.stages, wantProgress)
+	}
+}
+
+func TestIndex_Walk_Traverse(t *testing.T) {
+	// paths are formed like Traverse regardless of how the root is written
+	idx := index.Index{Root: newWalkTree(t) + "/", Filters: index.DefaultFilters()}
+	docs, _, err := idx.Walk(2, true, index.ParseOpts{})
+	if err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+
+	traversed, _ := idx.ParseDocs(idx.Filter(idx.Traverse(2, true), 2), 2, index.ParseOpts{})
+	if got, want := slices.Sorted(maps.Keys(docs)), slices.Sorted(maps.Keys(traversed)); !slices.Equal(got, want) {
+		t.Errorf("Walked %v, traversed %v", got, want)
+	}
+
+	idx.Root = filepath.Join(t.TempDir(), "missing")
+	if _, _, err := idx.Walk(2, true, index.ParseOpts{}); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Walk() error = %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestIndex_Progress(t *testing.T) {
+	progress := &progressRecorder{}
+	idx := index.Index{Root: newWalkTree(t), Filters: index.DefaultFilters(), Progress: progress.record}
+
+	paths := idx.Filter(idx.Traverse(2, true), 2)
+	docs, errCnt := idx.ParseDocs(paths, 2, index.ParseOpts{})
+	if len(docs) != 3 || errCnt != 1 {
+		t.Errorf("Parsed %d documents with %d errors, want 3 with 1", len(docs), errCnt)
+	}
+
+	wantProgress := map[string][3]int{"crawl": {6, 0, 0}, "filter": {6, 6, 0}, "parse": {4, 4, 1}}
+	if !maps.Equal(progress.stages, wantProgress) {
+		t.Errorf("Got progress %v, want %v", progress.stages, wantProgress)
+	}
+}
+
 func TestIndex_Filter(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -206,6 +316,63 @@ func TestIndex_ParseOne(t *testing.T) {
 			&index.Document{Date: time.Date(2025, time.May, 1, 0, 0, 0, 0, time.UTC)},
 			nil,
 		},
+		{
+			"epoch date",
+			func(t *testing.T) string {
+				f, path := newTestFile(t, "date")
+				defer f.Close()
+
+				f.WriteString("---\ndate: 1746057600\n---\n")
+
+				return path
+			},
+			index.ParseOpts{},
+			&index.Document{Date: time.Date(2025, time.May, 1, 0, 0, 0, 0, time.UTC)},
+			nil,
+		},
+		{
+			"compact date",
+			func(t *testing.T) string {
+				f, path := newTestFile(t, "date")
+				defer f.Close()
+
+				f.WriteString("---\ndate: 20250501\n---\n")
+
+				return path
+			},
+			index.ParseOpts{},
+			&index.Document{Date: time.Date(2025, time.May, 1, 0, 0, 0, 0, time.UTC)},
+			nil,
+		},
+		{
+			"iso week date",
+			func(t *testing.T) string {
+				f, path := newTestFile(t, "date")
+				defer f.Close()
+
+				f.WriteString("---\ndate: 2025-W18\n---\n")
+
+				return path
+			},
+			index.ParseOpts{},
+			&index.Document{Date: time.Date(2025, time.April, 28, 0, 0, 0, 0, time.UTC)},
+			nil,
+		},
+		{
+			"long header line",
+			func(t *testing.T) string {
+				f, path := newTestFile(t, "long")
+				defer f.Close()
+
+				f.WriteString("---\ntitle: " + strings.Repeat("a", 10000) + "\n---\n")
+				f.WriteString("# A heading\n")
+
+				return path
+			},
+			index.ParseOpts{ParseHeadings: true},
+			&index.Document{Title: strings.Repeat("a", 10000), Headings: "# A heading\n"},
+			nil,
+		},
 		{
 			"single author",
 			func(t *testing.T) string {
@@ -234,6 +401,88 @@ func TestIndex_ParseOne(t *testing.T) {
 			&index.Document{Authors: []string{"Robert Griesemer", "Rob Pike", "Ken Thompson"}},
 			nil,
 		},
+		{
+			"pandoc authors",
+			func(t *testing.T) string {
+				f, path := newTestFile(t, "author")
+				defer f.Close()
+
+				f.WriteString("---\nauthor:\n- name: Rob Pike\n  affiliation: Bell Labs\n- Ken Thompson\n---\n")
+
+				return path
+			},
+			index.ParseOpts{ParseMeta: true},
+			&index.Document{
+				Authors:   []string{"Rob Pike", "Ken Thompson"},
+				OtherMeta: "author:\n- name: Rob Pike\n  affiliation: Bell Labs\n- Ken Thompson\n",
+			},
+			nil,
+		},
+		{
+			"author emails",
+			func(t *testing.T) string {
+				f, path := newTestFile(t, "author")
+				defer f.Close()
+
+				f.WriteString("---\nauthor:\n- Rob Pike <r@golang.org>\n- name: Ken Thompson\n  email: ken@golang.org\n- Robert Griesemer\n---\n")
+
+				return path
+			},
+			index.ParseOpts{},
+			&index.Document{
+				Authors: []string{"Rob Pike", "Ken Thompson", "Robert Griesemer"},
+				Emails:  map[string]string{"Rob Pike": "r@golang.org", "Ken Thompson": "ken@golang.org"},
+			},
+			nil,
+		},
+		{
+			"pandoc author without name",
+			func(t *testing.T) string {
+				f, path := newTestFile(t, "author")
+				defer f.Close()
+
+				f.WriteString("---\nauthor:\n  affiliation: Bell Labs\n---\n")
+
+				return path
+			},
+			index.ParseOpts{},
+			&index.Document{},
+			index.ErrHeaderParse,
+		},
+		{
+			"pandoc keywords",
+			func(t *testing.T) string {
+				f, path := newTestFile(t, "keywords")
+				defer f.Close()
+
+				f.WriteString("---\ntags: [go]\nkeywords: [unix, go, plan9]\n---\n")
+
+				return path
+			},
+			index.ParseOpts{ParseMeta: true},
+			&index.Document{Tags: []string{"go", "unix", "plan9"}},
+			nil,
+		},
+		{
+			"pandoc metadata block",
+			func(t *testing.T) string {
+				f, path := newTestFile(t, "pandoc")
+				defer f.Close()
+
+				f.WriteString("---\ntitle: A title\nsubtitle: and more\nkeywords: systems, languages\n...\n# A heading\n")
+
+				return path
+			},
+			index.ParseOpts{ParseMeta: true, ParseHeadings: true},
+			&index.Document{
+				Title:      "A title",
+				Tags:       []string{"systems", "languages"},
+				OtherMeta:  "subtitle: and more\n",
+				MetaFields: []index.MetaField{{Key: "subtitle", Value: "and more"}},
+				Headings:   "# A heading\n",
+			},
+			nil,
+		},
 		{
 			"meta",
 			func(t *testing.T) string {
@@ -247,7 +496,43 @@ func TestIndex_ParseOne(t *testing.T) {
 				return path
 			},
 			index.ParseOpts{ParseMeta: true},
-			&index.Document{OtherMeta: "unknownKey: value\n"},
+			&index.Document{
+				OtherMeta:  "unknownKey: value\n",
+				MetaFields: []index.MetaField{{Key: "unknownKey", Value: "value"}},
+			},
+			nil,
+		},
+		{
+			"typed meta",
+			func(t *testing.T) string {
+				f, path := newTestFile(t, "typed")
+				defer f.Close()
+
+				f.WriteString("---\n")
+				f.WriteString("draft: true\n")
+				f.WriteString("status: \"true\"\n")
+				f.WriteString("aliases: [a, b]\n")
+				f.WriteString("rating: 4\n")
+				f.WriteString("weight: 0.5\n")
+				f.WriteString("project:\n  name: atlas\n  owner: {name: jp}\n")
+				f.WriteString("---\n")
+
+				return path
+			},
+			index.ParseOpts{ParseMeta: true},
+			&index.Document{
+				OtherMeta: "draft: true\nstatus: \"true\"\naliases: [a, b]\nrating: 4\nweight: 0.5\nproject:\n  name: atlas\n  owner: {name: jp}\n",
+				MetaFields: []index.MetaField{
+					{Key: "draft", Value: true},
+					{Key: "status", Value: "true"},
+					{Key: "aliases", Value: "a"},
+					{Key: "aliases", Value: "b"},
+					{Key: "rating", Value: float64(4)},
+					{Key: "weight", Value: 0.5},
+					{Key: "project.name", Value: "atlas"},
+					{Key: "project.owner.name", Value: "jp"},
+				},
+			},
 			nil,
 		},
 		{
@@ -320,6 +605,147 @@ func TestIndex_ParseOne(t *testing.T) {
 			},
 			nil,
 		},
+		{
+			"zettel id header",
+			func(t *testing.T) string {
+				f, path := newTestFile(t, "202401121230 note")
+				defer f.Close()
+
+				f.WriteString("---\nid: 202312251200\n---\n")
+
+				return path
+			},
+			index.ParseOpts{IDPattern: index.DefaultIDPattern},
+			&index.Document{ID: "202312251200"},
+			nil,
+		},
+		{
+			"zettel id filename",
+			func(t *testing.T) string {
+				f, path := newTestFile(t, "202401121230 note.md")
+				defer f.Close()
+
+				f.WriteString("---\ntitle: A note\n---\n")
+
+				return path
+			},
+			index.ParseOpts{IDPattern: index.DefaultIDPattern},
+			&index.Document{Title: "A note", ID: "202401121230"},
+			nil,
+		},
+		{
+			"zettel id pattern",
+			func(t *testing.T) string {
+				f, path := newTestFile(t, "z-42.md")
+				defer f.Close()
+
+				f.WriteString("---\ntitle: A note\n---\n")
+
+				return path
+			},
+			index.ParseOpts{IDPattern: regexp.MustCompile(`^z-(?P<id>\d+)`)},
+			&index.Document{Title: "A note", ID: "42"},
+			nil,
+		},
+		{
+			"citations",
+			func(t *testing.T) string {
+				f, path := newTestFile(t, "citations")
+				defer f.Close()
+
+				f.WriteString("---\ntitle: Citations\n---\n")
+				f.WriteString("As shown [see @knuth1974, p. 33; -@pike1984].\n")
+				f.WriteString("Mail [jp@example.com] and @bare are ignored, [@knuth1974] is repeated.\n")
+				f.WriteString("Keys may contain punctuation [@doe:2020.a].\n")
+
+				return path
+			},
+			index.ParseOpts{ParseCitations: true},
+			&index.Document{Title: "Citations", Citations: []string{"knuth1974", "pike1984", "doe:2020.a"}},
+			nil,
+		},
+		{
+			"html comments",
+			func(t *testing.T) string {
+				f, path := newTestFile(t, "comments")
+				defer f.Close()
+
+				f.WriteString("---\ntitle: Comments\n---\n")
+				f.WriteString("# Kept\n")
+				f.WriteString("<!--\n# Commented heading\n[draft](draft.md)\n-->\n")
+				f.WriteString("See [kept](kept.md)<!-- [hidden](hidden.md) --> and [@knuth1974].\n")
+				f.WriteString("<!-- [@pike1984] -->\n")
+				f.WriteString("## Also kept\n")
+				f.WriteString("<!-- unterminated\n# Never indexed\n")
+
+				return path
+			},
+			index.ParseOpts{ParseHeadings: true, ParseLinks: true, ParseCitations: true},
+			&index.Document{
+				Title:     "Comments",
+				Headings:  "# Kept\n## Also kept\n",
+				Links:     []string{"kept.md"},
+				Citations: []string{"knuth1974"},
+			},
+			nil,
+		},
+		{
+			"html comments in code",
+			func(t *testing.T) string {
+				f, path := newTestFile(t, "code")
+				defer f.Close()
+
+				f.WriteString("---\ntitle: Code\n---\n")
+				f.WriteString("```html\n<!-- unterminated\n```\n")
+				f.WriteString("# After fence\n")
+				f.WriteString("~~~~\n~~~\n-->\n~~~~\n")
+				f.WriteString("Inline `<!--` and ``a ` <!-- b`` then [kept](kept.md)\n")
+				f.WriteString("Unclosed `span <!-- [hidden](hidden.md) -->\n")
+				f.WriteString("<!--\n```\n-->\n")
+				f.WriteString("## Also after\n")
+
+				return path
+			},
+			index.ParseOpts{ParseHeadings: true, ParseLinks: true},
+			&index.Document{
+				Title:    "Code",
+				Headings: "# After fence\n## Also after\n",
+				Links:    []string{"kept.md"},
+			},
+			nil,
+		},
+		{
+			"language header",
+			func(t *testing.T) string {
+				f, path := newTestFile(t, "lang")
+				defer f.Close()
+
+				f.WriteString("---\nlang: de-DE\n---\n")
+				f.WriteString("The header takes priority over the contents of the document.\n")
+
+				return path
+			},
+			index.ParseOpts{DetectLanguage: true},
+			&index.Document{Language: "de"},
+			nil,
+		},
+		{
+			"detected language",
+			func(t *testing.T) string {
+				f, path := newTestFile(t, "lang")
+				defer f.Close()
+
+				f.WriteString("---\ntitle: Notizen\n---\n")
+				f.WriteString("Die Notizen werden in einer Datenbank gespeichert, damit man sie schnell ")
+				f.WriteString("durchsuchen kann. Das ist nicht schwer und es funktioniert auch mit ")
+				f.WriteString("vielen Dokumenten, die in unterschiedlichen Sprachen geschrieben sind.\n")
+
+				return path
+			},
+			index.ParseOpts{DetectLanguage: true},
+			&index.Document{Title: "Notizen", Language: "de"},
+			nil,
+		},
 		{
 			"bad tags",
 			func(t *testing.T) string {
@@ -359,3 +785,95 @@ func TestIndex_ParseOne(t *testing.T) {
 		})
 	}
 }
+
+func TestNewDocCmp(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 1, d, 0, 0, 0, 0, time.UTC) }
+	a := &index.Document{Path: "a", Title: "beta", Date: day(1)}
+	b := &index.Document{Path: "b", Title: "alpha", Date: day(2)}
+	c := &index.Document{Path: "c", Title: "alpha", Date: day(1)}
+	d := &index.Document{Path: "d", Title: "gamma", Date: day(3), Pinned: true}
+
+	tests := []struct {
+		fields  string
+		reverse bool
+		want    []string
+		wantOk  bool
+	}{
+		{"title", false, []string{"d", "b", "c", "a"}, true},
+		{"date,title", false, []string{"d", "c", "a", "b"}, true},
+		{"title,date", false, []string{"d", "c", "b", "a"}, true},
+		{"title, date", true, []string{"d", "a", "b", "c"}, true},
+		{"title,colour", false, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fields, func(t *testing.T) {
+			docCmp, ok := index.NewDocCmp(tt.fields, tt.reverse)
+			if ok != tt.wantOk {
+				t.Fatalf("NewDocCmp() ok = %v, want %v", ok, tt.wantOk)
+			} else if !ok {
+				return
+			}
+
+			docs := []*index.Document{a, b, c, d}
+			slices.SortStableFunc(docs, docCmp)
+			got := make([]string, len(docs))
+			for i, doc := range docs {
+				got[i] = doc.Path
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Sorted = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Create n notes spread across nested directories
+func benchTree(b *testing.B, n int) string {
+	root := b.TempDir()
+	for i := range n {
+		dir := fmt.Sprintf("%s/%d/%d", root, i%10, i%7)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			b.Fatal(err)
+		}
+		f, err := os.Create(fmt.Sprintf("%s/%03d.md", dir, i))
+		if err != nil {
+			b.Fatal(err)
+		}
+		fmt.Fprintf(f, "---\ntitle: Note %d\nauthor: Rob Pike\ntags: [go, notes]\ndate: 2025-01-02\n---\n", i)
+		for j := range 20 {
+			fmt.Fprintf(f, "## Section %d\nSome text with [a link](%03d.md).\n", j, (i+j)%n)
+		}
+		f.Close()
+	}
+	return root
+}
+
+func BenchmarkIndex_Build(b *testing.B) {
+	idx := index.Index{Root: benchTree(b, 500), Filters: index.DefaultFilters()}
+	opts := index.ParseOpts{ParseMeta: true, ParseHeadings: true, ParseLinks: true}
+	for b.Loop() {
+		paths := idx.Filter(idx.Traverse(4, false), 4)
+		if docs, errCnt := index.ParseDocs(paths, 4, opts); errCnt != 0 || len(docs) != 500 {
+			b.Fatalf("Parsed %d documents with %d errors", len(docs), errCnt)
+		}
+	}
+}
+
+func BenchmarkIndex_Walk(b *testing.B) {
+	idx := index.Index{Root: benchTree(b, 500), Filters: index.DefaultFilters()}
+	opts := index.ParseOpts{ParseMeta: true, ParseHeadings: true, ParseLinks: true}
+	for b.Loop() {
+		if docs, stats, err := idx.Walk(4, false, opts); err != nil || stats.Errors != 0 || len(docs) != 500 {
+			b.Fatalf("Parsed %d documents with %d errors", len(docs), stats.Errors)
+		}
+	}
+}
+
+func BenchmarkIndex_Traverse(b *testing.B) {
+	idx := index.Index{Root: benchTree(b, 500)}
+	for b.Loop() {
+		if paths := idx.Traverse(4, false); len(paths) != 500 {
+			b.Fatalf("Traversed %d files", len(paths))
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package index
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Fewest trigrams needed to guess a document's language
+const minLanguageTrigrams = 50
+
+// Frequent trigrams of each language, most frequent first. Spaces mark word boundaries.
+var languageProfiles = map[string][]string{
+	"en": {
+		" th", "the", "he ", "and", " an", "nd ", " of", "of ", " to", "to ",
+		"ed ", "ing", "ng ", " in", "in ", "is ", " is", "er ", "ion", " a ",
+		"tio", "on ", "at ", "re ", "es ", "ent", " co", "hat", "tha", " wh",
+		"for", " fo", "or ", " be", "as ", "it ", " it", "ly ", " re", "her",
+	},
+	"de": {
+		"en ", "er ", " de", "der", "ie ", "ich", "die", " di", "sch", "ein",
+		"che", "ch ", "nd ", " un", "und", "den", "in ", " ei", "te ", "ine",
+		" da", "gen", "es ", "ung", "cht", "ten", "ng ", " ge", "ber", " zu",
+		"ht ", "das", "as ", "nde", "ist", " is", "st ", "auf", " au", "nic",
+	},
+	"fr": {
+		"es ", " de", "de ", "le ", " le", "ent", "nt ", " la", "la ", "re ",
+		"les", "ion", "on ", " qu", "que", "ue ", "tio", "ne ", " et", "et ",
+		"ais", "our", " pa", "par", "des", " co", "men", "ur ", " un", "une",
+		"ans", " da", "dan", "ait", "est", " es", "pou", " po", "eme", "ell",
+	},
+	"es": {
+		"de ", " de", " la", "la ", "os ", "el ", " el", "que", " qu", "ue ",
+		"en ", "es ", "as ", " co", "ión", "ent", "ón ", " en", "con", "ado",
+		"do ", " se", "cio", "del", " lo", "los", "ara", "par", " pa", "est",
+		"ien", "ar ", "nte", " es", "una", " un", "por", " po", "er ", "ada",
+	},
+	"it": {
+		"di ", " di", "che", " ch", "he ", "la ", " la", "to ", "re ", "ell",
+		"del", "one", "ne ", " de", "lla", "le ", "ion", "zio", "ent", "per",
+		" pe", " co", "no ", "il ", " il", "ato", "non", " no", "sta", "ere",
+		"com", "ità", "ess", "nte", "con", " in", "are", "gli", " un", "ono",
+	},
+	"nl": {
+		"en ", "de ", " de", "van", " va", "an ", "het", " he", "et ", "ij ",
+		"een", " ee", "er ", "aar", "oor", " ge", "ing", "ng ", "sch", " in",
+		"dat", " da", "at ", " te", "ver", " ve", "den", "cht", "ie ", "nde",
+		"eer", "te ", "ijn", "zij", " zi", "ven", " op", "op ", "ond", " wo",
+	},
+	"pt": {
+		"de ", " de", "os ", "ão ", " qu", "que", "ue ", " co", "do ", "da ",
+		"ent", "as ", " da", "ção", "açã", "es ", " a ", "com", "em ", " em",
+		" se", "nte", "ara", "par", " pa", "não", " nã", "ado", "ida", "est",
+		"men", "um ", " um", "uma", "con", "dos", "ra ", "te ", " pr", "ões",
+	},
+}
+
+// Guess the primary language of text from its letter trigrams, returning an
+// ISO 639-1 code or an empty string if there is too little text to decide.
+func DetectLanguage(text []byte) string {
+	counts := make(map[string]int)
+	total := 0
+	for word := range strings.FieldsFuncSeq(string(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		runes := []rune(" " + strings.ToLower(word) + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			counts[string(runes[i:i+3])]++
+			total++
+		}
+	}
+	if total < minLanguageTrigrams {
+		return ""
+	}
+
+	// frequent trigrams of a language are weighted higher
+	var lang string
+	bestScore := 0
+	for code, profile := range languageProfiles {
+		score := 0
+		for rank, trigram := range profile {
+			score += counts[trigram] * (len(profile) - rank)
+		}
+		if score > bestScore || (score == bestScore && score > 0 && code < lang) {
+			lang, bestScore = code, score
+		}
+	}
+
+	return lang
+}
+
+// Normalize a language tag such as en-US to its lowercase primary subtag
+func NormalizeLanguage(tag string) string {
+	primary, _, _ := strings.Cut(strings.TrimSpace(tag), "-")
+	primary, _, _ = strings.Cut(primary, "_")
+	return strings.ToLower(primary)
+}
@@ -0,0 +1,39 @@
+package index_test
+
+import (
+	"testing"
+
+	"github.com/jpappel/atlas/pkg/index"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"english", "The notes are stored in a database so that they can be searched quickly. " +
+			"It is not hard to do and it works with many of the documents that are written in other languages.", "en"},
+		{"german", "Die Notizen werden in einer Datenbank gespeichert, damit man sie schnell durchsuchen kann. " +
+			"Das ist nicht schwer und es funktioniert auch mit vielen Dokumenten.", "de"},
+		{"french", "Les notes sont enregistrées dans une base de données pour que l'on puisse les chercher " +
+			"rapidement. Ce n'est pas difficile et cela fonctionne avec des documents dans plusieurs langues.", "fr"},
+		{"spanish", "Las notas se guardan en una base de datos para que se puedan buscar rápidamente. " +
+			"No es difícil y funciona con los documentos que están escritos en otros idiomas.", "es"},
+		{"italian", "Le note sono salvate in una base di dati per poterle cercare velocemente. " +
+			"Non è difficile e funziona anche con molti documenti scritti in altre lingue.", "it"},
+		{"dutch", "De notities worden in een database opgeslagen zodat ze snel doorzocht kunnen worden. " +
+			"Het is niet moeilijk en het werkt ook met veel documenten die in andere talen zijn geschreven.", "nl"},
+		{"portuguese", "As notas são guardadas em uma base de dados para que possam ser pesquisadas " +
+			"rapidamente. Não é difícil e funciona com os documentos que estão escritos em outras línguas.", "pt"},
+		{"too short", "Hello world", ""},
+		{"no letters", "1234 5678 !@#$ %^&* 1234 5678 !@#$ %^&* 1234 5678 !@#$ %^&* 1234 5678", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := index.DetectLanguage([]byte(tt.text)); got != tt.want {
+				t.Errorf("DetectLanguage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
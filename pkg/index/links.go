@@ -0,0 +1,129 @@
+package index
+
+import (
+	"cmp"
+	"errors"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+// A link whose target is neither indexed nor exists on disk
+type BrokenLink struct {
+	Path string // document containing the link
+	Link string
+}
+
+type LinkCount struct {
+	Path  string
+	Count int
+}
+
+type LinkReport struct {
+	Broken     []BrokenLink
+	Orphans    []string    // documents without inbound links
+	MostLinked []LinkCount // documents by number of inbound links, descending
+}
+
+// Resolve a link relative to the document containing it.
+// Returns false for links to external resources or within the same document.
+func (doc Document) ResolveLink(link string) (string, bool) {
+	link, _, _ = strings.Cut(link, "#")
+	link, _, _ = strings.Cut(link, "?")
+	if link == "" {
+		return "", false
+	}
+	if u, err := url.Parse(link); err != nil || u.Scheme != "" {
+		return "", false
+	} else if unescaped, err := url.PathUnescape(link); err == nil {
+		link = unescaped
+	}
+
+	if path.IsAbs(link) {
+		return path.Clean(link), true
+	}
+	return path.Join(path.Dir(doc.Path), link), true
+}
+
+// Paths of documents keyed by zettel id
+func (idx Index) IDs() map[string]string {
+	ids := make(map[string]string)
+	for p, doc := range idx.Documents {
+		if doc.ID != "" {
+			ids[doc.ID] = p
+		}
+	}
+	return ids
+}
+
+// Find broken links, orphaned documents, and inbound link counts.
+// Links matching a zettel id resolve to that document and links without an
+// extension also resolve to markdown documents.
+func (idx Index) LinkReport() LinkReport {
+	report := LinkReport{}
+	inbound := make(map[string]int, len(idx.Documents))
+	for p := range idx.Documents {
+		inbound[p] = 0
+	}
+	ids := idx.IDs()
+
+	onDisk := make(map[string]bool)
+	for _, doc := range idx.Documents {
+		for _, link := range doc.Links {
+			id, _, _ := strings.Cut(link, "#")
+			if target, ok := ids[id]; ok {
+				if target != doc.Path {
+					inbound[target]++
+				}
+				continue
+			}
+
+			target, ok := doc.ResolveLink(link)
+			if !ok {
+				continue
+			}
+
+			if _, ok := idx.Documents[target]; ok {
+				if target != doc.Path {
+					inbound[target]++
+				}
+				continue
+			} else if _, ok := idx.Documents[target+".md"]; ok && path.Ext(target) == "" {
+				inbound[target+".md"]++
+				continue
+			}
+
+			exists, ok := onDisk[target]
+			if !ok {
+				_, err := os.Stat(target)
+				exists = !errors.Is(err, fs.ErrNotExist)
+				onDisk[target] = exists
+			}
+			if !exists {
+				report.Broken = append(report.Broken, BrokenLink{doc.Path, link})
+			}
+		}
+	}
+
+	report.MostLinked = make([]LinkCount, 0, len(inbound))
+	for p, count := range inbound {
+		if count == 0 {
+			report.Orphans = append(report.Orphans, p)
+		} else {
+			report.MostLinked = append(report.MostLinked, LinkCount{p, count})
+		}
+	}
+
+	slices.SortFunc(report.Broken, func(a, b BrokenLink) int {
+		return cmp.Or(strings.Compare(a.Path, b.Path), strings.Compare(a.Link, b.Link))
+	})
+	slices.Sort(report.Orphans)
+	slices.SortFunc(report.MostLinked, func(a, b LinkCount) int {
+		return cmp.Or(b.Count-a.Count, strings.Compare(a.Path, b.Path))
+	})
+
+	return report
+}
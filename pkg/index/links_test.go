@@ -0,0 +1,87 @@
+package index_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/jpappel/atlas/pkg/index"
+)
+
+func TestDocument_ResolveLink(t *testing.T) {
+	doc := index.Document{Path: "/notes/dir/a.md"}
+	tests := []struct {
+		link   string
+		want   string
+		wantOk bool
+	}{
+		{"b.md", "/notes/dir/b.md", true},
+		{"../c.md#heading", "/notes/c.md", true},
+		{"/abs/d.md", "/abs/d.md", true},
+		{"my%20note.md", "/notes/dir/my note.md", true},
+		{"https://example.com/e.md", "", false},
+		{"mailto:jp@example.com", "", false},
+		{"#heading", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.link, func(t *testing.T) {
+			got, gotOk := doc.ResolveLink(tt.link)
+			if gotOk != tt.wantOk || got != tt.want {
+				t.Errorf("ResolveLink() = %q, %v, want %q, %v", got, gotOk, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestIndex_LinkReport(t *testing.T) {
+	root := t.TempDir()
+	asset := filepath.Join(root, "image.png")
+	if err := os.WriteFile(asset, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	a, b, c := root+"/a.md", root+"/b.md", root+"/c.md"
+	idx := index.Index{Documents: map[string]*index.Document{
+		a: {Path: a, Links: []string{"b.md", "c", "missing.md", "image.png", "https://example.com"}},
+		b: {Path: b, Links: []string{"c.md", "b.md"}},
+		c: {Path: c},
+	}}
+
+	report := idx.LinkReport()
+
+	wantBroken := []index.BrokenLink{{a, "missing.md"}}
+	if !slices.Equal(report.Broken, wantBroken) {
+		t.Errorf("Broken = %v, want %v", report.Broken, wantBroken)
+	}
+	if want := []string{a}; !slices.Equal(report.Orphans, want) {
+		t.Errorf("Orphans = %v, want %v", report.Orphans, want)
+	}
+	wantLinked := []index.LinkCount{{c, 2}, {b, 1}}
+	if !slices.Equal(report.MostLinked, wantLinked) {
+		t.Errorf("MostLinked = %v, want %v", report.MostLinked, wantLinked)
+	}
+}
+
+func TestIndex_LinkReport_ZettelIDs(t *testing.T) {
+	root := t.TempDir()
+	a, b := root+"/202401121230 first.md", root+"/second.md"
+	idx := index.Index{Documents: map[string]*index.Document{
+		a: {Path: a, ID: "202401121230", Links: []string{"202401121231"}},
+		b: {Path: b, ID: "202401121231", Links: []string{"202401121230#heading", "202401121299"}},
+	}}
+
+	report := idx.LinkReport()
+
+	wantBroken := []index.BrokenLink{{b, "202401121299"}}
+	if !slices.Equal(report.Broken, wantBroken) {
+		t.Errorf("Broken = %v, want %v", report.Broken, wantBroken)
+	}
+	if len(report.Orphans) != 0 {
+		t.Errorf("Orphans = %v, want none", report.Orphans)
+	}
+	wantLinked := []index.LinkCount{{a, 1}, {b, 1}}
+	if !slices.Equal(report.MostLinked, wantLinked) {
+		t.Errorf("MostLinked = %v, want %v", report.MostLinked, wantLinked)
+	}
+}
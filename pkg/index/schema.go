@@ -0,0 +1,204 @@
+package index
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/jpappel/atlas/pkg/util"
+)
+
+// Value types a schema can require of a header key
+var SchemaTypes = []string{"string", "number", "bool", "date", "list", "map"}
+
+// Metadata conventions for document headers
+type Schema struct {
+	Required []string          `yaml:"required"` // keys every header must contain
+	Types    map[string]string `yaml:"types"`    // key to one of SchemaTypes
+	// Allowed values of tags and keywords, a tag also allows tags nested beneath it.
+	// Empty allows any tag.
+	Tags []string `yaml:"tags"`
+}
+
+// A header which does not conform to a schema
+type SchemaError struct {
+	Path string
+	Line int
+	Msg  string
+}
+
+func (e SchemaError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.Path, e.Line, e.Msg)
+}
+
+// Read a schema from a YAML file
+func LoadSchema(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := &Schema{}
+	if err := yaml.UnmarshalWithOptions(data, schema, yaml.DisallowUnknownField()); err != nil {
+		return nil, fmt.Errorf("Cannot parse schema: %w", err)
+	}
+	for key, typ := range schema.Types {
+		if !slices.Contains(SchemaTypes, typ) {
+			return nil, fmt.Errorf("Unrecognized type %s for %s, expected one of %s",
+				typ, key, strings.Join(SchemaTypes, ", "))
+		}
+	}
+	for _, tag := range schema.Tags {
+		if strings.TrimSpace(tag) == "" {
+			return nil, fmt.Errorf("Empty tag in schema")
+		}
+	}
+
+	return schema, nil
+}
+
+// Check the header of the document at path against the schema.
+// Lines are relative to the start of the file.
+func (s Schema) Validate(path string) ([]SchemaError, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	buf := &bytes.Buffer{}
+	if err := readYamlHeader(bufio.NewReader(f), buf); err != nil {
+		return nil, fmt.Errorf("%w in %s", err, path)
+	}
+	file, err := parser.ParseBytes(buf.Bytes(), 0)
+	if err != nil {
+		return nil, errors.Join(ErrHeaderParse, err)
+	}
+
+	var errs []SchemaError
+	report := func(node ast.Node, format string, args ...any) {
+		line := 1
+		if node != nil {
+			line = node.GetToken().Position.Line
+		}
+		errs = append(errs, SchemaError{path, line, fmt.Sprintf(format, args...)})
+	}
+
+	var values []*ast.MappingValueNode
+	if len(file.Docs) > 0 {
+		switch body := file.Docs[0].Body.(type) {
+		case *ast.MappingNode:
+			values = body.Values
+		case *ast.MappingValueNode:
+			values = []*ast.MappingValueNode{body}
+		case nil:
+		default:
+			report(body, "Header is not a mapping")
+			return errs, nil
+		}
+	}
+
+	keys := make(map[string]*ast.MappingValueNode, len(values))
+	for _, kv := range values {
+		keys[kv.Key.GetToken().Value] = kv
+	}
+
+	for _, key := range s.Required {
+		if _, ok := keys[key]; !ok {
+			report(nil, "Missing required key %s", key)
+		}
+	}
+
+	for _, kv := range values {
+		key := kv.Key.GetToken().Value
+		if typ, ok := s.Types[key]; ok && !schemaTypeMatches(typ, kv.Value) {
+			report(kv.Key, "Expected %s to be a %s", key, typ)
+		}
+		if len(s.Tags) > 0 && (key == "tags" || key == "keywords") {
+			for _, tag := range schemaTags(kv.Value) {
+				if !s.allowsTag(tag.value) {
+					report(tag.node, "Tag %s is not allowed", tag.value)
+				}
+			}
+		}
+	}
+
+	return errs, nil
+}
+
+func (s Schema) allowsTag(tag string) bool {
+	return slices.ContainsFunc(s.Tags, func(allowed string) bool {
+		allowed = strings.TrimSuffix(allowed, "/")
+		return tag == allowed || strings.HasPrefix(tag, allowed+"/")
+	})
+}
+
+func schemaTypeMatches(typ string, node ast.Node) bool {
+	switch typ {
+	case "string":
+		switch node.(type) {
+		case *ast.StringNode, *ast.LiteralNode:
+			return true
+		}
+	case "number":
+		switch node.(type) {
+		case *ast.IntegerNode, *ast.FloatNode, *ast.InfinityNode, *ast.NanNode:
+			return true
+		}
+	case "bool":
+		_, ok := node.(*ast.BoolNode)
+		return ok
+	case "date":
+		switch n := node.(type) {
+		case *ast.StringNode:
+			_, err := util.ParseDateTime(n.Value)
+			return err == nil
+		case *ast.IntegerNode:
+			// unix timestamp
+			return true
+		}
+	case "list":
+		_, ok := node.(*ast.SequenceNode)
+		return ok
+	case "map":
+		switch node.(type) {
+		case *ast.MappingNode, *ast.MappingValueNode:
+			return true
+		}
+	}
+	return false
+}
+
+type schemaTag struct {
+	node  ast.Node
+	value string
+}
+
+// Tags within a tags or keywords value and the nodes they were read from,
+// strings are split on commas like keywords
+func schemaTags(node ast.Node) []schemaTag {
+	var tags []schemaTag
+	nodes := []ast.Node{node}
+	if seq, ok := node.(*ast.SequenceNode); ok {
+		nodes = seq.Values
+	}
+	for _, n := range nodes {
+		strNode, ok := n.(*ast.StringNode)
+		if !ok {
+			continue
+		}
+		for tag := range strings.SplitSeq(strNode.Value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, schemaTag{n, tag})
+			}
+		}
+	}
+	return tags
+}
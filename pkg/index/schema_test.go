@@ -0,0 +1,106 @@
+package index_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/jpappel/atlas/pkg/index"
+)
+
+func TestLoadSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"valid", "required: [title]\ntypes:\n  date: date\ntags: [work, home]\n", false},
+		{"unknown type", "types:\n  date: timestamp\n", true},
+		{"unknown key", "requires: [title]\n", true},
+		{"empty tag", "tags: [work, '']\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "schema.yml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := index.LoadSchema(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadSchema() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSchema_Validate(t *testing.T) {
+	schema := index.Schema{
+		Required: []string{"title", "date"},
+		Types: map[string]string{
+			"title":    "string",
+			"date":     "date",
+			"draft":    "bool",
+			"priority": "number",
+			"tags":     "list",
+		},
+		Tags: []string{"work", "home"},
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    []index.SchemaError
+	}{
+		{
+			"conforming",
+			"---\ntitle: Notes\ndate: May 1, 2025\ndraft: false\npriority: 2\ntags: [work/atlas, home]\n---\n",
+			nil,
+		},
+		{
+			"missing keys",
+			"---\ntags: [work]\n---\n",
+			[]index.SchemaError{
+				{Line: 1, Msg: "Missing required key title"},
+				{Line: 1, Msg: "Missing required key date"},
+			},
+		},
+		{
+			"wrong types",
+			"---\ntitle: Notes\ndate: someday\ndraft: maybe\npriority: high\ntags: work\n---\n",
+			[]index.SchemaError{
+				{Line: 3, Msg: "Expected date to be a date"},
+				{Line: 4, Msg: "Expected draft to be a bool"},
+				{Line: 5, Msg: "Expected priority to be a number"},
+				{Line: 6, Msg: "Expected tags to be a list"},
+			},
+		},
+		{
+			"disallowed tags",
+			"---\ntitle: Notes\ndate: 2025-05-01\ntags:\n  - work\n  - workshop\nkeywords: home, garden\n---\n",
+			[]index.SchemaError{
+				{Line: 6, Msg: "Tag workshop is not allowed"},
+				{Line: 7, Msg: "Tag garden is not allowed"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "doc.md")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			for i := range tt.want {
+				tt.want[i].Path = path
+			}
+
+			got, err := schema.Validate(path)
+			if err != nil {
+				t.Fatal("Unexpected error:", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package index
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// Counts of files seen by each stage of Walk
+type WalkStats struct {
+	Crawled  int
+	Filtered int
+	Errors   uint64
+}
+
+// Crawl, filter, and parse documents in a single pass.
+//
+// The tree is crawled sequentially while numWorkers goroutines filter and
+// parse files as they are found, crawling waits once numWorkers files are
+// queued. Unlike Traverse, only one directory is read at a time, which can be
+// faster and use less memory on filesystems that handle concurrent reads
+// poorly. Paths are formed the same way as Traverse.
+func (idx Index) Walk(numWorkers uint, ignoreHidden bool, opts ParseOpts) (map[string]*Document, WalkStats, error) {
+	if numWorkers == 0 {
+		panic(fmt.Sprint("Invalid number of workers: ", numWorkers))
+	}
+
+	rootInfo, err := os.Stat(idx.Root)
+	if err != nil {
+		return nil, WalkStats{}, err
+	}
+	var rootEntries []os.DirEntry
+	if rootInfo.IsDir() {
+		if rootEntries, err = os.ReadDir(idx.Root); err != nil {
+			return nil, WalkStats{}, err
+		}
+	}
+
+	docs := make(map[string]*Document)
+	mu := &sync.Mutex{}
+	checked := &atomic.Int64{}
+	filtered := &atomic.Int64{}
+	parsed := &atomic.Int64{}
+	errCnt := &atomic.Uint64{}
+
+	// totals are unknown until the crawl finishes
+	paths := make(chan string, numWorkers)
+	wg := &sync.WaitGroup{}
+	wg.Add(int(numWorkers))
+	for range numWorkers {
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				passed := idx.FilterOne(path)
+				idx.report("filter", int(checked.Add(1)), 0, 0)
+				if !passed {
+					continue
+				}
+				filtered.Add(1)
+
+				doc, err := ParseDoc(path, opts)
+				if err != nil {
+					slog.Warn("Error occured while parsing file",
+						slog.String("path", path), slog.String("err", err.Error()),
+					)
+					idx.report("parse", int(parsed.Add(1)), 0, errCnt.Add(1))
+					continue
+				}
+
+				mu.Lock()
+				docs[doc.Path] = doc
+				mu.Unlock()
+				idx.report("parse", int(parsed.Add(1)), 0, errCnt.Load())
+			}
+		}()
+	}
+
+	crawled := 0
+	submit := func(path string) {
+		crawled++
+		idx.report("crawl", crawled, 0, 0)
+		paths <- path
+	}
+
+	var walk func(dir string, entries []os.DirEntry)
+	walk = func(dir string, entries []os.DirEntry) {
+		for _, entry := range entries {
+			if ignoreHidden && entry.Name()[0] == '.' {
+				continue
+			}
+
+			path := dir + "/" + entry.Name()
+			if entry.IsDir() {
+				subEntries, err := os.ReadDir(path)
+				if err != nil {
+					slog.Warn("Error occured while walking",
+						slog.String("path", path), slog.String("err", err.Error()),
+					)
+				}
+				// entries read before an error are still walked
+				walk(path, subEntries)
+			} else if entry.Type().IsRegular() {
+				submit(path)
+			}
+		}
+	}
+
+	if rootInfo.IsDir() {
+		walk(idx.Root, rootEntries)
+	} else if rootInfo.Mode().IsRegular() {
+		submit(idx.Root)
+	}
+	close(paths)
+	wg.Wait()
+
+	return docs, WalkStats{Crawled: crawled, Filtered: int(filtered.Load()), Errors: errCnt.Load()}, nil
+}
@@ -0,0 +1,314 @@
+package publish
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jpappel/atlas/pkg/index"
+)
+
+var ErrCollision = errors.New("Multiple pages publish to the same path")
+
+// Location of the site index, reserved along with everything under TagDir
+const (
+	IndexFile = "index.html"
+	TagDir    = "tags/"
+)
+
+// A published document
+type Document struct {
+	*index.Document
+	File    string // location of the page relative to the site root
+	URL     string // escaped link to File
+	Content string // document contents without the yaml header
+}
+
+// Data passed to the document.html and index.html templates
+type Page struct {
+	Title     string
+	Root      string     // relative path from the page to the site root, empty or ending in '/'
+	Document  *Document  // document of a document page
+	Documents []Document // documents listed on an index page
+	Tag       string     // tag of a tag index page
+	Tags      []string   // tags of every published document
+}
+
+// Documents and tag pages of a static site
+type Site struct {
+	Documents []Document            // ordered by descending date then title
+	Tagged    map[string][]Document // documents of each tag, ordered like Documents
+}
+
+const defaultDocumentTemplate = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Title}}</title></head>
+<body>
+<nav><a href="{{.Root}}index.html">Index</a></nav>
+<h1>{{.Title}}</h1>
+{{with .Document}}
+<p>{{if not .Date.IsZero}}{{.Date.Format "2006-01-02"}} {{end}}{{range $i, $a := .Authors}}{{if $i}}, {{end}}{{$a}}{{end}}</p>
+<p>{{range .Tags}}<a href="{{$.Root}}{{tagURL .}}">#{{.}}</a> {{end}}</p>
+<pre>{{.Content}}</pre>
+{{end}}
+</body>
+</html>
+`
+
+const defaultIndexTemplate = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Title}}</title></head>
+<body>
+<nav><a href="{{.Root}}index.html">Index</a></nav>
+<h1>{{.Title}}</h1>
+<ul>
+{{range .Documents}}<li><a href="{{$.Root}}{{.URL}}">{{if .Title}}{{.Title}}{{else}}{{.File}}{{end}}</a>{{if not .Date.IsZero}} {{.Date.Format "2006-01-02"}}{{end}}</li>
+{{end}}</ul>
+{{if not .Tag}}<h2>Tags</h2>
+<p>{{range .Tags}}<a href="{{$.Root}}{{tagURL .}}">#{{.}}</a> {{end}}</p>{{end}}
+</body>
+</html>
+`
+
+// Read the documents under root into a site.
+// Documents outside of root or which cannot be read are skipped, tags which
+// are not valid paths are dropped. Returns ErrCollision when two documents
+// publish to the same page or a document publishes to a reserved page.
+func NewSite(root string, docs map[string]*index.Document) (Site, error) {
+	site := Site{Tagged: make(map[string][]Document)}
+	owners := make(map[string]string, len(docs))
+	for _, docPath := range slices.Sorted(maps.Keys(docs)) {
+		doc := docs[docPath]
+		rel, err := filepath.Rel(root, doc.Path)
+		if err != nil || !filepath.IsLocal(rel) {
+			slog.Warn("Skipping document outside of index root", slog.String("path", doc.Path))
+			continue
+		}
+
+		content, err := os.ReadFile(doc.Path)
+		if err != nil {
+			slog.Warn("Cannot read document",
+				slog.String("path", doc.Path),
+				slog.String("err", err.Error()),
+			)
+			continue
+		}
+
+		file := filepath.ToSlash(rel)
+		file = strings.TrimSuffix(file, path.Ext(file)) + ".html"
+		if file == IndexFile || strings.HasPrefix(file, TagDir) {
+			return Site{}, fmt.Errorf("%w: %s publishes to %s which is reserved for index pages", ErrCollision, doc.Path, file)
+		} else if owner, ok := owners[file]; ok {
+			return Site{}, fmt.Errorf("%w: %s and %s both publish to %s", ErrCollision, owner, doc.Path, file)
+		}
+		owners[file] = doc.Path
+
+		pDoc := Document{
+			Document: doc,
+			File:     file,
+			URL:      escapePath(file),
+			Content:  string(DocumentBody(content)),
+		}
+		site.Documents = append(site.Documents, pDoc)
+		for _, tag := range doc.Tags {
+			if !filepath.IsLocal(filepath.FromSlash(TagFile(tag))) {
+				slog.Warn("Skipping tag which is not a valid path", slog.String("tag", tag))
+				continue
+			}
+			site.Tagged[tag] = append(site.Tagged[tag], pDoc)
+		}
+	}
+
+	docCmp, _ := index.NewDocCmp("date,title", true)
+	pDocCmp := func(a, b Document) int {
+		return docCmp(a.Document, b.Document)
+	}
+	slices.SortFunc(site.Documents, pDocCmp)
+	for _, tagDocs := range site.Tagged {
+		slices.SortFunc(tagDocs, pDocCmp)
+	}
+
+	return site, nil
+}
+
+// Render each document, the index, and tag index pages into outDir
+func (s Site) Write(tmpl *template.Template, outDir string) error {
+	tags := slices.Sorted(maps.Keys(s.Tagged))
+	for _, doc := range s.Documents {
+		title := doc.Title
+		if title == "" {
+			title = doc.File
+		}
+		page := Page{Title: title, Root: PageRoot(doc.File), Document: &doc, Tags: tags}
+		if err := renderPage(tmpl, "document.html", outDir, doc.File, page); err != nil {
+			return err
+		}
+	}
+
+	indexPage := Page{Title: "Index", Documents: s.Documents, Tags: tags}
+	if err := renderPage(tmpl, "index.html", outDir, IndexFile, indexPage); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		file := TagFile(tag)
+		page := Page{Title: "#" + tag, Root: PageRoot(file), Documents: s.Tagged[tag], Tag: tag, Tags: tags}
+		if err := renderPage(tmpl, "index.html", outDir, file, page); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Parse the *.html templates in dir, using the default for any of document.html
+// and index.html which are missing
+func ParseTemplates(dir string) (*template.Template, error) {
+	tmpl := template.New("").Funcs(template.FuncMap{"tagURL": TagURL})
+	if dir != "" {
+		matches, err := filepath.Glob(filepath.Join(dir, "*.html"))
+		if err != nil {
+			return nil, err
+		} else if len(matches) > 0 {
+			if tmpl, err = tmpl.ParseFiles(matches...); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	defaults := map[string]string{
+		"document.html": defaultDocumentTemplate,
+		"index.html":    defaultIndexTemplate,
+	}
+	for name, text := range defaults {
+		if tmpl.Lookup(name) != nil {
+			continue
+		}
+		if _, err := tmpl.New(name).Parse(text); err != nil {
+			return nil, err
+		}
+	}
+
+	return tmpl, nil
+}
+
+// Location of a tag's index page relative to the site root
+func TagFile(tag string) string {
+	return TagDir + tag + ".html"
+}
+
+// Escaped link to a tag's index page relative to the site root
+func TagURL(tag string) string {
+	return escapePath(TagFile(tag))
+}
+
+// Escape each segment of a slash separated path
+func escapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	escaped := strings.Join(segments, "/")
+	// a colon before the first slash would be read as a scheme
+	if strings.Contains(segments[0], ":") {
+		escaped = "./" + escaped
+	}
+	return escaped
+}
+
+// Relative path from a page to the site root
+func PageRoot(file string) string {
+	return strings.Repeat("../", strings.Count(file, "/"))
+}
+
+// Contents of a document after its yaml header
+func DocumentBody(content []byte) []byte {
+	pos := index.YamlHeaderPos(bytes.NewReader(content))
+	if pos < 0 {
+		return content
+	}
+	body := content[min(pos, int64(len(content))):]
+	// skip the rest of the closing line
+	if i := bytes.IndexByte(body, '\n'); i >= 0 {
+		return body[i+1:]
+	}
+	return nil
+}
+
+func renderPage(tmpl *template.Template, name string, outDir string, file string, page Page) error {
+	dest := filepath.Join(outDir, filepath.FromSlash(file))
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := tmpl.ExecuteTemplate(f, name, page); err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	return nil
+}
+
+// Copy files from the template directory which are not templates.
+// An output directory within the template directory is not copied, call
+// before Write so published pages are never read back as static files.
+func CopyStaticFiles(templateDir string, outDir string) error {
+	absOut, err := filepath.Abs(outDir)
+	if err != nil {
+		return err
+	}
+	return filepath.WalkDir(templateDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(templateDir, p)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if absPath, err := filepath.Abs(p); err != nil {
+				return err
+			} else if absPath == absOut {
+				return filepath.SkipDir
+			}
+			return nil
+		} else if !d.Type().IsRegular() {
+			return nil
+		} else if filepath.Dir(rel) == "." && filepath.Ext(rel) == ".html" {
+			return nil
+		}
+
+		dest := filepath.Join(outDir, rel)
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		src, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		dst, err := os.Create(dest)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(dst, src); err != nil {
+			dst.Close()
+			return err
+		}
+		return dst.Close()
+	})
+}
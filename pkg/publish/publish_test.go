@@ -0,0 +1,241 @@
+package publish_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jpappel/atlas/pkg/index"
+	"github.com/jpappel/atlas/pkg/publish"
+)
+
+func TestDocumentBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"no header", "# Title\nbody\n", "# Title\nbody\n"},
+		{"header", "---\ntitle: A\n---\n# Title\nbody\n", "# Title\nbody\n"},
+		{"dots terminator", "---\ntitle: A\n...\nbody\n", "body\n"},
+		{"header only", "---\ntitle: A\n---\n", ""},
+		{"unterminated header", "---\ntitle: A\n", "---\ntitle: A\n"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(publish.DocumentBody([]byte(tt.content)))
+			if got != tt.want {
+				t.Errorf("DocumentBody() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPageRoot(t *testing.T) {
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"index.html", ""},
+		{"notes/a.html", "../"},
+		{"tags/lang/go.html", "../../"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			if got := publish.PageRoot(tt.file); got != tt.want {
+				t.Errorf("PageRoot() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTagURL(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"go", "tags/go.html"},
+		{"c#", "tags/c%23.html"},
+		{"to do?", "tags/to%20do%3F.html"},
+		{"lang/go", "tags/lang/go.html"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			if got := publish.TagURL(tt.tag); got != tt.want {
+				t.Errorf("TagURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Write files at paths relative to root, returning their documents
+func writeDocs(t *testing.T, root string, paths ...string) map[string]*index.Document {
+	t.Helper()
+	docs := make(map[string]*index.Document, len(paths))
+	for i, rel := range paths {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("---\ntitle: "+rel+"\n---\nbody of "+rel+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		docs[p] = &index.Document{
+			Path:  p,
+			Title: rel,
+			Date:  time.Unix(int64(i), 0),
+			Tags:  []string{"c#"},
+		}
+	}
+	return docs
+}
+
+func TestNewSite_Collisions(t *testing.T) {
+	tests := []struct {
+		name    string
+		paths   []string
+		wantErr error
+	}{
+		{"distinct", []string{"a.md", "notes/a.md", "notes/b.markdown"}, nil},
+		{"same page", []string{"a.md", "a.markdown"}, publish.ErrCollision},
+		{"site index", []string{"index.md"}, publish.ErrCollision},
+		{"tag pages", []string{"tags/c#.md"}, publish.ErrCollision},
+		{"nested index", []string{"notes/index.md"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			docs := writeDocs(t, root, tt.paths...)
+
+			site, err := publish.NewSite(root, docs)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewSite() error = %v, want %v", err, tt.wantErr)
+			} else if err != nil {
+				return
+			}
+			if len(site.Documents) != len(tt.paths) {
+				t.Errorf("Got %d documents, want %d", len(site.Documents), len(tt.paths))
+			}
+		})
+	}
+}
+
+func TestSite_Write(t *testing.T) {
+	root := t.TempDir()
+	docs := writeDocs(t, root, "a.md", "notes/with space.md")
+	docs[filepath.Join(root, "outside.md")] = &index.Document{Path: filepath.Join(filepath.Dir(root), "outside.md")}
+
+	site, err := publish.NewSite(root, docs)
+	if err != nil {
+		t.Fatal("Unexpected error creating site:", err)
+	}
+	gotFiles := []string{}
+	for _, doc := range site.Documents {
+		gotFiles = append(gotFiles, doc.File)
+	}
+	if want := []string{"notes/with space.html", "a.html"}; !slices.Equal(gotFiles, want) {
+		t.Errorf("Got files %v, want %v", gotFiles, want)
+	}
+
+	tmpl, err := publish.ParseTemplates("")
+	if err != nil {
+		t.Fatal("Unexpected error parsing templates:", err)
+	}
+	out := t.TempDir()
+	if err := site.Write(tmpl, out); err != nil {
+		t.Fatal("Unexpected error writing site:", err)
+	}
+
+	read := func(file string) string {
+		t.Helper()
+		b, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(file)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	page := read("notes/with space.html")
+	if !strings.Contains(page, `href="../tags/c%23.html"`) {
+		t.Errorf("Document page does not link to its escaped tag page:\n%s", page)
+	} else if !strings.Contains(page, "body of notes/with space.md") || strings.Contains(page, "title:") {
+		t.Errorf("Document page does not contain only the document body:\n%s", page)
+	}
+
+	page = read("tags/c#.html")
+	for _, want := range []string{`href="../notes/with%20space.html"`, `href="../a.html"`} {
+		if !strings.Contains(page, want) {
+			t.Errorf("Tag page is missing %s:\n%s", want, page)
+		}
+	}
+	read("index.html")
+}
+
+func TestCopyStaticFiles(t *testing.T) {
+	root := t.TempDir()
+	docs := writeDocs(t, root, "a.md", "notes/b.md")
+	site, err := publish.NewSite(root, docs)
+	if err != nil {
+		t.Fatal("Unexpected error creating site:", err)
+	}
+
+	tmplDir := t.TempDir()
+	for file, content := range map[string]string{
+		"style.css":     "body {}",
+		"img/logo.svg":  "<svg/>",
+		"document.html": `{{.Title}}`,
+	} {
+		p := filepath.Join(tmplDir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tmpl, err := publish.ParseTemplates(tmplDir)
+	if err != nil {
+		t.Fatal("Unexpected error parsing templates:", err)
+	}
+
+	for _, out := range []string{t.TempDir(), filepath.Join(tmplDir, "site")} {
+		// publishing again reads the previous output
+		for range 2 {
+			if err := publish.CopyStaticFiles(tmplDir, out); err != nil {
+				t.Fatal("Unexpected error copying static files:", err)
+			}
+			if err := site.Write(tmpl, out); err != nil {
+				t.Fatal("Unexpected error writing site:", err)
+			}
+		}
+
+		for file, want := range map[string]string{
+			"style.css":    "body {}",
+			"img/logo.svg": "<svg/>",
+			"notes/b.html": "notes/b.md",
+		} {
+			got, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(file)))
+			if err != nil {
+				t.Fatal(err)
+			} else if string(got) != want {
+				t.Errorf("%s contains %q, want %q", file, got, want)
+			}
+		}
+		if info, err := os.Stat(filepath.Join(out, publish.TagFile("c#"))); err != nil {
+			t.Fatal(err)
+		} else if info.Size() == 0 {
+			t.Error("Tag page is empty")
+		}
+		if _, err := os.Stat(filepath.Join(out, "site")); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Output directory was copied into itself: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(out, "document.html")); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Template was copied as a static file: %v", err)
+		}
+	}
+}
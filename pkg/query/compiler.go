@@ -2,16 +2,23 @@ package query
 
 import (
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/jpappel/atlas/pkg/index"
 	"github.com/jpappel/atlas/pkg/util"
 )
 
 const MAX_CLAUSE_DEPTH int = 16
 
 type CompilationArtifact struct {
-	Query string
-	Args  []any
+	Query    string
+	Args     []any
+	SortBy   string // document field to order results by, empty for no ordering
+	SortDesc bool
+	Limit    int // maximum number of results, <= 0 for no limit
+	Offset   int // number of results to skip
 }
 
 func (art CompilationArtifact) String() string {
@@ -26,6 +33,15 @@ func (art CompilationArtifact) String() string {
 		}
 	}
 	b.WriteByte(']')
+	if art.SortBy != "" {
+		fmt.Fprintf(&b, "\nsort: %s", art.SortBy)
+		if art.SortDesc {
+			b.WriteString(" desc")
+		}
+	}
+	if art.Limit > 0 || art.Offset > 0 {
+		fmt.Fprintf(&b, "\nlimit: %d offset: %d", art.Limit, art.Offset)
+	}
 	return b.String()
 }
 
@@ -49,6 +65,16 @@ func (s Statements) buildCompile(b *strings.Builder, delim string) ([]any, error
 			catStr = "fileTime "
 		case CAT_LINKS:
 			catStr = "link "
+		case CAT_ID:
+			catStr = "zettelId "
+		case CAT_CITATIONS:
+			catStr = "citation "
+		case CAT_LANG:
+			catStr = "lang "
+		case CAT_PINNED:
+			catStr = "pinned "
+		case CAT_META_FIELD:
+			catStr = "docId "
 		case CAT_META:
 			catStr = "meta "
 		case CAT_TAGS:
@@ -72,6 +98,9 @@ func (s Statements) buildCompile(b *strings.Builder, delim string) ([]any, error
 			case OP_AP:
 				if cat.IsOrdered() {
 					opStr = "BETWEEN "
+				} else if cat == CAT_LANG {
+					// languages aren't full text indexed, match ignoring case instead
+					opStr = "LIKE "
 				} else {
 					opStr = "MATCH "
 				}
@@ -91,6 +120,8 @@ func (s Statements) buildCompile(b *strings.Builder, delim string) ([]any, error
 				opStr = "< "
 			case OP_RE:
 				opStr = "REGEXP "
+			case OP_FZ:
+				// compiled to a subquery of the full text index
 			case OP_NE:
 				if cat.IsSet() {
 					opStr = "NOT IN "
@@ -110,7 +141,20 @@ func (s Statements) buildCompile(b *strings.Builder, delim string) ([]any, error
 			// .isSet   !ap
 			// .isSet   ap
 			// any      any
-			if op == OP_RE {
+			if buildStmt := subqueryCompiler(cat, op); buildStmt != nil {
+				for i, stmt := range opStmts {
+					stmtArgs, err := buildStmt(stmt, b)
+					if err != nil {
+						return nil, err
+					}
+					args = append(args, stmtArgs...)
+					if i != len(opStmts)-1 {
+						b.WriteString(delim)
+						b.WriteByte(' ')
+					}
+					sCount++
+				}
+			} else if op == OP_RE {
 				idx := 0
 				for _, stmt := range opStmts {
 					b.WriteString("( ")
@@ -180,6 +224,9 @@ func (s Statements) buildCompile(b *strings.Builder, delim string) ([]any, error
 					}
 
 					start, end := util.FuzzDatetime(d.D)
+					if d.IsRange() {
+						start, end = d.D, d.End.Add(-time.Second)
+					}
 
 					if stmt.Negated {
 						b.WriteString("NOT ")
@@ -203,6 +250,21 @@ func (s Statements) buildCompile(b *strings.Builder, delim string) ([]any, error
 					b.WriteString("IS NOT NULL AND ")
 				}
 				for _, stmt := range opStmts {
+					if d, ok := stmt.Value.(DatetimeValue); ok && d.IsRange() && (op == OP_EQ || op == OP_NE) {
+						// periods match any time within them
+						if stmt.Negated != (op == OP_NE) {
+							b.WriteString("NOT ")
+						}
+						fmt.Fprintf(b, "( %s>= %d AND %s< %d ) ", catStr, d.D.Unix(), catStr, d.End.Unix())
+						if idx != len(opStmts)-1 {
+							b.WriteString(delim)
+							b.WriteByte(' ')
+						}
+						idx++
+						sCount++
+						continue
+					}
+
 					if stmt.Negated {
 						// FIXME: doesn't evaluate correctly for when using MATCH operator in SQL
 						//        a potential fix for negated statements is using an EXCEPT-like subquery
@@ -237,6 +299,204 @@ func (s Statements) buildCompile(b *strings.Builder, delim string) ([]any, error
 	return args, nil
 }
 
+// Compile a statement on a typed metadata field to a subquery of the Meta table.
+// Negated statements match documents without a matching field.
+func (stmt Statement) buildMetaCompile(b *strings.Builder) ([]any, error) {
+	v, ok := stmt.Value.(MetaValue)
+	if !ok {
+		return nil, &CompileError{fmt.Sprintf("expected a metadata value, got %#v", stmt.Value)}
+	}
+
+	negated := stmt.Negated
+	var opStr string
+	switch stmt.Operator {
+	case OP_EQ:
+		opStr = "= "
+	case OP_NE:
+		opStr = "= "
+		negated = !negated
+	case OP_AP:
+		opStr = "LIKE "
+		v.V = "%" + fmt.Sprint(v.V) + "%"
+	case OP_RE:
+		opStr = "REGEXP "
+	case OP_LT:
+		opStr = "< "
+	case OP_LE:
+		opStr = "<= "
+	case OP_GE:
+		opStr = ">= "
+	case OP_GT:
+		opStr = "> "
+	default:
+		return nil, &CompileError{
+			fmt.Sprintf("unsupported operator for metadata fields %s", stmt.Operator),
+		}
+	}
+
+	valueType := "string"
+	switch v.V.(type) {
+	case bool:
+		valueType = "bool"
+	case float64:
+		valueType = "number"
+	}
+	if stmt.Operator.IsOrder() && valueType != "number" {
+		return nil, &CompileError{
+			fmt.Sprintf("ordered comparisons of metadata fields require a number, got %v", v.V),
+		}
+	}
+
+	if negated {
+		b.WriteString("docId NOT IN ")
+	} else {
+		b.WriteString("docId IN ")
+	}
+	fmt.Fprintf(b, "( SELECT docId FROM Meta WHERE key = ? AND type = '%s' AND value ", valueType)
+	b.WriteString(opStr)
+	args := []any{v.Key}
+	if arg, ok := v.buildCompile(b); ok {
+		args = append(args, arg)
+	}
+	b.WriteString(" ) ")
+
+	return args, nil
+}
+
+// Get the compiler for statements matched with a subquery rather than the
+// Search view, nil if there is none
+func subqueryCompiler(cat catType, op opType) func(Statement, *strings.Builder) ([]any, error) {
+	switch {
+	case cat == CAT_META_FIELD:
+		return Statement.buildMetaCompile
+	case op == OP_FZ:
+		return Statement.buildFuzzyCompile
+	case cat == CAT_TAGS && (op == OP_EQ || op == OP_NE):
+		return Statement.buildTagCompile
+	case cat == CAT_AUTHOR && (op == OP_EQ || op == OP_NE):
+		return Statement.buildAuthorCompile
+	default:
+		return nil
+	}
+}
+
+// Compile an author equality to a subquery matching either the author's name
+// or their email, ignoring the case of emails.
+// Values of the form `Name <email>` match by email.
+func (stmt Statement) buildAuthorCompile(b *strings.Builder) ([]any, error) {
+	v, ok := stmt.Value.(StringValue)
+	if !ok {
+		return nil, &CompileError{fmt.Sprintf("expected a string value, got %#v", stmt.Value)}
+	}
+
+	negated := stmt.Negated
+	if stmt.Operator == OP_NE {
+		negated = !negated
+	}
+
+	if negated {
+		b.WriteString("docId NOT IN ")
+	} else {
+		b.WriteString("docId IN ")
+	}
+	b.WriteString("( SELECT da.docId FROM DocumentAuthors da JOIN Authors au ON da.authorId = au.id ")
+	b.WriteString("WHERE au.author = ? OR au.email = ? COLLATE NOCASE ) ")
+
+	_, email := index.SplitAuthorEmail(v.S)
+	if email == "" {
+		email = v.S
+	}
+	return []any{v.S, email}, nil
+}
+
+// Compile a tag equality to a subquery matching the tag and its descendants,
+// ie work matches work and work/atlas but not workshop.
+// Descendants are found with a range over the unique index on Tags.tag.
+func (stmt Statement) buildTagCompile(b *strings.Builder) ([]any, error) {
+	v, ok := stmt.Value.(StringValue)
+	if !ok {
+		return nil, &CompileError{fmt.Sprintf("expected a string value, got %#v", stmt.Value)}
+	}
+
+	negated := stmt.Negated
+	if stmt.Operator == OP_NE {
+		negated = !negated
+	}
+
+	if negated {
+		b.WriteString("docId NOT IN ")
+	} else {
+		b.WriteString("docId IN ")
+	}
+	b.WriteString("( SELECT dt.docId FROM DocumentTags dt JOIN Tags t ON dt.tagId = t.id ")
+	b.WriteString("WHERE t.tag = ? OR ( t.tag >= ? AND t.tag < ? ) ) ")
+
+	// '0' is the character after '/'
+	tag := strings.TrimRight(v.S, "/")
+	return []any{tag, tag + "/", tag + "0"}, nil
+}
+
+// Full text index, column, and document id of the categories OP_FZ can match
+var fuzzySources = map[catType]struct {
+	table, column, docId, join string
+}{
+	CAT_PATH:      {"Documents_fts", "path", "f.rowid", ""},
+	CAT_TITLE:     {"Documents_fts", "title", "f.rowid", ""},
+	CAT_HEADINGS:  {"Documents_fts", "headings", "f.rowid", ""},
+	CAT_META:      {"Documents_fts", "meta", "f.rowid", ""},
+	CAT_ID:        {"Documents_fts", "zettelId", "f.rowid", ""},
+	CAT_AUTHOR:    {"Authors_fts", "author", "da.docId", "JOIN DocumentAuthors da ON da.authorId = f.rowid "},
+	CAT_TAGS:      {"Tags_fts", "tag", "dt.docId", "JOIN DocumentTags dt ON dt.tagId = f.rowid "},
+	CAT_LINKS:     {"Links_fts", "link", "f.docId", ""},
+	CAT_CITATIONS: {"Citations_fts", "citation", "f.docId", ""},
+}
+
+// Compile a typo tolerant match to a subquery of the category's full text index.
+// Candidates sharing a trigram with the value are checked with the fuzzy function,
+// unless fuzzy_scan reports the value is too short for trigrams to find every match.
+// Sets compare whole values, other categories match within their text.
+func (stmt Statement) buildFuzzyCompile(b *strings.Builder) ([]any, error) {
+	v, ok := stmt.Value.(StringValue)
+	if !ok {
+		return nil, &CompileError{fmt.Sprintf("expected a string value, got %#v", stmt.Value)}
+	}
+	src, ok := fuzzySources[stmt.Category]
+	if !ok {
+		return nil, &CompileError{
+			fmt.Sprintf("unsupported category for typo tolerant matching %s", stmt.Category),
+		}
+	}
+
+	if stmt.Negated {
+		b.WriteString("docId NOT IN ")
+	} else {
+		b.WriteString("docId IN ")
+	}
+	fmt.Fprintf(b, "( SELECT %s FROM %s f %s", src.docId, src.table, src.join)
+	fmt.Fprintf(b, "WHERE ( fuzzy_scan(?) OR f.rowid IN ( SELECT rowid FROM %s WHERE %s MATCH ? ) ) ", src.table, src.column)
+	fmt.Fprintf(b, "AND fuzzy(f.%s, ?, %t) ) ", src.column, !stmt.Category.IsSet())
+
+	return []any{v.S, trigramQuery(v.S), v.S}, nil
+}
+
+// Create a full text search query matching any trigram of s
+func trigramQuery(s string) string {
+	runes := []rune(s)
+	if len(runes) < 3 {
+		// an empty phrase matches nothing
+		return `""`
+	}
+
+	trigrams := make([]string, 0, len(runes)-2)
+	for i := range len(runes) - 2 {
+		trigram := `"` + strings.ReplaceAll(string(runes[i:i+3]), `"`, `""`) + `"`
+		if !slices.Contains(trigrams, trigram) {
+			trigrams = append(trigrams, trigram)
+		}
+	}
+	return strings.Join(trigrams, " OR ")
+}
+
 func (root Clause) Compile() (CompilationArtifact, error) {
 	if d := root.Depth(); d > MAX_CLAUSE_DEPTH {
 		return CompilationArtifact{}, &CompileError{
@@ -251,7 +511,7 @@ func (root Clause) Compile() (CompilationArtifact, error) {
 	} else if b.Len() == 0 {
 		return CompilationArtifact{}, fmt.Errorf("Empty query")
 	}
-	return CompilationArtifact{b.String(), args}, nil
+	return CompilationArtifact{Query: b.String(), Args: args}, nil
 }
 
 func (c Clause) buildCompile(b *strings.Builder) ([]any, error) {
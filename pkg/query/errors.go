@@ -11,6 +11,7 @@ var ErrDatetimeTokenParse = errors.New("Unrecognized format for datetime")
 // output errors
 var ErrUnrecognizedOutputToken = errors.New("Unrecognized output token")
 var ErrExpectedMoreStringTokens = errors.New("Expected more string tokens")
+var ErrSelectSyntax = errors.New("Invalid select expression")
 
 // optimizer errors
 var ErrUnexpectedValueType = errors.New("Unexpected value type")
@@ -2,8 +2,10 @@ package query
 
 import (
 	"fmt"
+	"math"
 	"regexp"
 	"slices"
+	"strconv"
 	"strings"
 )
 
@@ -20,6 +22,7 @@ const (
 	TOK_CLAUSE_AND // clause and
 	TOK_CLAUSE_START
 	TOK_CLAUSE_END
+	TOK_SAVED // reference to a saved query, value holds its name
 
 	// statement tokens
 	TOK_OP_NEG // negation
@@ -31,6 +34,7 @@ const (
 	TOK_OP_GE  // greater than or equal
 	TOK_OP_GT  // greater than
 	TOK_OP_RE  // regex match
+	TOK_OP_FZ  // typo tolerant match
 	// categories
 	TOK_CAT_PATH
 	TOK_CAT_TITLE
@@ -40,10 +44,16 @@ const (
 	TOK_CAT_TAGS
 	TOK_CAT_HEADINGS
 	TOK_CAT_LINKS
+	TOK_CAT_ID
+	TOK_CAT_CITATIONS
+	TOK_CAT_LANG
+	TOK_CAT_PINNED
+	TOK_CAT_META_FIELD // typed metadata key, value holds the category with its key
 	TOK_CAT_META
 	// values
 	TOK_VAL_STR
 	TOK_VAL_DATETIME
+	TOK_VAL_NUMBER
 )
 
 type Token struct {
@@ -71,6 +81,8 @@ func (tokType queryTokenType) String() string {
 		return "Approximate"
 	case TOK_OP_RE:
 		return "Regular Expression"
+	case TOK_OP_FZ:
+		return "Typo Tolerant"
 	case TOK_OP_NE:
 		return "Not Equal"
 	case TOK_OP_LT:
@@ -97,12 +109,26 @@ func (tokType queryTokenType) String() string {
 		return "Headings Category"
 	case TOK_CAT_LINKS:
 		return "Links Category"
+	case TOK_CAT_ID:
+		return "Zettel ID Category"
+	case TOK_CAT_CITATIONS:
+		return "Citations Category"
+	case TOK_CAT_LANG:
+		return "Language Category"
+	case TOK_CAT_PINNED:
+		return "Pinned Category"
+	case TOK_CAT_META_FIELD:
+		return "Metadata Field Category"
 	case TOK_CAT_META:
 		return "Metadata Category"
+	case TOK_SAVED:
+		return "Saved Query"
 	case TOK_VAL_DATETIME:
 		return "Datetime Value"
 	case TOK_VAL_STR:
 		return "String Value"
+	case TOK_VAL_NUMBER:
+		return "Number Value"
 	default:
 		return "Invalid"
 	}
@@ -113,7 +139,7 @@ func (t Token) String() string {
 }
 
 func (t Token) Equal(other Token) bool {
-	if t.Type.isValue() {
+	if t.Type.isValue() || t.Type == TOK_SAVED {
 		return t.Type == other.Type && t.Value == other.Value
 	}
 	return t.Type == other.Type
@@ -127,7 +153,7 @@ func (tokType queryTokenType) Any(expected ...queryTokenType) bool {
 func (t queryTokenType) isCategory() bool {
 	return t.Any(TOK_CAT_PATH, TOK_CAT_TITLE, TOK_CAT_AUTHOR,
 		TOK_CAT_DATE, TOK_CAT_FILETIME, TOK_CAT_TAGS, TOK_CAT_HEADINGS, TOK_CAT_LINKS,
-		TOK_CAT_META)
+		TOK_CAT_ID, TOK_CAT_CITATIONS, TOK_CAT_LANG, TOK_CAT_PINNED, TOK_CAT_META_FIELD, TOK_CAT_META)
 }
 
 func (t queryTokenType) isOrdered() bool {
@@ -139,11 +165,15 @@ func (t queryTokenType) isDateOperation() bool {
 }
 
 func (t queryTokenType) isStringOperation() bool {
-	return t.Any(TOK_OP_EQ, TOK_OP_AP, TOK_OP_NE, TOK_OP_RE)
+	return t.Any(TOK_OP_EQ, TOK_OP_AP, TOK_OP_NE, TOK_OP_RE, TOK_OP_FZ)
+}
+
+func (t queryTokenType) isNumberOperation() bool {
+	return t.Any(TOK_OP_EQ, TOK_OP_AP, TOK_OP_NE, TOK_OP_LT, TOK_OP_LE, TOK_OP_GE, TOK_OP_GT, TOK_OP_RE)
 }
 
 func (t queryTokenType) isValue() bool {
-	return t == TOK_VAL_STR || t == TOK_VAL_DATETIME
+	return t == TOK_VAL_STR || t == TOK_VAL_DATETIME || t == TOK_VAL_NUMBER
 }
 
 func Lex(query string) []Token {
@@ -156,6 +186,7 @@ func Lex(query string) []Token {
 		CATEGORY
 		OPERATOR
 		VALUE
+		SAVED
 		UNKNOWN
 		CLAUSE_END
 	)
@@ -167,9 +198,16 @@ func Lex(query string) []Token {
 	tokens = append(tokens, Token{TOK_CLAUSE_AND, "and"}) // default to and'ing all args
 	clauseLevel := 1
 	for _, match := range matches {
-		if match[CLAUSE_START] != "" {
+		if clauseStart := match[CLAUSE_START]; clauseStart != "" {
 			tokens = append(tokens, Token{Type: TOK_CLAUSE_START})
 			clauseLevel += 1
+			// set operations on saved queries, ie union(@a,@b)
+			switch strings.ToLower(clauseStart) {
+			case "union(":
+				tokens = append(tokens, Token{TOK_CLAUSE_OR, "or"})
+			case "intersect(":
+				tokens = append(tokens, Token{TOK_CLAUSE_AND, "and"})
+			}
 		}
 		if match[CLAUSE_OPERATOR] != "" {
 			if len(tokens) == 0 || tokens[len(tokens)-1].Type != TOK_CLAUSE_START {
@@ -193,6 +231,10 @@ func Lex(query string) []Token {
 			tokens = append(tokens, tokenizeValue(match[VALUE], tokens[len(tokens)-2].Type))
 		}
 
+		if match[SAVED] != "" {
+			tokens = append(tokens, Token{TOK_SAVED, match[SAVED]})
+		}
+
 		if match[UNKNOWN] != "" {
 			tokens = append(tokens, Token{Value: match[UNKNOWN]})
 		}
@@ -249,6 +291,8 @@ func tokenizeOperation(s string) Token {
 		t.Type = TOK_OP_GT
 	case "/":
 		t.Type = TOK_OP_RE
+	case "?=":
+		t.Type = TOK_OP_FZ
 	}
 
 	return t
@@ -256,6 +300,11 @@ func tokenizeOperation(s string) Token {
 
 func tokenizeCategory(s string) Token {
 	t := Token{Value: s}
+	if _, ok := metaFieldKey(s); ok {
+		t.Type = TOK_CAT_META_FIELD
+		return t
+	}
+
 	switch s {
 	case "p", "path":
 		t.Type = TOK_CAT_PATH
@@ -273,15 +322,30 @@ func tokenizeCategory(s string) Token {
 		t.Type = TOK_CAT_HEADINGS
 	case "l", "links":
 		t.Type = TOK_CAT_LINKS
+	case "i", "id":
+		t.Type = TOK_CAT_ID
+	case "c", "cite":
+		t.Type = TOK_CAT_CITATIONS
+	case "lang":
+		t.Type = TOK_CAT_LANG
+	case "pinned":
+		t.Type = TOK_CAT_PINNED
 	case "m", "meta":
 		t.Type = TOK_CAT_META
 	}
 	return t
 }
 
+// Key of a metadata field category, ie project.name from m.project.name
+func metaFieldKey(category string) (string, bool) {
+	prefix, key, ok := strings.Cut(category, ".")
+	return key, ok && (prefix == "m" || prefix == "meta")
+}
+
 func tokenizeValue(s string, catType queryTokenType) Token {
 	t := Token{}
-	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+	quoted := len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"'
+	if quoted {
 		t.Value = s[1 : len(s)-1]
 	} else {
 		t.Value = s
@@ -289,12 +353,24 @@ func tokenizeValue(s string, catType queryTokenType) Token {
 	switch catType {
 	case TOK_CAT_DATE, TOK_CAT_FILETIME:
 		t.Type = TOK_VAL_DATETIME
-	case TOK_CAT_PATH, TOK_CAT_TITLE, TOK_CAT_AUTHOR, TOK_CAT_TAGS, TOK_CAT_HEADINGS, TOK_CAT_LINKS, TOK_CAT_META:
+	case TOK_CAT_META_FIELD:
+		// quote numbers to compare them as strings
+		if !quoted && isNumber(t.Value) {
+			t.Type = TOK_VAL_NUMBER
+		} else {
+			t.Type = TOK_VAL_STR
+		}
+	case TOK_CAT_PATH, TOK_CAT_TITLE, TOK_CAT_AUTHOR, TOK_CAT_TAGS, TOK_CAT_HEADINGS, TOK_CAT_LINKS, TOK_CAT_ID, TOK_CAT_CITATIONS, TOK_CAT_LANG, TOK_CAT_PINNED, TOK_CAT_META:
 		t.Type = TOK_VAL_STR
 	}
 	return t
 }
 
+func isNumber(s string) bool {
+	n, err := strconv.ParseFloat(s, 64)
+	return err == nil && !math.IsInf(n, 0) && !math.IsNaN(n)
+}
+
 func TokensStringify(tokens []Token) string {
 	b := strings.Builder{}
 
@@ -320,12 +396,16 @@ func TokensStringify(tokens []Token) string {
 		case TOK_CLAUSE_AND:
 			b.WriteString("and\n")
 			indentLvl += 1
-		case TOK_CAT_PATH, TOK_CAT_TITLE, TOK_CAT_AUTHOR, TOK_CAT_DATE, TOK_CAT_FILETIME, TOK_CAT_HEADINGS, TOK_CAT_TAGS, TOK_CAT_LINKS, TOK_CAT_META, TOK_OP_NEG:
+		case TOK_CAT_PATH, TOK_CAT_TITLE, TOK_CAT_AUTHOR, TOK_CAT_DATE, TOK_CAT_FILETIME, TOK_CAT_HEADINGS, TOK_CAT_TAGS, TOK_CAT_LINKS, TOK_CAT_ID, TOK_CAT_CITATIONS, TOK_CAT_LANG, TOK_CAT_PINNED, TOK_CAT_META_FIELD, TOK_CAT_META, TOK_OP_NEG:
 			if i == 0 || tokens[i-1].Type != TOK_OP_NEG {
 				writeIndent(&b, indentLvl)
 			}
 			writeToken(token)
-		case TOK_VAL_STR, TOK_VAL_DATETIME, TOK_UNKNOWN:
+		case TOK_SAVED:
+			writeIndent(&b, indentLvl)
+			writeToken(token)
+			b.WriteByte('\n')
+		case TOK_VAL_STR, TOK_VAL_DATETIME, TOK_VAL_NUMBER, TOK_UNKNOWN:
 			writeToken(token)
 			b.WriteByte('\n')
 		default:
@@ -338,16 +418,17 @@ func TokensStringify(tokens []Token) string {
 
 func init() {
 	negPattern := `(?<negation>-?)`
-	categoryPattern := `(?<category>T|p(?:ath)?|a(?:uthor)?|d(?:ate)?|f(?:iletime)?|t(?:ags|itle)?|h(?:eadings)?|l(?:inks)?|m(?:eta)?)`
-	opPattern := `(?<operator>!=|<=|>=|=|:|/|~|<|>)`
+	categoryPattern := `(?<category>T|pinned|p(?:ath)?|a(?:uthor)?|d(?:ate)?|f(?:iletime)?|t(?:ags|itle)?|h(?:eadings)?|lang|l(?:inks)?|i(?:d)?|c(?:ite)?|m(?:eta)?(?:\.[\w-]+)*)`
+	opPattern := `(?<operator>!=|<=|>=|\?=|=|:|/|~|<|>)`
 	valPattern := `(?<value>".*?"|\S*[^\s\)])`
 	statementPattern := `(?<statement>` + negPattern + categoryPattern + opPattern + valPattern + `)`
+	savedPattern := `@(?<saved>[\w-]+),?`
 	unknownPattern := `(?<unknown>\S*".*?"[^\s)]*|\S*[^\s\)])`
 
 	clauseOpPattern := `(?<clause_operator>(?i)and|or)?`
-	clauseStart := `(?<clause_start>\()?`
+	clauseStart := `(?<clause_start>\(|(?i:union|intersect)\()?`
 	clauseEnd := `(?<clause_end>\))?`
-	clausePattern := clauseStart + `\s*` + clauseOpPattern + `\s*(?:` + statementPattern + `|` + unknownPattern + `)\s*` + clauseEnd + `\s*`
+	clausePattern := clauseStart + `\s*` + clauseOpPattern + `\s*(?:` + statementPattern + `|` + savedPattern + `|` + unknownPattern + `)\s*` + clauseEnd + `\s*`
 	LexRegexPattern = clausePattern
 
 	// FIXME: fails to match start of clauses with no values
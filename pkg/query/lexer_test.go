@@ -9,30 +9,36 @@ import (
 type Token = query.Token
 
 const (
-	TOK_UNKNOWN      = query.TOK_UNKNOWN
-	TOK_CLAUSE_OR    = query.TOK_CLAUSE_OR
-	TOK_CLAUSE_AND   = query.TOK_CLAUSE_AND
-	TOK_CLAUSE_START = query.TOK_CLAUSE_START
-	TOK_CLAUSE_END   = query.TOK_CLAUSE_END
-	TOK_OP_NEG       = query.TOK_OP_NEG
-	TOK_OP_EQ        = query.TOK_OP_EQ
-	TOK_OP_AP        = query.TOK_OP_AP
-	TOK_OP_NE        = query.TOK_OP_NE
-	TOK_OP_LT        = query.TOK_OP_LT
-	TOK_OP_LE        = query.TOK_OP_LE
-	TOK_OP_GE        = query.TOK_OP_GE
-	TOK_OP_GT        = query.TOK_OP_GT
-	TOK_OP_RE        = query.TOK_OP_RE
-	TOK_CAT_TITLE    = query.TOK_CAT_TITLE
-	TOK_CAT_AUTHOR   = query.TOK_CAT_AUTHOR
-	TOK_CAT_DATE     = query.TOK_CAT_DATE
-	TOK_CAT_FILETIME = query.TOK_CAT_FILETIME
-	TOK_CAT_TAGS     = query.TOK_CAT_TAGS
-	TOK_CAT_HEADINGS = query.TOK_CAT_HEADINGS
-	TOK_CAT_LINKS    = query.TOK_CAT_LINKS
-	TOK_CAT_META     = query.TOK_CAT_META
-	TOK_VAL_STR      = query.TOK_VAL_STR
-	TOK_VAL_DATETIME = query.TOK_VAL_DATETIME
+	TOK_UNKNOWN       = query.TOK_UNKNOWN
+	TOK_CLAUSE_OR     = query.TOK_CLAUSE_OR
+	TOK_CLAUSE_AND    = query.TOK_CLAUSE_AND
+	TOK_CLAUSE_START  = query.TOK_CLAUSE_START
+	TOK_CLAUSE_END    = query.TOK_CLAUSE_END
+	TOK_SAVED         = query.TOK_SAVED
+	TOK_OP_NEG        = query.TOK_OP_NEG
+	TOK_OP_EQ         = query.TOK_OP_EQ
+	TOK_OP_AP         = query.TOK_OP_AP
+	TOK_OP_NE         = query.TOK_OP_NE
+	TOK_OP_LT         = query.TOK_OP_LT
+	TOK_OP_LE         = query.TOK_OP_LE
+	TOK_OP_GE         = query.TOK_OP_GE
+	TOK_OP_GT         = query.TOK_OP_GT
+	TOK_OP_RE         = query.TOK_OP_RE
+	TOK_OP_FZ         = query.TOK_OP_FZ
+	TOK_CAT_TITLE     = query.TOK_CAT_TITLE
+	TOK_CAT_AUTHOR    = query.TOK_CAT_AUTHOR
+	TOK_CAT_DATE      = query.TOK_CAT_DATE
+	TOK_CAT_FILETIME  = query.TOK_CAT_FILETIME
+	TOK_CAT_TAGS      = query.TOK_CAT_TAGS
+	TOK_CAT_HEADINGS  = query.TOK_CAT_HEADINGS
+	TOK_CAT_LINKS     = query.TOK_CAT_LINKS
+	TOK_CAT_ID        = query.TOK_CAT_ID
+	TOK_CAT_CITATIONS = query.TOK_CAT_CITATIONS
+	TOK_CAT_LANG      = query.TOK_CAT_LANG
+	TOK_CAT_PINNED    = query.TOK_CAT_PINNED
+	TOK_CAT_META      = query.TOK_CAT_META
+	TOK_VAL_STR       = query.TOK_VAL_STR
+	TOK_VAL_DATETIME  = query.TOK_VAL_DATETIME
 )
 
 func TestLex(t *testing.T) {
@@ -52,6 +58,57 @@ func TestLex(t *testing.T) {
 			{TOK_UNKNOWN, "foo:bar"},
 			{Type: TOK_CLAUSE_END},
 		}},
+		{"zettel id", "id:202401121230 -i=202401121231", []Token{
+			{Type: TOK_CLAUSE_START}, {TOK_CLAUSE_AND, "and"},
+			{TOK_CAT_ID, "id"}, {TOK_OP_AP, ":"}, {TOK_VAL_STR, "202401121230"},
+			{TOK_OP_NEG, "-"}, {TOK_CAT_ID, "i"}, {TOK_OP_EQ, "="}, {TOK_VAL_STR, "202401121231"},
+			{Type: TOK_CLAUSE_END},
+		}},
+		{"citations", "cite=knuth1974 c:pike", []Token{
+			{Type: TOK_CLAUSE_START}, {TOK_CLAUSE_AND, "and"},
+			{TOK_CAT_CITATIONS, "cite"}, {TOK_OP_EQ, "="}, {TOK_VAL_STR, "knuth1974"},
+			{TOK_CAT_CITATIONS, "c"}, {TOK_OP_AP, ":"}, {TOK_VAL_STR, "pike"},
+			{Type: TOK_CLAUSE_END},
+		}},
+		{"language", "lang=de l:example.com", []Token{
+			{Type: TOK_CLAUSE_START}, {TOK_CLAUSE_AND, "and"},
+			{TOK_CAT_LANG, "lang"}, {TOK_OP_EQ, "="}, {TOK_VAL_STR, "de"},
+			{TOK_CAT_LINKS, "l"}, {TOK_OP_AP, ":"}, {TOK_VAL_STR, "example.com"},
+			{Type: TOK_CLAUSE_END},
+		}},
+		{"saved query", "@inbox -t=done", []Token{
+			{Type: TOK_CLAUSE_START}, {TOK_CLAUSE_AND, "and"},
+			{TOK_SAVED, "inbox"},
+			{Type: TOK_OP_NEG}, {TOK_CAT_TAGS, "t"}, {TOK_OP_EQ, "="}, {TOK_VAL_STR, "done"},
+			{Type: TOK_CLAUSE_END},
+		}},
+		{"saved query union", "union(@inbox,@reading) T:go", []Token{
+			{Type: TOK_CLAUSE_START}, {TOK_CLAUSE_AND, "and"},
+			{Type: TOK_CLAUSE_START}, {TOK_CLAUSE_OR, "or"},
+			{TOK_SAVED, "inbox"}, {TOK_SAVED, "reading"},
+			{Type: TOK_CLAUSE_END},
+			{TOK_CAT_TITLE, "T"}, {TOK_OP_AP, ":"}, {TOK_VAL_STR, "go"},
+			{Type: TOK_CLAUSE_END},
+		}},
+		{"saved query intersect", "intersect(@inbox, @reading)", []Token{
+			{Type: TOK_CLAUSE_START}, {TOK_CLAUSE_AND, "and"},
+			{Type: TOK_CLAUSE_START}, {TOK_CLAUSE_AND, "and"},
+			{TOK_SAVED, "inbox"}, {TOK_SAVED, "reading"},
+			{Type: TOK_CLAUSE_END},
+			{Type: TOK_CLAUSE_END},
+		}},
+		{"pinned", "pinned=true T:notes", []Token{
+			{Type: TOK_CLAUSE_START}, {TOK_CLAUSE_AND, "and"},
+			{TOK_CAT_PINNED, "pinned"}, {TOK_OP_EQ, "="}, {TOK_VAL_STR, "true"},
+			{TOK_CAT_TITLE, "T"}, {TOK_OP_AP, ":"}, {TOK_VAL_STR, "notes"},
+			{Type: TOK_CLAUSE_END},
+		}},
+		{"typo tolerant", `a?="jon smth" -t?=wrok`, []Token{
+			{Type: TOK_CLAUSE_START}, {TOK_CLAUSE_AND, "and"},
+			{TOK_CAT_AUTHOR, "a"}, {TOK_OP_FZ, "?="}, {TOK_VAL_STR, "jon smth"},
+			{TOK_OP_NEG, "-"}, {TOK_CAT_TAGS, "t"}, {TOK_OP_FZ, "?="}, {TOK_VAL_STR, "wrok"},
+			{Type: TOK_CLAUSE_END},
+		}},
 		{"simple query", "a:a t:b d:01010001", []Token{
 			{Type: TOK_CLAUSE_START}, {TOK_CLAUSE_AND, "and"},
 			{TOK_CAT_AUTHOR, "a"}, {TOK_OP_AP, ":"}, {TOK_VAL_STR, "a"},
@@ -133,3 +190,14 @@ func TestLex(t *testing.T) {
 		})
 	}
 }
+
+// query exercising every category and most operators
+const benchQuery = `T:notes p:meetings (or a="Ken Thompson" a:pike a~griesemer) ` +
+	`d>="January 1, 2020" d<2025Q1 f>=2024-W10 t=go t:plan9 -t=draft ` +
+	`(and h:design l:golang.org m:status) (or T/^weekly T/standup$)`
+
+func BenchmarkLex(b *testing.B) {
+	for b.Loop() {
+		query.Lex(benchQuery)
+	}
+}
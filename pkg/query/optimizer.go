@@ -2,6 +2,7 @@ package query
 
 import (
 	"bytes"
+	"context"
 	"slices"
 	"strings"
 	"sync"
@@ -12,7 +13,18 @@ import (
 type Optimizer struct {
 	workers  uint
 	root     *Clause
-	isSorted bool // current sort state of statement for all clauses
+	isSorted bool                    // current sort state of statement for all clauses
+	pool     *util.Pool[optimizeJob] // shared across passes while optimizing, nil otherwise
+}
+
+type optimizeJob struct {
+	clause   *Clause
+	optimize func(*Clause)
+}
+
+func runOptimizeJob(_ context.Context, job optimizeJob) error {
+	job.optimize(job.clause)
+	return nil
 }
 
 func StatementCmp(a Statement, b Statement) int {
@@ -49,6 +61,9 @@ func NewOptimizer(root *Clause, workers uint) Optimizer {
 // Optimize clause according to level.
 // level 0 is automatic and levels < 0 do nothing.
 func (o Optimizer) Optimize(level int) {
+	o.pool = util.NewPool(context.Background(), o.workers, runOptimizeJob)
+	defer o.pool.Wait()
+
 	o.Simplify()
 	if level < 0 {
 		return
@@ -81,43 +96,30 @@ func (o Optimizer) Optimize(level int) {
 
 // Perform optimizations in parallel. They should **NOT** mutate the tree
 func (o Optimizer) parallel(optimize func(*Clause)) {
-	jobs := make(chan *Clause, o.workers)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(int(o.workers))
-
-	for range o.workers {
-		go func(jobs <-chan *Clause, wg *sync.WaitGroup) {
-			for clause := range jobs {
-				optimize(clause)
-			}
-			wg.Done()
-		}(jobs, wg)
+	pool := o.pool
+	if pool == nil {
+		pool = util.NewPool(context.Background(), o.workers, runOptimizeJob)
+		defer pool.Wait()
 	}
 
 	for clause := range o.root.DFS() {
-		jobs <- clause
+		pool.Submit(optimizeJob{clause, optimize})
 	}
-	close(jobs)
-	wg.Wait()
+	pool.Flush()
 }
 
 // Perform Optimizations serially. Only use this if the tree is being modified.
 // When modifying a clause set children that should not be explored to nil
 func (o *Optimizer) serial(optimize func(*Clause)) {
-	stack := make([]*Clause, 0, len(o.root.Clauses))
-	stack = append(stack, o.root)
-	for len(stack) != 0 {
-		top := len(stack) - 1
-		node := stack[top]
-		stack = stack[:top]
-
+	stack := util.NewStack[*Clause](len(o.root.Clauses) + 1)
+	stack.Push(o.root)
+	for node, ok := stack.Pop(); ok; node, ok = stack.Pop() {
 		optimize(node)
 
 		node.Clauses = slices.DeleteFunc(node.Clauses, func(child *Clause) bool {
 			return child == nil
 		})
-		stack = append(stack, node.Clauses...)
+		stack.Push(node.Clauses...)
 	}
 }
 
@@ -198,9 +200,12 @@ func (o *Optimizer) Tidy() {
 		// this means no valid categories in statements
 		if start == -1 {
 			c.Statements = nil
-			markedLock.Lock()
-			marked[c] = true
-			markedLock.Unlock()
+			// clauses of only clauses, such as expanded saved queries, aren't noops
+			if len(c.Clauses) == 0 {
+				markedLock.Lock()
+				marked[c] = true
+				markedLock.Unlock()
+			}
 			return
 		}
 
@@ -247,7 +252,8 @@ func (o *Optimizer) Contradictions() {
 		removals := make(map[int]bool, 8)
 		var isContradiction func(s1, s2 Statement) bool
 		for category, stmts := range c.Statements.CategoryPartition() {
-			if c.Operator == COP_AND && !category.IsSet() {
+			// fields with different keys may both hold
+			if c.Operator == COP_AND && !category.IsSet() && category != CAT_META_FIELD {
 				isContradiction = func(s1, s2 Statement) bool {
 					return (s1.Operator == OP_EQ && s1.Operator == s2.Operator) || inverseEq(s1, s2)
 				}
@@ -299,7 +305,9 @@ func (o Optimizer) StrictEquality() {
 
 		stricts := make([]string, 0)
 		for category, stmts := range c.Statements.CategoryPartition() {
-			if category.IsSet() {
+			if category == CAT_META_FIELD {
+				continue
+			} else if category.IsSet() {
 				clear(stricts)
 				for i, s := range stmts {
 					val := strings.ToLower(s.Value.(StringValue).S)
@@ -349,7 +357,10 @@ func (o *Optimizer) MergeRegex() {
 		defer pool.Put(buf)
 		defer buf.Reset()
 		sortChanged := false
-		for _, catStmts := range c.Statements.CategoryPartition() {
+		for category, catStmts := range c.Statements.CategoryPartition() {
+			if category == CAT_META_FIELD {
+				continue
+			}
 			for op, opStmts := range catStmts.OperatorPartition() {
 				if op != OP_RE {
 					continue
@@ -414,7 +425,7 @@ func (o *Optimizer) MergeApproximateMatches() {
 
 		changeSort := false
 		for category, catStmts := range c.Statements.CategoryPartition() {
-			if len(catStmts) < 2 || category.IsOrdered() {
+			if len(catStmts) < 2 || category.IsOrdered() || category == CAT_META_FIELD || category == CAT_LANG {
 				continue
 			}
 			for op, opStmts := range catStmts.OperatorPartition() {
@@ -457,7 +468,7 @@ func (o *Optimizer) Tighten() {
 
 	o.parallel(func(c *Clause) {
 		for category, stmts := range c.Statements.CategoryPartition() {
-			if len(stmts) < 2 {
+			if len(stmts) < 2 || category == CAT_META_FIELD || category == CAT_LANG {
 				continue
 			}
 			if c.Operator == COP_AND {
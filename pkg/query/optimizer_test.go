@@ -393,6 +393,35 @@ func TestOptimizer_Tidy(t *testing.T) {
 				},
 			},
 		},
+		{
+			"clause of clauses",
+			&query.Clause{
+				Operator: query.COP_AND,
+				Clauses: []*query.Clause{
+					{Operator: query.COP_OR, Clauses: []*query.Clause{
+						{Operator: query.COP_AND, Statements: []query.Statement{
+							{false, query.CAT_TAGS, query.OP_EQ, query.StringValue{"inbox"}},
+						}},
+						{Operator: query.COP_AND, Statements: []query.Statement{
+							{false, query.CAT_TAGS, query.OP_EQ, query.StringValue{"reading"}},
+						}},
+					}},
+				},
+			},
+			query.Clause{
+				Operator: query.COP_AND,
+				Clauses: []*query.Clause{
+					{Operator: query.COP_OR, Clauses: []*query.Clause{
+						{Operator: query.COP_AND, Statements: []query.Statement{
+							{false, query.CAT_TAGS, query.OP_EQ, query.StringValue{"inbox"}},
+						}},
+						{Operator: query.COP_AND, Statements: []query.Statement{
+							{false, query.CAT_TAGS, query.OP_EQ, query.StringValue{"reading"}},
+						}},
+					}},
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -543,14 +572,14 @@ func TestOptimizer_StrictEquality(t *testing.T) {
 			&query.Clause{
 				Operator: query.COP_AND,
 				Statements: []query.Statement{
-					{Category: CAT_DATE, Operator: OP_EQ, Value: query.DatetimeValue{time.Date(1886, time.May, 1, 0, 0, 0, 0, time.UTC)}},
-					{Category: CAT_DATE, Operator: OP_GE, Value: query.DatetimeValue{time.Date(1880, time.January, 1, 0, 0, 0, 0, time.UTC)}},
+					{Category: CAT_DATE, Operator: OP_EQ, Value: query.DatetimeValue{D: time.Date(1886, time.May, 1, 0, 0, 0, 0, time.UTC)}},
+					{Category: CAT_DATE, Operator: OP_GE, Value: query.DatetimeValue{D: time.Date(1880, time.January, 1, 0, 0, 0, 0, time.UTC)}},
 				},
 			},
 			query.Clause{
 				Operator: query.COP_AND,
 				Statements: []query.Statement{
-					{Category: CAT_DATE, Operator: OP_EQ, Value: query.DatetimeValue{time.Date(1886, time.May, 1, 0, 0, 0, 0, time.UTC)}},
+					{Category: CAT_DATE, Operator: OP_EQ, Value: query.DatetimeValue{D: time.Date(1886, time.May, 1, 0, 0, 0, 0, time.UTC)}},
 				},
 			},
 		},
@@ -577,14 +606,14 @@ func TestOptimizer_Tighten(t *testing.T) {
 			&query.Clause{
 				Operator: query.COP_OR,
 				Statements: []query.Statement{
-					{Category: query.CAT_DATE, Operator: query.OP_GT, Value: query.DatetimeValue{time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}},
-					{Category: query.CAT_DATE, Operator: query.OP_GT, Value: query.DatetimeValue{time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC)}},
+					{Category: query.CAT_DATE, Operator: query.OP_GT, Value: query.DatetimeValue{D: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}},
+					{Category: query.CAT_DATE, Operator: query.OP_GT, Value: query.DatetimeValue{D: time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC)}},
 				},
 			},
 			query.Clause{
 				Operator: query.COP_OR,
 				Statements: []query.Statement{
-					{Category: query.CAT_DATE, Operator: query.OP_GT, Value: query.DatetimeValue{time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}},
+					{Category: query.CAT_DATE, Operator: query.OP_GT, Value: query.DatetimeValue{D: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}},
 				},
 			},
 		},
@@ -593,14 +622,14 @@ func TestOptimizer_Tighten(t *testing.T) {
 			&query.Clause{
 				Operator: query.COP_AND,
 				Statements: []query.Statement{
-					{Category: query.CAT_DATE, Operator: query.OP_GT, Value: query.DatetimeValue{time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}},
-					{Category: query.CAT_DATE, Operator: query.OP_GT, Value: query.DatetimeValue{time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC)}},
+					{Category: query.CAT_DATE, Operator: query.OP_GT, Value: query.DatetimeValue{D: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}},
+					{Category: query.CAT_DATE, Operator: query.OP_GT, Value: query.DatetimeValue{D: time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC)}},
 				},
 			},
 			query.Clause{
 				Operator: query.COP_AND,
 				Statements: []query.Statement{
-					{Category: query.CAT_DATE, Operator: query.OP_GT, Value: query.DatetimeValue{time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC)}},
+					{Category: query.CAT_DATE, Operator: query.OP_GT, Value: query.DatetimeValue{D: time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC)}},
 				},
 			},
 		},
@@ -719,3 +748,17 @@ func TestOptimizer_MergeRegex(t *testing.T) {
 		})
 	}
 }
+
+func BenchmarkOptimizer_Optimize(b *testing.B) {
+	tokens := query.Lex(benchQuery)
+	for b.Loop() {
+		b.StopTimer()
+		clause, err := query.Parse(tokens)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+
+		query.NewOptimizer(clause, 4).Optimize(0)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"sync"
 
 	"github.com/goccy/go-yaml"
 	"github.com/jpappel/atlas/pkg/index"
@@ -16,16 +17,20 @@ const DefaultOutputFormat string = "%p %T %d authors:%a tags:%t"
 type OutputToken uint64
 
 const (
-	OUT_TOK_STR      OutputToken = iota
-	OUT_TOK_PATH                 // %p %path
-	OUT_TOK_TITLE                // %T %title
-	OUT_TOK_DATE                 // %d %date
-	OUT_TOK_FILETIME             // %f %filetime
-	OUT_TOK_AUTHORS              // %a %authors
-	OUT_TOK_TAGS                 // %t %tags
-	OUT_TOK_HEADINGS             // %h %headings
-	OUT_TOK_LINKS                // %l %links
-	OUT_TOK_META                 // %m %meta
+	OUT_TOK_STR       OutputToken = iota
+	OUT_TOK_PATH                  // %p %path
+	OUT_TOK_TITLE                 // %T %title
+	OUT_TOK_DATE                  // %d %date
+	OUT_TOK_FILETIME              // %f %filetime
+	OUT_TOK_AUTHORS               // %a %authors
+	OUT_TOK_TAGS                  // %t %tags
+	OUT_TOK_HEADINGS              // %h %headings
+	OUT_TOK_LINKS                 // %l %links
+	OUT_TOK_META                  // %m %meta
+	OUT_TOK_DATABASE              // %D %database
+	OUT_TOK_ID                    // %i %id
+	OUT_TOK_CITATIONS             // %c %citations
+	OUT_TOK_LANG                  // %L %lang
 )
 
 type Outputer interface {
@@ -92,31 +97,43 @@ func (o DefaultOutput) OutputTo(w io.Writer, docs []*index.Document) (int, error
 	return n, nil
 }
 
+// layout of time.Time.String without the monotonic clock reading
+const defaultDateLayout = "2006-01-02 15:04:05.999999999 -0700 MST"
+
+var outputBufPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
 func (o DefaultOutput) WriteDoc(w io.Writer, doc *index.Document) (int, error) {
-	var n int
-	s := [][]byte{
-		[]byte(doc.Path),
-		{' '},
-		[]byte(doc.Title),
-		{' '},
-		[]byte(doc.Date.String()),
-		{' '},
-		[]byte("authors:"),
-		[]byte(strings.Join(doc.Authors, ",")),
-		[]byte(" tags:"),
-		[]byte(strings.Join(doc.Tags, ",")),
-		{'\n'},
+	b := outputBufPool.Get().(*bytes.Buffer)
+	defer outputBufPool.Put(b)
+	b.Reset()
+
+	b.WriteString(doc.Path)
+	b.WriteByte(' ')
+	b.WriteString(doc.Title)
+	b.WriteByte(' ')
+	b.Write(doc.Date.AppendFormat(b.AvailableBuffer(), defaultDateLayout))
+	b.WriteString(" authors:")
+	writeList(b, doc.Authors, ",")
+	b.WriteString(" tags:")
+	writeList(b, doc.Tags, ",")
+	if doc.Database != "" {
+		b.WriteString(" db:")
+		b.WriteString(doc.Database)
 	}
-	for _, b := range s {
-		cnt, err := w.Write(b)
-		if err != nil {
-			return n, err
-		}
+	b.WriteByte('\n')
 
-		n += cnt
-	}
+	return w.Write(b.Bytes())
+}
 
-	return n, nil
+func writeList(b *bytes.Buffer, items []string, sep string) {
+	for i, item := range items {
+		if i != 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(item)
+	}
 }
 
 func (o JsonOutput) OutputOne(doc *index.Document) (string, error) {
@@ -224,6 +241,14 @@ func ParseOutputFormat(formatStr string) ([]OutputToken, []string, error) {
 				toks = append(toks, OUT_TOK_LINKS)
 			case "%m":
 				toks = append(toks, OUT_TOK_META)
+			case "%D":
+				toks = append(toks, OUT_TOK_DATABASE)
+			case "%i":
+				toks = append(toks, OUT_TOK_ID)
+			case "%c":
+				toks = append(toks, OUT_TOK_CITATIONS)
+			case "%L":
+				toks = append(toks, OUT_TOK_LANG)
 			default:
 				return nil, nil, ErrUnrecognizedOutputToken
 			}
@@ -260,6 +285,77 @@ func NewCustomOutput(
 	}, nil
 }
 
+// Create a CustomOutput that writes the named fields of each document
+// delimited by fieldSeparator.
+//
+// Fields: path,id,title,lang,date,filetime,authors,tags,headings,links,citations,meta,database
+func NewFieldsOutput(
+	fields []string, fieldSeparator string, datetimeFormat string,
+	docSeparator string, listSeparator string,
+) (CustomOutput, error) {
+	outToks := make([]OutputToken, 0, 2*len(fields))
+	strToks := make([]string, 0, len(fields))
+	for i, field := range fields {
+		if i != 0 {
+			outToks = append(outToks, OUT_TOK_STR)
+			strToks = append(strToks, fieldSeparator)
+		}
+
+		var tok OutputToken
+		switch strings.TrimSpace(field) {
+		case "path":
+			tok = OUT_TOK_PATH
+		case "title":
+			tok = OUT_TOK_TITLE
+		case "date":
+			tok = OUT_TOK_DATE
+		case "filetime":
+			tok = OUT_TOK_FILETIME
+		case "authors":
+			tok = OUT_TOK_AUTHORS
+		case "tags":
+			tok = OUT_TOK_TAGS
+		case "headings":
+			tok = OUT_TOK_HEADINGS
+		case "links":
+			tok = OUT_TOK_LINKS
+		case "meta":
+			tok = OUT_TOK_META
+		case "database":
+			tok = OUT_TOK_DATABASE
+		case "id":
+			tok = OUT_TOK_ID
+		case "citations":
+			tok = OUT_TOK_CITATIONS
+		case "lang":
+			tok = OUT_TOK_LANG
+		default:
+			return CustomOutput{}, fmt.Errorf("%w: %s", ErrUnrecognizedOutputToken, field)
+		}
+		outToks = append(outToks, tok)
+	}
+
+	return CustomOutput{
+		strToks,
+		outToks,
+		datetimeFormat,
+		docSeparator,
+		listSeparator,
+	}, nil
+}
+
+// Reports if the format uses fields stored outside of a document's row
+// (authors, tags, or links)
+func (o CustomOutput) NeedsRelations() bool {
+	for _, token := range o.tokens {
+		switch token {
+		case OUT_TOK_AUTHORS, OUT_TOK_TAGS, OUT_TOK_LINKS, OUT_TOK_CITATIONS:
+			return true
+		}
+	}
+	return false
+}
+
 func (o CustomOutput) OutputOne(doc *index.Document) (string, error) {
 	b := strings.Builder{}
 
@@ -332,6 +428,14 @@ func (o CustomOutput) writeDoc(w io.Writer, doc *index.Document) (int, error) {
 			b.WriteString(strings.Join(doc.Links, o.listSeparator))
 		case OUT_TOK_META:
 			b.WriteString(doc.OtherMeta)
+		case OUT_TOK_DATABASE:
+			b.WriteString(doc.Database)
+		case OUT_TOK_ID:
+			b.WriteString(doc.ID)
+		case OUT_TOK_CITATIONS:
+			b.WriteString(strings.Join(doc.Citations, o.listSeparator))
+		case OUT_TOK_LANG:
+			b.WriteString(doc.Language)
 		default:
 			return 0, ErrUnrecognizedOutputToken
 		}
@@ -2,9 +2,14 @@ package query_test
 
 import (
 	"errors"
+	"fmt"
+	"io"
 	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/jpappel/atlas/pkg/index"
 	"github.com/jpappel/atlas/pkg/query"
 )
 
@@ -74,3 +79,137 @@ func Test_parseOutputFormat(t *testing.T) {
 		})
 	}
 }
+
+func TestNewFieldsOutput(t *testing.T) {
+	doc := &index.Document{
+		Path:    "/a/path",
+		Title:   "A Title",
+		Date:    time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+		Authors: []string{"jp", "pj"},
+		Tags:    []string{"foo", "bar"},
+	}
+	tests := []struct {
+		name    string
+		fields  []string
+		want    string
+		wantErr error
+	}{
+		{"single field", []string{"path"}, "/a/path\n", nil},
+		{"multiple fields", []string{"path", "title", "date"}, "/a/path\tA Title\t2006-01-02\n", nil},
+		{"list fields", []string{"authors", "tags"}, "jp, pj\tfoo, bar\n", nil},
+		{"unknown field", []string{"path", "colour"}, "", query.ErrUnrecognizedOutputToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, gotErr := query.NewFieldsOutput(tt.fields, "\t", time.DateOnly, "\n", ", ")
+			if !errors.Is(gotErr, tt.wantErr) {
+				t.Fatalf("Recieved unexpected error: got %v want %v", gotErr, tt.wantErr)
+			} else if gotErr != nil {
+				return
+			}
+
+			got, err := o.OutputOne(doc)
+			if err != nil {
+				t.Fatal("Unexpected error during output:", err)
+			}
+			if got != tt.want {
+				t.Errorf("OutputOne() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCustomOutput_NeedsRelations(t *testing.T) {
+	tests := []struct {
+		format string
+		want   bool
+	}{
+		{"%p", false},
+		{"%p %T %d %f %h %m %D", false},
+		{"%p %a", true},
+		{"%tags", true},
+		{"%p %l", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			o, err := query.NewCustomOutput(tt.format, time.DateOnly, "\n", ", ")
+			if err != nil {
+				t.Fatal("Unexpected error:", err)
+			}
+			if got := o.NeedsRelations(); got != tt.want {
+				t.Errorf("NeedsRelations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultOutput_WriteDoc(t *testing.T) {
+	date := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name string
+		doc  *index.Document
+		want string
+	}{
+		{"empty", &index.Document{}, "  0001-01-01 00:00:00 +0000 UTC authors: tags:\n"},
+		{"full", &index.Document{
+			Path:    "/a/path",
+			Title:   "A Title",
+			Date:    date,
+			Authors: []string{"jp", "pj"},
+			Tags:    []string{"foo"},
+		}, "/a/path A Title " + date.String() + " authors:jp,pj tags:foo\n"},
+		{"database", &index.Document{Path: "/a", Date: date, Database: "notes.db"},
+			"/a  " + date.String() + " authors: tags: db:notes.db\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &strings.Builder{}
+			n, err := query.DefaultOutput{}.WriteDoc(b, tt.doc)
+			if err != nil {
+				t.Fatal("Unexpected error:", err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("WriteDoc() = %q, want %q", got, tt.want)
+			}
+			if n != len(tt.want) {
+				t.Errorf("WriteDoc() wrote %d bytes, want %d", n, len(tt.want))
+			}
+		})
+	}
+}
+
+func benchOutputDocs(n int) []*index.Document {
+	docs := make([]*index.Document, n)
+	for i := range docs {
+		docs[i] = &index.Document{
+			Path:    fmt.Sprintf("/notes/%04d.md", i),
+			Title:   fmt.Sprintf("Weekly notes %d", i),
+			Date:    time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, i),
+			Authors: []string{"Ken Thompson", "Rob Pike"},
+			Tags:    []string{"go", "plan9", "notes"},
+		}
+	}
+	return docs
+}
+
+func BenchmarkDefaultOutput_OutputTo(b *testing.B) {
+	docs := benchOutputDocs(1000)
+	for b.Loop() {
+		if _, err := (query.DefaultOutput{}).OutputTo(io.Discard, docs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCustomOutput_OutputTo(b *testing.B) {
+	docs := benchOutputDocs(1000)
+	o, err := query.NewCustomOutput(query.DefaultOutputFormat, time.RFC3339, "\n", ", ")
+	if err != nil {
+		b.Fatal(err)
+	}
+	for b.Loop() {
+		if _, err := o.OutputTo(io.Discard, docs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -5,9 +5,11 @@ import (
 	"iter"
 	"os"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/jpappel/atlas/pkg/index"
 	"github.com/jpappel/atlas/pkg/util"
 )
 
@@ -23,6 +25,11 @@ const (
 	CAT_TAGS
 	CAT_HEADINGS
 	CAT_LINKS
+	CAT_ID
+	CAT_CITATIONS
+	CAT_LANG
+	CAT_PINNED
+	CAT_META_FIELD
 	CAT_META
 )
 
@@ -38,6 +45,7 @@ const (
 	OP_GE             // greater than or equal
 	OP_GT             // greater than
 	OP_RE             // regular expresion
+	OP_FZ             // typo tolerant
 )
 
 type clauseOperator int16
@@ -69,6 +77,8 @@ const (
 	VAL_NOOP valuerType = iota
 	VAL_STR
 	VAL_DATETIME
+	VAL_META
+	VAL_BOOL
 )
 
 type Valuer interface {
@@ -79,6 +89,8 @@ type Valuer interface {
 
 var _ Valuer = StringValue{}
 var _ Valuer = DatetimeValue{}
+var _ Valuer = MetaValue{}
+var _ Valuer = BoolValue{}
 
 type StringValue struct {
 	S string
@@ -109,7 +121,13 @@ func (v StringValue) buildCompile(b *strings.Builder) (string, bool) {
 }
 
 type DatetimeValue struct {
-	D time.Time
+	D   time.Time
+	End time.Time // exclusive end when the value is a period, otherwise zero
+}
+
+// Report if the value covers a period rather than a single point in time
+func (v DatetimeValue) IsRange() bool {
+	return !v.End.IsZero()
 }
 
 func (v DatetimeValue) Type() valuerType {
@@ -122,7 +140,10 @@ func (v DatetimeValue) Compare(other Valuer) int {
 		return 0
 	}
 
-	return v.D.Compare(o.D)
+	if c := v.D.Compare(o.D); c != 0 {
+		return c
+	}
+	return v.End.Compare(o.End)
 }
 
 func (v DatetimeValue) buildCompile(b *strings.Builder) (string, bool) {
@@ -130,18 +151,107 @@ func (v DatetimeValue) buildCompile(b *strings.Builder) (string, bool) {
 	return "", false
 }
 
-var _ Valuer = StringValue{}
-var _ Valuer = DatetimeValue{}
+// A typed value for a metadata key
+type MetaValue struct {
+	Key string
+	V   any // string, bool, or float64
+}
+
+// Convert a value for a metadata key to its type.
+// Only equality compares booleans, otherwise values are strings.
+func NewMetaValue(key string, s string, op opType) MetaValue {
+	v := MetaValue{Key: key, V: s}
+	if op == OP_EQ || op == OP_NE {
+		switch s {
+		case "true":
+			v.V = true
+		case "false":
+			v.V = false
+		}
+	}
+	return v
+}
+
+func (v MetaValue) Type() valuerType {
+	return VAL_META
+}
+
+func (v MetaValue) Compare(other Valuer) int {
+	o, ok := other.(MetaValue)
+	if !ok {
+		return 0
+	}
+
+	if c := strings.Compare(v.Key, o.Key); c != 0 {
+		return c
+	}
+	return strings.Compare(fmt.Sprintf("%T%v", v.V, v.V), fmt.Sprintf("%T%v", o.V, o.V))
+}
+
+func (v MetaValue) buildCompile(b *strings.Builder) (string, bool) {
+	switch val := v.V.(type) {
+	case bool:
+		if val {
+			b.WriteString("1")
+		} else {
+			b.WriteString("0")
+		}
+		return "", false
+	case float64:
+		b.WriteString(strconv.FormatFloat(val, 'g', -1, 64))
+		return "", false
+	default:
+		b.WriteByte('?')
+		return fmt.Sprint(val), true
+	}
+}
+
+type BoolValue struct {
+	B bool
+}
+
+func (v BoolValue) Type() valuerType {
+	return VAL_BOOL
+}
+
+func (v BoolValue) Compare(other Valuer) int {
+	o, ok := other.(BoolValue)
+	if !ok || v.B == o.B {
+		return 0
+	} else if o.B {
+		return -1
+	}
+	return 1
+}
+
+func (v BoolValue) buildCompile(b *strings.Builder) (string, bool) {
+	if v.B {
+		b.WriteString("1 ")
+	} else {
+		b.WriteString("0 ")
+	}
+	return "", false
+}
 
 // Return if OP_EQ behaves like set membership
 func (t catType) IsSet() bool {
-	return t == CAT_TAGS || t == CAT_AUTHOR || t == CAT_LINKS
+	return t == CAT_TAGS || t == CAT_AUTHOR || t == CAT_LINKS || t == CAT_CITATIONS
 }
 
 func (t catType) IsOrdered() bool {
 	return t == CAT_DATE || t == CAT_FILETIME
 }
 
+// Return if OP_FZ can match the category, only full text indexed categories can be
+func (t catType) IsTypoTolerant() bool {
+	switch t {
+	case CAT_PATH, CAT_TITLE, CAT_AUTHOR, CAT_TAGS, CAT_HEADINGS, CAT_LINKS, CAT_ID, CAT_CITATIONS, CAT_META:
+		return true
+	default:
+		return false
+	}
+}
+
 func (t catType) String() string {
 	switch t {
 	case CAT_PATH:
@@ -160,6 +270,16 @@ func (t catType) String() string {
 		return "headings"
 	case CAT_LINKS:
 		return "links"
+	case CAT_ID:
+		return "id"
+	case CAT_CITATIONS:
+		return "citations"
+	case CAT_LANG:
+		return "lang"
+	case CAT_PINNED:
+		return "pinned"
+	case CAT_META_FIELD:
+		return "metaField"
 	case CAT_META:
 		return "meta"
 	default:
@@ -168,7 +288,7 @@ func (t catType) String() string {
 }
 
 func (t opType) IsFuzzy() bool {
-	return t == OP_AP || t == OP_RE || t.IsOrder()
+	return t == OP_AP || t == OP_RE || t == OP_FZ || t.IsOrder()
 }
 
 func (t opType) IsOrder() bool {
@@ -193,6 +313,8 @@ func (t opType) String() string {
 		return "Greater Than"
 	case OP_RE:
 		return "Regular Expression"
+	case OP_FZ:
+		return "Typo Tolerant"
 	default:
 		return "Invalid"
 	}
@@ -217,6 +339,16 @@ func tokToCat(t queryTokenType) catType {
 		return CAT_HEADINGS
 	case TOK_CAT_LINKS:
 		return CAT_LINKS
+	case TOK_CAT_ID:
+		return CAT_ID
+	case TOK_CAT_CITATIONS:
+		return CAT_CITATIONS
+	case TOK_CAT_LANG:
+		return CAT_LANG
+	case TOK_CAT_PINNED:
+		return CAT_PINNED
+	case TOK_CAT_META_FIELD:
+		return CAT_META_FIELD
 	case TOK_CAT_META:
 		return CAT_META
 	default:
@@ -243,6 +375,8 @@ func tokToOp(t queryTokenType) opType {
 		return OP_GT
 	case TOK_OP_RE:
 		return OP_RE
+	case TOK_OP_FZ:
+		return OP_FZ
 	default:
 		return OP_UNKNOWN
 	}
@@ -250,7 +384,11 @@ func tokToOp(t queryTokenType) opType {
 
 // Apply negation to a statements operator
 func (s *Statement) Simplify() {
-	if s.Negated && s.Operator != OP_AP && s.Operator != OP_RE {
+	// inverting an ordered metadata comparison would drop documents without the field
+	if s.Category == CAT_META_FIELD && s.Operator.IsOrder() {
+		return
+	}
+	if s.Negated && s.Operator != OP_AP && s.Operator != OP_RE && s.Operator != OP_FZ {
 		s.Negated = false
 		switch s.Operator {
 		case OP_EQ:
@@ -400,45 +538,28 @@ func (root Clause) Order() int {
 
 func (root *Clause) DFS() iter.Seq[*Clause] {
 	return func(yield func(*Clause) bool) {
-		stack := make([]*Clause, 0, len(root.Clauses)+1)
-		stack = append(stack, root)
-
-		for len(stack) != 0 {
-			node := stack[len(stack)-1]
-			stack = stack[:len(stack)-1]
+		stack := util.NewStack[*Clause](len(root.Clauses) + 1)
+		stack.Push(root)
 
+		for node, ok := stack.Pop(); ok; node, ok = stack.Pop() {
 			if !yield(node) {
 				return
 			}
-
-			stack = append(stack, node.Clauses...)
+			stack.Push(node.Clauses...)
 		}
 	}
 }
 
 func (root *Clause) BFS() iter.Seq[*Clause] {
 	return func(yield func(*Clause) bool) {
-		queue := make([]*Clause, 64*len(root.Clauses))
-		queue = append(queue, root)
-		read := 0
-		write := 1
-		size := 1
-
-		// WARN: can potentially discard values if queue is too small
-		for size != 0 {
-			node := queue[read]
+		queue := util.NewDeque[*Clause](len(root.Clauses) + 1)
+		queue.PushBack(root)
 
+		for node, ok := queue.PopFront(); ok; node, ok = queue.PopFront() {
 			if !yield(node) {
 				return
 			}
-			read = (read + 1) % len(queue)
-			size -= 1
-
-			for _, child := range node.Clauses {
-				queue[write] = child
-				write = (write + 1) % len(queue)
-				size += 1
-			}
+			queue.PushBack(node.Clauses...)
 		}
 	}
 }
@@ -483,7 +604,7 @@ func Parse(tokens []Token) (*Clause, error) {
 			}
 			clause.Operator = COP_OR
 		case TOK_OP_NEG:
-			if !prevToken.Type.Any(TOK_CLAUSE_OR, TOK_CLAUSE_AND, TOK_VAL_STR, TOK_VAL_DATETIME, TOK_CLAUSE_END) {
+			if !prevToken.Type.Any(TOK_CLAUSE_OR, TOK_CLAUSE_AND, TOK_VAL_STR, TOK_VAL_DATETIME, TOK_VAL_NUMBER, TOK_CLAUSE_END) {
 				return nil, &TokenError{
 					got:      token,
 					gotPrev:  prevToken,
@@ -493,8 +614,8 @@ func Parse(tokens []Token) (*Clause, error) {
 
 			stmt := Statement{Negated: true}
 			clause.Statements = append(clause.Statements, stmt)
-		case TOK_CAT_PATH, TOK_CAT_TITLE, TOK_CAT_AUTHOR, TOK_CAT_DATE, TOK_CAT_FILETIME, TOK_CAT_TAGS, TOK_CAT_HEADINGS, TOK_CAT_LINKS, TOK_CAT_META:
-			if !prevToken.Type.Any(TOK_CLAUSE_OR, TOK_CLAUSE_AND, TOK_VAL_STR, TOK_VAL_DATETIME, TOK_OP_NEG, TOK_CLAUSE_END) {
+		case TOK_CAT_PATH, TOK_CAT_TITLE, TOK_CAT_AUTHOR, TOK_CAT_DATE, TOK_CAT_FILETIME, TOK_CAT_TAGS, TOK_CAT_HEADINGS, TOK_CAT_LINKS, TOK_CAT_ID, TOK_CAT_CITATIONS, TOK_CAT_LANG, TOK_CAT_PINNED, TOK_CAT_META_FIELD, TOK_CAT_META:
+			if !prevToken.Type.Any(TOK_CLAUSE_OR, TOK_CLAUSE_AND, TOK_VAL_STR, TOK_VAL_DATETIME, TOK_VAL_NUMBER, TOK_OP_NEG, TOK_CLAUSE_END) {
 				return nil, &TokenError{
 					got:      token,
 					gotPrev:  prevToken,
@@ -508,7 +629,7 @@ func Parse(tokens []Token) (*Clause, error) {
 				stmt := Statement{Category: tokToCat(token.Type)}
 				clause.Statements = append(clause.Statements, stmt)
 			}
-		case TOK_OP_EQ, TOK_OP_AP, TOK_OP_NE, TOK_OP_LT, TOK_OP_LE, TOK_OP_GE, TOK_OP_GT, TOK_OP_RE:
+		case TOK_OP_EQ, TOK_OP_AP, TOK_OP_NE, TOK_OP_LT, TOK_OP_LE, TOK_OP_GE, TOK_OP_GT, TOK_OP_RE, TOK_OP_FZ:
 			if !prevToken.Type.isCategory() {
 				return nil, &TokenError{
 					got:      token,
@@ -527,11 +648,55 @@ func Parse(tokens []Token) (*Clause, error) {
 				}
 			}
 
-			if prevToken.Type == TOK_OP_AP {
+			stmt := &clause.Statements[len(clause.Statements)-1]
+			if stmt.Operator == OP_FZ && !stmt.Category.IsTypoTolerant() {
+				return nil, fmt.Errorf("Cannot use typo tolerant matching on %s", stmt.Category)
+			} else if stmt.Category == CAT_META_FIELD {
+				key, _ := metaFieldKey(tokens[i-2].Value)
+				stmt.Value = NewMetaValue(key, token.Value, stmt.Operator)
+			} else if stmt.Category == CAT_PINNED {
+				if stmt.Operator == OP_RE {
+					return nil, fmt.Errorf("Cannot match pinned `%s` with a regular expression", token.Value)
+				}
+				pinned, err := strconv.ParseBool(token.Value)
+				if err != nil {
+					return nil, fmt.Errorf("Cannot parse pinned `%s`, expected true or false", token.Value)
+				}
+				// there is nothing approximate about a flag
+				if stmt.Operator == OP_AP {
+					stmt.Operator = OP_EQ
+				}
+				stmt.Value = BoolValue{pinned}
+			} else if stmt.Category == CAT_LANG && stmt.Operator != OP_RE {
+				// languages are stored normalized
+				stmt.Value = StringValue{index.NormalizeLanguage(token.Value)}
+			} else if prevToken.Type == TOK_OP_AP {
+				// quote full text search phrases
 				clause.Statements[len(clause.Statements)-1].Value = StringValue{"\"" + token.Value + "\""}
 			} else {
 				clause.Statements[len(clause.Statements)-1].Value = StringValue{token.Value}
 			}
+		case TOK_VAL_NUMBER:
+			if !prevToken.Type.isNumberOperation() {
+				return nil, &TokenError{
+					got:      token,
+					gotPrev:  prevToken,
+					wantPrev: "number operation",
+				}
+			}
+
+			stmt := &clause.Statements[len(clause.Statements)-1]
+			key, _ := metaFieldKey(tokens[i-2].Value)
+			if stmt.Operator == OP_AP || stmt.Operator == OP_RE {
+				stmt.Value = MetaValue{Key: key, V: token.Value}
+				break
+			}
+
+			n, err := strconv.ParseFloat(token.Value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("Cannot parse number `%s`, %v", token.Value, err)
+			}
+			stmt.Value = MetaValue{Key: key, V: n}
 		case TOK_VAL_DATETIME:
 			if !prevToken.Type.isDateOperation() {
 				return nil, &TokenError{
@@ -541,16 +706,32 @@ func Parse(tokens []Token) (*Clause, error) {
 				}
 			}
 
-			var t time.Time
-			var err error
-			if t, err = util.ParseDateTime(token.Value); err != nil {
+			start, end, err := util.ParseDateRange(token.Value)
+			if err != nil {
 				return nil, fmt.Errorf("Cannot parse time `%s`, %v",
 					token.Value,
 					ErrDatetimeTokenParse,
 				)
 			}
 
-			clause.Statements[len(clause.Statements)-1].Value = DatetimeValue{t}
+			// periods are only kept for equality, otherwise compare against a bound
+			stmt := &clause.Statements[len(clause.Statements)-1]
+			switch {
+			case end.IsZero():
+				stmt.Value = DatetimeValue{D: start}
+			case stmt.Operator == OP_GT:
+				stmt.Operator = OP_GE
+				stmt.Value = DatetimeValue{D: end}
+			case stmt.Operator == OP_LE:
+				stmt.Operator = OP_LT
+				stmt.Value = DatetimeValue{D: end}
+			case stmt.Operator == OP_GE || stmt.Operator == OP_LT:
+				stmt.Value = DatetimeValue{D: start}
+			default:
+				stmt.Value = DatetimeValue{D: start, End: end}
+			}
+		case TOK_SAVED:
+			return nil, fmt.Errorf("Saved query @%s must be expanded before parsing", token.Value)
 		default:
 			fmt.Fprintln(os.Stderr, token)
 			return nil, &TokenError{
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"slices"
 	"testing"
+	"time"
 
 	"github.com/jpappel/atlas/pkg/query"
 )
@@ -19,6 +20,8 @@ const (
 	CAT_LINKS    = query.CAT_LINKS
 	CAT_META     = query.CAT_META
 
+	CAT_META_FIELD = query.CAT_META_FIELD
+
 	OP_UNKNOWN = query.OP_UNKNOWN
 	OP_EQ      = query.OP_EQ
 	OP_AP      = query.OP_AP
@@ -28,6 +31,7 @@ const (
 	OP_GE      = query.OP_GE
 	OP_GT      = query.OP_GT
 	OP_RE      = query.OP_RE
+	OP_FZ      = query.OP_FZ
 )
 
 func TestParse(t *testing.T) {
@@ -123,3 +127,163 @@ func TestParse(t *testing.T) {
 		})
 	}
 }
+
+func TestParse_DatePeriods(t *testing.T) {
+	q2 := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	q3 := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		query string
+		want  query.Statement
+	}{
+		{"d=2024Q2", query.Statement{Category: CAT_DATE, Operator: OP_EQ, Value: query.DatetimeValue{D: q2, End: q3}}},
+		{"d!=2024Q2", query.Statement{Category: CAT_DATE, Operator: OP_NE, Value: query.DatetimeValue{D: q2, End: q3}}},
+		{"d:2024Q2", query.Statement{Category: CAT_DATE, Operator: OP_AP, Value: query.DatetimeValue{D: q2, End: q3}}},
+		{"d>2024Q2", query.Statement{Category: CAT_DATE, Operator: OP_GE, Value: query.DatetimeValue{D: q3}}},
+		{"d>=2024Q2", query.Statement{Category: CAT_DATE, Operator: OP_GE, Value: query.DatetimeValue{D: q2}}},
+		{"d<2024Q2", query.Statement{Category: CAT_DATE, Operator: OP_LT, Value: query.DatetimeValue{D: q2}}},
+		{"d<=2024Q2", query.Statement{Category: CAT_DATE, Operator: OP_LT, Value: query.DatetimeValue{D: q3}}},
+		{"d=2024-04-01", query.Statement{Category: CAT_DATE, Operator: OP_EQ, Value: query.DatetimeValue{D: q2}}},
+		{"d>2024", query.Statement{Category: CAT_DATE, Operator: OP_GE, Value: query.DatetimeValue{D: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}}},
+		{"d=20240401", query.Statement{Category: CAT_DATE, Operator: OP_EQ, Value: query.DatetimeValue{D: q2}}},
+		{"d=@1711929600", query.Statement{Category: CAT_DATE, Operator: OP_EQ, Value: query.DatetimeValue{D: q2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			clause, err := query.Parse(query.Lex(tt.query))
+			if err != nil {
+				t.Fatal("Unexpected parse error:", err)
+			}
+			if len(clause.Statements) != 1 {
+				t.Fatalf("Expected 1 statement, got %d", len(clause.Statements))
+			}
+
+			got := clause.Statements[0]
+			if got.Category != tt.want.Category || got.Operator != tt.want.Operator || got.Value.Compare(tt.want.Value) != 0 {
+				t.Errorf("Parsed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_MetaFields(t *testing.T) {
+	tests := []struct {
+		query string
+		want  query.Statement
+	}{
+		{"m.draft=true", query.Statement{Category: CAT_META_FIELD, Operator: OP_EQ, Value: query.MetaValue{Key: "draft", V: true}}},
+		{"meta.draft!=false", query.Statement{Category: CAT_META_FIELD, Operator: OP_NE, Value: query.MetaValue{Key: "draft", V: false}}},
+		{"m.status=done", query.Statement{Category: CAT_META_FIELD, Operator: OP_EQ, Value: query.MetaValue{Key: "status", V: "done"}}},
+		{"m.status:true", query.Statement{Category: CAT_META_FIELD, Operator: OP_AP, Value: query.MetaValue{Key: "status", V: "true"}}},
+		{"m.rating>=4", query.Statement{Category: CAT_META_FIELD, Operator: OP_GE, Value: query.MetaValue{Key: "rating", V: 4.0}}},
+		{"m.priority<-1.5", query.Statement{Category: CAT_META_FIELD, Operator: OP_LT, Value: query.MetaValue{Key: "priority", V: -1.5}}},
+		{`m.rating="4"`, query.Statement{Category: CAT_META_FIELD, Operator: OP_EQ, Value: query.MetaValue{Key: "rating", V: "4"}}},
+		{"m.project.name=atlas", query.Statement{Category: CAT_META_FIELD, Operator: OP_EQ, Value: query.MetaValue{Key: "project.name", V: "atlas"}}},
+		{"m.rating:4", query.Statement{Category: CAT_META_FIELD, Operator: OP_AP, Value: query.MetaValue{Key: "rating", V: "4"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			clause, err := query.Parse(query.Lex(tt.query))
+			if err != nil {
+				t.Fatal("Unexpected parse error:", err)
+			}
+			if len(clause.Statements) != 1 {
+				t.Fatalf("Expected 1 statement, got %d", len(clause.Statements))
+			}
+
+			got := clause.Statements[0]
+			if got.Category != tt.want.Category || got.Operator != tt.want.Operator || got.Value.Compare(tt.want.Value) != 0 {
+				t.Errorf("Parsed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_Language(t *testing.T) {
+	tests := []struct {
+		query string
+		want  query.Statement
+	}{
+		{"lang=DE", query.Statement{Category: query.CAT_LANG, Operator: OP_EQ, Value: query.StringValue{S: "de"}}},
+		{"lang!=en-US", query.Statement{Category: query.CAT_LANG, Operator: OP_NE, Value: query.StringValue{S: "en"}}},
+		{"lang:en_GB", query.Statement{Category: query.CAT_LANG, Operator: OP_AP, Value: query.StringValue{S: "en"}}},
+		{"lang/^E", query.Statement{Category: query.CAT_LANG, Operator: OP_RE, Value: query.StringValue{S: "^E"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			clause, err := query.Parse(query.Lex(tt.query))
+			if err != nil {
+				t.Fatal("Unexpected parse error:", err)
+			}
+			if len(clause.Statements) != 1 {
+				t.Fatalf("Expected 1 statement, got %d", len(clause.Statements))
+			}
+
+			got := clause.Statements[0]
+			if got.Category != tt.want.Category || got.Operator != tt.want.Operator || got.Value.Compare(tt.want.Value) != 0 {
+				t.Errorf("Parsed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_TypoTolerant(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr bool
+	}{
+		{`a?="jon smth"`, false},
+		{"-T?=metting", false},
+		{"i?=20240112", false},
+		{"lang?=en", true},
+		{"pinned?=true", true},
+		{"m.status?=done", true},
+		{"d?=2024-01-01", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			clause, err := query.Parse(query.Lex(tt.query))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %v", clause)
+				}
+				return
+			} else if err != nil {
+				t.Fatal("Unexpected parse error:", err)
+			}
+
+			if got := clause.Statements[0].Operator; got != OP_FZ {
+				t.Errorf("Parsed operator %v, want %v", got, OP_FZ)
+			}
+		})
+	}
+}
+
+func TestClause_Traversal(t *testing.T) {
+	// root
+	//  ├── a
+	//  │   └── c
+	//  └── b
+	c := &query.Clause{Operator: query.COP_OR}
+	a := &query.Clause{Operator: query.COP_AND, Clauses: []*query.Clause{c}}
+	b := &query.Clause{Operator: query.COP_OR}
+	root := &query.Clause{Operator: query.COP_AND, Clauses: []*query.Clause{a, b}}
+
+	if got, want := slices.Collect(root.BFS()), []*query.Clause{root, a, b, c}; !slices.Equal(got, want) {
+		t.Errorf("BFS visited %v, want %v", got, want)
+	}
+	if got, want := slices.Collect(root.DFS()), []*query.Clause{root, b, a, c}; !slices.Equal(got, want) {
+		t.Errorf("DFS visited %v, want %v", got, want)
+	}
+	if got := root.Order(); got != 4 {
+		t.Errorf("Order() = %d, want 4", got)
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	tokens := query.Lex(benchQuery)
+	for b.Loop() {
+		if _, err := query.Parse(tokens); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
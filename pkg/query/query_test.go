@@ -0,0 +1,29 @@
+package query_test
+
+import (
+	"testing"
+
+	"github.com/jpappel/atlas/pkg/query"
+)
+
+func BenchmarkClause_Compile(b *testing.B) {
+	clause, err := query.Parse(query.Lex(benchQuery))
+	if err != nil {
+		b.Fatal(err)
+	}
+	query.NewOptimizer(clause, 4).Optimize(0)
+
+	for b.Loop() {
+		if _, err := clause.Compile(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCompile(b *testing.B) {
+	for b.Loop() {
+		if _, err := query.Compile(benchQuery, 0, 4); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// Allowed names for saved queries
+var SavedNameRegex = regexp.MustCompile(`^[\w-]+$`)
+
+// Replace references to saved queries with a clause of the saved query's statements.
+// Saved queries may reference other saved queries but not themselves.
+func ExpandSaved(tokens []Token, lookup func(name string) (string, error)) ([]Token, error) {
+	return expandSaved(tokens, lookup, nil)
+}
+
+func expandSaved(tokens []Token, lookup func(string) (string, error), expanding []string) ([]Token, error) {
+	if !slices.ContainsFunc(tokens, func(t Token) bool { return t.Type == TOK_SAVED }) {
+		return tokens, nil
+	}
+
+	expanded := make([]Token, 0, len(tokens))
+	for _, token := range tokens {
+		if token.Type != TOK_SAVED {
+			expanded = append(expanded, token)
+			continue
+		}
+
+		name := token.Value
+		if slices.Contains(expanding, name) {
+			return nil, fmt.Errorf("Saved query @%s references itself", name)
+		}
+		saved, err := lookup(name)
+		if err != nil {
+			return nil, err
+		}
+
+		// lexed queries are wrapped in a clause
+		savedTokens, err := expandSaved(Lex(saved), lookup, append(expanding, name))
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, savedTokens...)
+	}
+
+	return expanded, nil
+}
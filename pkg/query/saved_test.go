@@ -0,0 +1,71 @@
+package query_test
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/jpappel/atlas/pkg/query"
+)
+
+func TestExpandSaved(t *testing.T) {
+	saved := map[string]string{
+		"inbox":   "t=inbox -t=archived",
+		"reading": "t=reading",
+		"both":    "intersect(@inbox,@reading)",
+		"loop":    "T:a @loop",
+	}
+	lookup := func(name string) (string, error) {
+		s, ok := saved[name]
+		if !ok {
+			return "", fmt.Errorf("No saved query named @%s", name)
+		}
+		return s, nil
+	}
+
+	start := []Token{{Type: TOK_CLAUSE_START}, {TOK_CLAUSE_AND, "and"}}
+	startOr := []Token{{Type: TOK_CLAUSE_START}, {TOK_CLAUSE_OR, "or"}}
+	end := []Token{{Type: TOK_CLAUSE_END}}
+	// saved queries expand to their lexed clause
+	inbox := query.Lex(saved["inbox"])
+	reading := query.Lex(saved["reading"])
+
+	tests := []struct {
+		query   string
+		want    []Token
+		wantErr bool
+	}{
+		{"T:notes", query.Lex("T:notes"), false},
+		{"@inbox -t=done", slices.Concat(
+			start, inbox,
+			[]Token{{Type: TOK_OP_NEG}, {TOK_CAT_TAGS, "t"}, {TOK_OP_EQ, "="}, {TOK_VAL_STR, "done"}},
+			end,
+		), false},
+		{"union(@inbox,@reading)", slices.Concat(start, startOr, inbox, reading, end, end), false},
+		{"@both", slices.Concat(start, start, start, inbox, reading, end, end, end), false},
+		{"@missing", nil, true},
+		{"@loop", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := query.ExpandSaved(query.Lex(tt.query), lookup)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error, got\n", query.TokensStringify(got))
+				}
+				return
+			} else if err != nil {
+				t.Fatal("Unexpected error:", err)
+			}
+
+			if !slices.EqualFunc(got, tt.want, Token.Equal) {
+				t.Error("Expanded tokens differ")
+				t.Log("Got\n", query.TokensStringify(got))
+				t.Log("Want\n", query.TokensStringify(tt.want))
+			}
+			if _, err := query.Parse(got); err != nil {
+				t.Error("Unexpected parse error:", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,328 @@
+package query
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jpappel/atlas/pkg/index"
+)
+
+// A step into a selected value, either a key of a mapping or an index of a list
+type selector struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// Comma separated jq-like paths into a document such as .title, .tags[0], .meta.project
+//
+// Documents have the fields of their json output, except meta which is a mapping
+// of their typed header fields. Header keys with many values are lists.
+type Selection struct {
+	exprs []string
+	paths [][]selector
+}
+
+// Writes the values selected from each document as tab separated rows
+// or as a json list of objects keyed by each expression
+type SelectOutput struct {
+	selection      Selection
+	asJson         bool
+	datetimeFormat string
+	listSeparator  string
+}
+
+var _ Outputer = &SelectOutput{}
+
+func ParseSelection(s string) (Selection, error) {
+	var sel Selection
+	rest := strings.TrimLeftFunc(s, unicode.IsSpace)
+	for {
+		expr, path, remaining, err := parseSelectPath(rest)
+		if err != nil {
+			return Selection{}, err
+		}
+		sel.exprs = append(sel.exprs, expr)
+		sel.paths = append(sel.paths, path)
+
+		rest = strings.TrimLeftFunc(remaining, unicode.IsSpace)
+		if rest == "" {
+			return sel, nil
+		} else if rest[0] != ',' {
+			return Selection{}, fmt.Errorf("%w: expected , after %s", ErrSelectSyntax, expr)
+		}
+		rest = strings.TrimLeftFunc(rest[1:], unicode.IsSpace)
+	}
+}
+
+func isSelectIdent(c byte) bool {
+	return c == '_' || c == '-' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// Parse a path from the start of s, returning the path's text and the unparsed remainder
+func parseSelectPath(s string) (string, []selector, string, error) {
+	if s == "" || s[0] != '.' {
+		return "", nil, "", fmt.Errorf("%w: expected a path starting with . at %q", ErrSelectSyntax, s)
+	}
+
+	var path []selector
+	ident := func(i int) int {
+		j := i
+		for j < len(s) && isSelectIdent(s[j]) {
+			j++
+		}
+		return j
+	}
+
+	// the first key may follow the leading .
+	i := ident(1)
+	if i > 1 {
+		path = append(path, selector{key: s[1:i]})
+	}
+	for i < len(s) {
+		switch s[i] {
+		case '.':
+			j := ident(i + 1)
+			if j == i+1 {
+				return "", nil, "", fmt.Errorf("%w: expected a key after . in %s", ErrSelectSyntax, s[:j])
+			}
+			path = append(path, selector{key: s[i+1 : j]})
+			i = j
+		case '[':
+			rest := strings.TrimLeftFunc(s[i+1:], unicode.IsSpace)
+			if strings.HasPrefix(rest, `"`) {
+				quoted, err := strconv.QuotedPrefix(rest)
+				if err != nil {
+					return "", nil, "", fmt.Errorf("%w: unterminated key in %s", ErrSelectSyntax, s)
+				}
+				key, _ := strconv.Unquote(quoted)
+				path = append(path, selector{key: key})
+				rest = strings.TrimLeftFunc(rest[len(quoted):], unicode.IsSpace)
+				if !strings.HasPrefix(rest, "]") {
+					return "", nil, "", fmt.Errorf("%w: expected ] after %s", ErrSelectSyntax, quoted)
+				}
+				i = len(s) - len(rest) + 1
+			} else {
+				end := strings.IndexByte(rest, ']')
+				if end < 0 {
+					return "", nil, "", fmt.Errorf("%w: unterminated [ in %s", ErrSelectSyntax, s)
+				}
+				n, err := strconv.Atoi(strings.TrimSpace(rest[:end]))
+				if err != nil {
+					return "", nil, "", fmt.Errorf("%w: invalid index %s", ErrSelectSyntax, rest[:end])
+				}
+				path = append(path, selector{index: n, isIndex: true})
+				i = len(s) - len(rest) + end + 1
+			}
+		default:
+			return s[:i], path, s[i:], nil
+		}
+	}
+
+	return s, path, "", nil
+}
+
+// Values selected from doc in expression order, nil when a path is missing
+func (sel Selection) Eval(doc *index.Document) []any {
+	root := selectRoot(doc)
+	values := make([]any, len(sel.paths))
+	for i, path := range sel.paths {
+		var v any = root
+		for _, step := range path {
+			if step.isIndex {
+				list, _ := v.([]any)
+				idx := step.index
+				if idx < 0 {
+					idx += len(list)
+				}
+				if idx < 0 || idx >= len(list) {
+					v = nil
+					break
+				}
+				v = list[idx]
+			} else {
+				m, _ := v.(map[string]any)
+				v = m[step.key]
+			}
+		}
+		values[i] = v
+	}
+	return values
+}
+
+// Document fields as generic values to select from
+func selectRoot(doc *index.Document) map[string]any {
+	strs := func(items []string) []any {
+		list := make([]any, len(items))
+		for i, item := range items {
+			list[i] = item
+		}
+		return list
+	}
+	emails := make(map[string]any, len(doc.Emails))
+	for author, email := range doc.Emails {
+		emails[author] = email
+	}
+
+	// nested keys are flattened into dotted keys, unflatten them
+	meta := make(map[string]any)
+	for _, field := range doc.MetaFields {
+		m := meta
+		keys := strings.Split(field.Key, ".")
+		for _, key := range keys[:len(keys)-1] {
+			next, ok := m[key].(map[string]any)
+			if !ok {
+				next = make(map[string]any)
+				m[key] = next
+			}
+			m = next
+		}
+		key := keys[len(keys)-1]
+		switch existing := m[key].(type) {
+		case nil:
+			m[key] = field.Value
+		case []any:
+			m[key] = append(existing, field.Value)
+		default:
+			m[key] = []any{existing, field.Value}
+		}
+	}
+
+	return map[string]any{
+		"path":      doc.Path,
+		"id":        doc.ID,
+		"title":     doc.Title,
+		"lang":      doc.Language,
+		"date":      doc.Date,
+		"filetime":  doc.FileTime,
+		"authors":   strs(doc.Authors),
+		"emails":    emails,
+		"tags":      strs(doc.Tags),
+		"links":     strs(doc.Links),
+		"citations": strs(doc.Citations),
+		"headings":  doc.Headings,
+		"meta":      meta,
+		"pinned":    doc.Pinned,
+		"database":  doc.Database,
+	}
+}
+
+func NewSelectOutput(sel Selection, asJson bool, datetimeFormat string, listSeparator string) SelectOutput {
+	return SelectOutput{sel, asJson, datetimeFormat, listSeparator}
+}
+
+func (o SelectOutput) OutputOne(doc *index.Document) (string, error) {
+	b := strings.Builder{}
+	if _, err := o.OutputOneTo(&b, doc); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func (o SelectOutput) OutputOneTo(w io.Writer, doc *index.Document) (int, error) {
+	b := &bytes.Buffer{}
+	if err := o.writeDoc(b, doc); err != nil {
+		return 0, err
+	}
+	return w.Write(b.Bytes())
+}
+
+func (o SelectOutput) Output(docs []*index.Document) (string, error) {
+	b := strings.Builder{}
+	if _, err := o.OutputTo(&b, docs); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func (o SelectOutput) OutputTo(w io.Writer, docs []*index.Document) (int, error) {
+	b := &bytes.Buffer{}
+	if o.asJson {
+		b.WriteByte('[')
+	}
+	for i, doc := range docs {
+		if o.asJson && i != 0 {
+			b.WriteByte(',')
+		}
+		if err := o.writeDoc(b, doc); err != nil {
+			return 0, err
+		}
+	}
+	if o.asJson {
+		b.WriteByte(']')
+	}
+	return w.Write(b.Bytes())
+}
+
+func (o SelectOutput) writeDoc(b *bytes.Buffer, doc *index.Document) error {
+	values := o.selection.Eval(doc)
+	if o.asJson {
+		// keep expression order rather than sorting keys like a map
+		b.WriteByte('{')
+		for i, v := range values {
+			if i != 0 {
+				b.WriteByte(',')
+			}
+			key, _ := json.Marshal(o.selection.exprs[i])
+			b.Write(key)
+			b.WriteByte(':')
+			value, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			b.Write(value)
+		}
+		b.WriteByte('}')
+		return nil
+	}
+
+	for i, v := range values {
+		if i != 0 {
+			b.WriteByte('\t')
+		}
+		s, err := o.format(v)
+		if err != nil {
+			return err
+		}
+		b.WriteString(tsvEscaper.Replace(s))
+	}
+	b.WriteByte('\n')
+	return nil
+}
+
+// Escape characters which would break tab separated rows
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+func (o SelectOutput) format(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case time.Time:
+		return v.Format(o.datetimeFormat), nil
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			s, err := o.format(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = s
+		}
+		return strings.Join(items, o.listSeparator), nil
+	default:
+		b, err := json.Marshal(v)
+		return string(b), err
+	}
+}
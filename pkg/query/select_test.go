@@ -0,0 +1,115 @@
+package query_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jpappel/atlas/pkg/index"
+	"github.com/jpappel/atlas/pkg/query"
+)
+
+func TestParseSelection(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr error
+	}{
+		{".title", nil},
+		{".", nil},
+		{".title, .tags[0],.meta.project", nil},
+		{`.meta["due date"], .tags[ -1 ]`, nil},
+		{"title", query.ErrSelectSyntax},
+		{".title .tags", query.ErrSelectSyntax},
+		{".meta.", query.ErrSelectSyntax},
+		{".tags[first]", query.ErrSelectSyntax},
+		{".tags[0", query.ErrSelectSyntax},
+		{`.meta["due date"`, query.ErrSelectSyntax},
+		{".title,", query.ErrSelectSyntax},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := query.ParseSelection(tt.expr)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ParseSelection() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSelectOutput(t *testing.T) {
+	docs := []*index.Document{
+		{
+			Path:     "/notes/a.md",
+			Title:    "Weekly\tnotes",
+			Date:     time.Date(2025, time.May, 1, 0, 0, 0, 0, time.UTC),
+			Tags:     []string{"work", "go"},
+			Headings: "# Agenda\n# Notes\n",
+			MetaFields: []index.MetaField{
+				{Key: "project", Value: "atlas"},
+				{Key: "priority", Value: float64(2)},
+				{Key: "draft", Value: false},
+				{Key: "refs", Value: "a"},
+				{Key: "refs", Value: "b"},
+				{Key: "owner.name", Value: "jp"},
+				{Key: "due date", Value: "soon"},
+			},
+		},
+		{Path: "/notes/b.md", Title: "Empty"},
+	}
+
+	tests := []struct {
+		name   string
+		expr   string
+		asJson bool
+		want   string
+	}{
+		{
+			"fields",
+			".path, .title, .date, .tags, .headings",
+			false,
+			"/notes/a.md\tWeekly\\tnotes\t2025-05-01\twork,go\t# Agenda\\n# Notes\\n\n" +
+				"/notes/b.md\tEmpty\t0001-01-01\t\t\n",
+		},
+		{
+			"indexes",
+			".tags[0], .tags[-1], .tags[2]",
+			false,
+			"work\tgo\t\n\t\t\n",
+		},
+		{
+			"typed meta",
+			`.meta.project, .meta.priority, .meta.draft, .meta.refs[1], .meta.owner.name, .meta["due date"]`,
+			false,
+			"atlas\t2\tfalse\tb\tjp\tsoon\n\t\t\t\t\t\n",
+		},
+		{
+			"nested meta",
+			".meta.owner",
+			false,
+			"{\"name\":\"jp\"}\n\n",
+		},
+		{
+			"json",
+			".title, .meta.priority, .meta.refs, .tags[5]",
+			true,
+			`[{".title":"Weekly\tnotes",".meta.priority":2,".meta.refs":["a","b"],".tags[5]":null},` +
+				`{".title":"Empty",".meta.priority":null,".meta.refs":null,".tags[5]":null}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sel, err := query.ParseSelection(tt.expr)
+			if err != nil {
+				t.Fatal("Unexpected error:", err)
+			}
+			o := query.NewSelectOutput(sel, tt.asJson, "2006-01-02", ",")
+			got, err := o.Output(docs)
+			if err != nil {
+				t.Fatal("Unexpected error:", err)
+			}
+			if got != tt.want {
+				t.Errorf("Output() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+var paramRegex = regexp.MustCompile(`^\$([1-9][0-9]*)$`)
+
+// A query whose values may be positional parameters $1, $2, ...
+// Arguments are bound to lexed value tokens so they cannot change the structure of the query.
+type Template struct {
+	tokens []Token
+	params [][]int // indices of the value tokens for each parameter
+}
+
+// Lex a template and check its parameters are numbered from $1 without gaps
+func NewTemplate(template string) (Template, error) {
+	t := Template{tokens: Lex(template)}
+
+	numValues := 0
+	for _, token := range t.tokens {
+		if token.Type.isValue() {
+			numValues++
+		}
+	}
+
+	for i, token := range t.tokens {
+		match := paramRegex.FindStringSubmatch(token.Value)
+		if match == nil {
+			continue
+		} else if !token.Type.isValue() {
+			return Template{}, fmt.Errorf("Parameter %s must be the value of a statement", token.Value)
+		}
+
+		n, err := strconv.Atoi(match[1])
+		if err != nil {
+			return Template{}, fmt.Errorf("Cannot parse parameter %s, %v", token.Value, err)
+		} else if n > numValues {
+			// a template with fewer values cannot number its parameters up to n without gaps
+			return Template{}, fmt.Errorf("Parameter %s exceeds the %d values of the template", token.Value, numValues)
+		}
+		for len(t.params) < n {
+			t.params = append(t.params, nil)
+		}
+		t.params[n-1] = append(t.params[n-1], i)
+	}
+
+	for i, indices := range t.params {
+		if len(indices) == 0 {
+			return Template{}, fmt.Errorf("Missing parameter $%d", i+1)
+		}
+	}
+
+	return t, nil
+}
+
+// Number of arguments needed to bind the template
+func (t Template) NumParams() int {
+	return len(t.params)
+}
+
+// Bind an argument to each parameter and parse the resulting query
+func (t Template) Bind(args ...string) (*Clause, error) {
+	if len(args) != len(t.params) {
+		return nil, fmt.Errorf("Template takes %d arguments, got %d", len(t.params), len(args))
+	}
+
+	tokens := make([]Token, len(t.tokens))
+	copy(tokens, t.tokens)
+	for i, indices := range t.params {
+		for _, idx := range indices {
+			// values follow a category and operator
+			tokens[idx] = tokenizeValue(args[i], tokens[idx-2].Type)
+		}
+	}
+
+	return Parse(tokens)
+}
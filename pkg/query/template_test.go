@@ -0,0 +1,105 @@
+package query_test
+
+import (
+	"testing"
+
+	"github.com/jpappel/atlas/pkg/query"
+)
+
+func TestTemplate_Bind(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		args     []string
+		want     string
+		wantErr  bool
+	}{
+		{"author and date", "a=$1 d>$2", []string{"Rob Pike", "2024-01-01"}, `a="Rob Pike" d>2024-01-01`, false},
+		{"repeated parameter", "(or T:$1 h:$1)", []string{"notes"}, "(or T:notes h:notes)", false},
+		{"numeric field", "m.rating>=$1", []string{"4"}, "m.rating>=4", false},
+		{"quoted parameter", `T="$1"`, []string{"plan 9"}, `T="plan 9"`, false},
+		{"too few arguments", "a=$1 T=$2", []string{"Rob Pike"}, "", true},
+		{"too many arguments", "a=$1", []string{"Rob Pike", "Ken Thompson"}, "", true},
+		{"invalid date", "d>$1", []string{"not a date"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := query.NewTemplate(tt.template)
+			if err != nil {
+				t.Fatal("Unexpected template error:", err)
+			}
+
+			got, err := tmpl.Bind(tt.args...)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error binding %v, got %s", tt.args, got)
+				}
+				return
+			} else if err != nil {
+				t.Fatal("Unexpected bind error:", err)
+			}
+
+			want, err := query.Parse(query.Lex(tt.want))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != want.String() {
+				t.Errorf("Bound %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestTemplate_BindValues(t *testing.T) {
+	tmpl, err := query.NewTemplate("T=$1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// arguments cannot add statements or clauses
+	arg := `x) (or a=y`
+	clause, err := tmpl.Bind(arg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clause.Statements) != 1 || len(clause.Clauses) != 0 {
+		t.Fatalf("Expected a single statement, got %s", clause)
+	}
+	if got := clause.Statements[0].Value; got.Compare(query.StringValue{S: arg}) != 0 {
+		t.Errorf("Bound value %v, want %q", got, arg)
+	}
+}
+
+func TestNewTemplate(t *testing.T) {
+	tests := []struct {
+		template   string
+		wantParams int
+		wantErr    bool
+	}{
+		{"a=$1 d>$2", 2, false},
+		{"T:notes", 0, false},
+		{"(or T=$1 a=$1)", 1, false},
+		{"T=$2", 0, true},
+		{"T=notes $1", 0, true},
+		{"t=$99999999999", 0, true},
+		{"t=$99999999999999999999", 0, true},
+		{"(and T=$1 a=$3 d>$2)", 3, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			tmpl, err := query.NewTemplate(tt.template)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error")
+				}
+				return
+			} else if err != nil {
+				t.Fatal("Unexpected error:", err)
+			}
+
+			if got := tmpl.NumParams(); got != tt.wantParams {
+				t.Errorf("NumParams() = %d, want %d", got, tt.wantParams)
+			}
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jpappel/atlas/pkg/data"
+	"github.com/jpappel/atlas/pkg/index"
+)
+
+// JSON-RPC 2.0 error codes
+const (
+	RPC_PARSE_ERROR      = -32700
+	RPC_INVALID_REQUEST  = -32600
+	RPC_METHOD_NOT_FOUND = -32601
+	RPC_INVALID_PARAMS   = -32602
+	RPC_INTERNAL_ERROR   = -32603
+)
+
+// Longest accepted request line
+const maxRPCRequestSize = 1 << 20
+
+// Serves JSON-RPC 2.0 requests, one per line, and writes a response line for
+// each request with an id. Requests are handled in the order they are read.
+//
+// Methods:
+//
+//	search      {query, sortBy, sortDesc, limit, offset} -> [document]
+//	getDocument {path} -> document
+//	tags        -> [{tag, count}]
+//	reindex     -> {crawled, filtered, parsed, errors}
+//
+// Changes made by reindex are POSTed to each of Webhooks.
+type RPCServer struct {
+	Db        *data.Query
+	Root      string // index root used by reindex
+	Filters   []index.DocFilter
+	ParseOpts index.ParseOpts
+	Workers   uint
+	Timeout   time.Duration // maximum duration of each request, 0 for no timeout
+	Webhooks  []string      // urls notified of index changes
+}
+
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.Code)
+}
+
+type rpcRequest struct {
+	Version string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	Version string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+type searchParams struct {
+	Query    string `json:"query"`
+	SortBy   string `json:"sortBy"`
+	SortDesc bool   `json:"sortDesc"`
+	Limit    int    `json:"limit"`
+	Offset   int    `json:"offset"`
+}
+
+type getDocumentParams struct {
+	Path string `json:"path"`
+}
+
+type ReindexResult struct {
+	Crawled  int    `json:"crawled"`
+	Filtered int    `json:"filtered"`
+	Parsed   int    `json:"parsed"`
+	Errors   uint64 `json:"errors"`
+}
+
+// Handle requests from r until it is exhausted or ctx is done
+func (s *RPCServer) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxRPCRequestSize)
+	enc := json.NewEncoder(w)
+
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+
+		resp, ok := s.handle(ctx, line)
+		if !ok {
+			continue
+		}
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// Handle a single request line, returns false for notifications
+func (s *RPCServer) handle(ctx context.Context, line []byte) (rpcResponse, bool) {
+	resp := rpcResponse{Version: "2.0", Id: json.RawMessage("null")}
+
+	var req rpcRequest
+	if err := json.Unmarshal(line, &req); err != nil {
+		code := RPC_PARSE_ERROR
+		if line[0] == '[' {
+			code = RPC_INVALID_REQUEST
+			err = errors.New("Batch requests are not supported")
+		}
+		resp.Error = &RPCError{code, err.Error()}
+		return resp, true
+	}
+	if req.Id != nil {
+		resp.Id = req.Id
+	}
+	if req.Version != "2.0" || req.Method == "" {
+		resp.Error = &RPCError{RPC_INVALID_REQUEST, "Expected a jsonrpc 2.0 request with a method"}
+		return resp, true
+	}
+
+	slog.Debug("Recieved rpc request", slog.String("method", req.Method))
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+
+	var result any
+	var err error
+	switch req.Method {
+	case "search":
+		params := searchParams{}
+		if err = decodeParams(req.Params, &params); err == nil {
+			result, err = s.search(ctx, params)
+		}
+	case "getDocument":
+		params := getDocumentParams{}
+		if err = decodeParams(req.Params, &params); err == nil {
+			result, err = s.getDocument(ctx, params)
+		}
+	case "tags":
+		result, err = s.Db.Tags(ctx)
+	case "reindex":
+		result, err = s.reindex(ctx)
+	default:
+		err = &RPCError{RPC_METHOD_NOT_FOUND, "Unrecognized method: " + req.Method}
+	}
+
+	// notifications never recieve a response
+	if req.Id == nil {
+		return resp, false
+	}
+
+	if err != nil {
+		rpcErr, ok := err.(*RPCError)
+		if !ok {
+			slog.Warn("Error handling rpc request",
+				slog.String("method", req.Method), slog.String("err", err.Error()),
+			)
+			rpcErr = &RPCError{RPC_INTERNAL_ERROR, err.Error()}
+		}
+		resp.Error = rpcErr
+		return resp, true
+	}
+
+	if resp.Result, err = json.Marshal(result); err != nil {
+		resp.Error = &RPCError{RPC_INTERNAL_ERROR, err.Error()}
+	}
+	return resp, true
+}
+
+func decodeParams(raw json.RawMessage, v any) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return &RPCError{RPC_INVALID_PARAMS, err.Error()}
+	}
+	return nil
+}
+
+func (s *RPCServer) search(ctx context.Context, params searchParams) ([]*index.Document, error) {
+	if params.Query == "" {
+		return nil, &RPCError{RPC_INVALID_PARAMS, "Missing query"}
+	}
+	artifact, err := s.Db.Compile(ctx, params.Query, 0, s.Workers)
+	if err != nil {
+		return nil, &RPCError{RPC_INVALID_PARAMS, err.Error()}
+	}
+
+	docCmp, ok := index.NewDocCmp(params.SortBy, params.SortDesc)
+	if params.SortBy != "" && !ok {
+		return nil, &RPCError{RPC_INVALID_PARAMS, "Cannot sort by " + params.SortBy}
+	} else if !ok {
+		docCmp, _ = index.NewDocCmp("path", false)
+	}
+	artifact.SortBy = params.SortBy
+	artifact.SortDesc = params.SortDesc
+	artifact.Limit = params.Limit
+	artifact.Offset = params.Offset
+
+	pathDocs, err := s.Db.Execute(ctx, artifact)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]*index.Document, 0, len(pathDocs))
+	for _, doc := range pathDocs {
+		docs = append(docs, doc)
+	}
+	slices.SortFunc(docs, docCmp)
+
+	return docs, nil
+}
+
+func (s *RPCServer) getDocument(ctx context.Context, params getDocumentParams) (*index.Document, error) {
+	if params.Path == "" {
+		return nil, &RPCError{RPC_INVALID_PARAMS, "Missing path"}
+	}
+
+	doc, err := s.Db.GetDocument(ctx, params.Path)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &RPCError{RPC_INVALID_PARAMS, "No document with path " + params.Path}
+	}
+	return doc, err
+}
+
+func (s *RPCServer) reindex(ctx context.Context) (ReindexResult, error) {
+	// traversing panics on a missing root
+	if _, err := os.Stat(s.Root); err != nil {
+		return ReindexResult{}, &RPCError{RPC_INTERNAL_ERROR, "Cannot read index root: " + err.Error()}
+	}
+	idx := index.Index{Root: s.Root, Filters: s.Filters}
+
+	traversedFiles := idx.Traverse(s.Workers, s.ParseOpts.IgnoreHidden)
+	filteredFiles := idx.Filter(traversedFiles, s.Workers)
+	var errCnt uint64
+	idx.Documents, errCnt = index.ParseDocs(filteredFiles, s.Workers, s.ParseOpts)
+
+	result := ReindexResult{
+		Crawled:  len(traversedFiles),
+		Filtered: len(filteredFiles),
+		Parsed:   len(idx.Documents),
+		Errors:   errCnt,
+	}
+
+	var prev *index.Index
+	var next index.Index
+	if len(s.Webhooks) > 0 {
+		var err error
+		if prev, err = s.Db.Get(ctx, s.Root); err != nil {
+			return result, err
+		}
+		if next, err = s.Db.ResolveAliases(ctx, idx); err != nil {
+			return result, err
+		}
+	}
+
+	if err := s.Db.Update(ctx, idx); err != nil {
+		return result, err
+	}
+
+	// the index is updated regardless of whether webhooks are delivered
+	if prev != nil {
+		if diff := prev.Diff(next); !diff.Empty() {
+			if err := NotifyWebhooks(ctx, s.Webhooks, NewWebhookPayload(s.Root, diff)); err != nil {
+				slog.Error("Error notifying webhooks", slog.String("err", err.Error()))
+			}
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,92 @@
+package server_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jpappel/atlas/pkg/data"
+	"github.com/jpappel/atlas/pkg/index"
+	"github.com/jpappel/atlas/pkg/server"
+)
+
+func TestRPCServer_Serve(t *testing.T) {
+	db := data.NewMemQuery("test")
+	defer db.Close()
+	docs := map[string]*index.Document{
+		"/a.md": {Path: "/a.md", Title: "Meeting notes", FileTime: time.Unix(1, 0), Tags: []string{"work"}},
+		"/b.md": {Path: "/b.md", Title: "Reading notes", FileTime: time.Unix(2, 0), Tags: []string{"books", "work"}},
+	}
+	if err := db.Put(t.Context(), index.Index{Documents: docs}); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := strings.Join([]string{
+		`{"jsonrpc": "2.0", "id": 1, "method": "search", "params": {"query": "T:notes", "sortBy": "path", "sortDesc": true}}`,
+		`{"jsonrpc": "2.0", "id": "two", "method": "getDocument", "params": {"path": "/a.md"}}`,
+		`{"jsonrpc": "2.0", "method": "tags"}`,
+		`{"jsonrpc": "2.0", "id": 3, "method": "tags"}`,
+		`{"jsonrpc": "2.0", "id": 4, "method": "getDocument", "params": {"path": "/missing.md"}}`,
+		`{"jsonrpc": "2.0", "id": 5, "method": "frobnicate"}`,
+		`{"jsonrpc": "2.0", "id": 6, "method": "search", "params": "T:notes"}`,
+		`{"jsonrpc": "2.0", "id": 7, "method": "reindex"}`,
+		`{not json`,
+	}, "\n")
+
+	s := server.RPCServer{Db: db, Root: filepath.Join(t.TempDir(), "missing"), Workers: 1}
+	out := &bytes.Buffer{}
+	if err := s.Serve(t.Context(), strings.NewReader(requests), out); err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+
+	type response struct {
+		Id     json.RawMessage
+		Result json.RawMessage
+		Error  *server.RPCError
+	}
+	var responses []response
+	dec := json.NewDecoder(out)
+	for dec.More() {
+		var r response
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal("Invalid response:", err)
+		}
+		responses = append(responses, r)
+	}
+
+	want := []struct {
+		id     string
+		result string
+		code   int
+	}{
+		{`1`, `[{"path":"/b.md"`, 0},
+		{`"two"`, `{"path":"/a.md","title":"Meeting notes"`, 0},
+		{`3`, `[{"tag":"books","count":1},{"tag":"work","count":2}]`, 0},
+		{`4`, ``, server.RPC_INVALID_PARAMS},
+		{`5`, ``, server.RPC_METHOD_NOT_FOUND},
+		{`6`, ``, server.RPC_INVALID_PARAMS},
+		{`7`, ``, server.RPC_INTERNAL_ERROR},
+		{`null`, ``, server.RPC_PARSE_ERROR},
+	}
+	if len(responses) != len(want) {
+		t.Fatalf("Got %d responses, want %d:\n%v", len(responses), len(want), responses)
+	}
+	for i, w := range want {
+		got := responses[i]
+		if string(got.Id) != w.id {
+			t.Errorf("Response %d: id %s, want %s", i, got.Id, w.id)
+		}
+		if w.code != 0 {
+			if got.Error == nil || got.Error.Code != w.code {
+				t.Errorf("Response %d: error %v, want code %d", i, got.Error, w.code)
+			}
+		} else if got.Error != nil {
+			t.Errorf("Response %d: unexpected error %v", i, got.Error)
+		} else if !strings.HasPrefix(string(got.Result), w.result) {
+			t.Errorf("Response %d: result %s, want prefix %s", i, got.Result, w.result)
+		}
+	}
+}
@@ -35,6 +35,7 @@ Try POSTing a query to <pre>/search</pre></p>
 <li>filetime</li>
 <li>meta</li>
 </ul>
+Separate fields with commas, like <pre>date,title</pre>, to break ties.
 You can change the order using <pre>sortOrder</pre> with <pre>asc</pre> or <pre>desc</pre>
 </p>
 <form action="/search" method="post">
@@ -69,7 +70,7 @@ func NewMux(db *data.Query) *http.ServeMux {
 			slog.Error("Error reading request body", slog.String("err", err.Error()))
 			return
 		}
-		artifact, err := query.Compile(b.String(), 0, 1)
+		artifact, err := db.Compile(r.Context(), b.String(), 0, 1)
 		if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
 			w.Write([]byte(err.Error()))
@@ -102,6 +103,8 @@ func NewMux(db *data.Query) *http.ServeMux {
 			if ok {
 				slices.SortFunc(docs, docCmp)
 			}
+		} else {
+			slices.SortStableFunc(docs, index.PinnedCmp)
 		}
 
 		if !maxFileTime.IsZero() {
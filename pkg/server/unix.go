@@ -122,16 +122,18 @@ func (s *UnixServer) handleConn(conn *net.UnixConn, id uint64) {
 			slog.String("query", queryTxt),
 		)
 
+		ctx, cancel := context.WithTimeout(context.Background(), 400*time.Millisecond)
+
 		// TODO: cache compilation artifacts
-		artifact, err := query.Compile(queryTxt, 0, s.WorkersPerConn)
+		artifact, err := s.Db.Compile(ctx, queryTxt, 0, s.WorkersPerConn)
 		if err != nil {
+			cancel()
 			slog.Warn("Failed to compile query",
 				slog.String("err", err.Error()))
 			s.writeError(conn, "query compilation error")
 			break
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 400*time.Millisecond)
 		docs, err := s.Db.Execute(ctx, artifact)
 		if err != nil {
 			slog.Warn("Failed to execute query",
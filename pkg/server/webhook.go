@@ -0,0 +1,87 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/jpappel/atlas/pkg/index"
+)
+
+// Maximum duration of each webhook delivery
+const webhookTimeout = 10 * time.Second
+
+// Changes to an index, POSTed as JSON to webhooks after an update
+type WebhookPayload struct {
+	Root    string   `json:"root"`
+	Added   []string `json:"added"`
+	Updated []string `json:"updated"`
+	Removed []string `json:"removed"`
+}
+
+// Create a payload from the changes to the index at root with paths in sorted order
+func NewWebhookPayload(root string, diff index.Diff) WebhookPayload {
+	payload := WebhookPayload{
+		Root:    root,
+		Added:   slices.Sorted(slices.Values(diff.Added)),
+		Updated: diff.ModifiedPaths(),
+		Removed: slices.Sorted(slices.Values(diff.Removed)),
+	}
+	// encode empty lists rather than null
+	if payload.Added == nil {
+		payload.Added = []string{}
+	}
+	if payload.Updated == nil {
+		payload.Updated = []string{}
+	}
+	if payload.Removed == nil {
+		payload.Removed = []string{}
+	}
+
+	return payload
+}
+
+// POST payload to each url. Every url is tried, failed deliveries are joined
+// into the returned error.
+func NotifyWebhooks(ctx context.Context, urls []string, payload WebhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	errs := make([]error, 0)
+	for _, url := range urls {
+		if err := notifyWebhook(ctx, url, body); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func notifyWebhook(ctx context.Context, url string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("Webhook %s: %w", url, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Webhook %s: %w", url, err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Webhook %s responded with %s", url, resp.Status)
+	}
+	return nil
+}
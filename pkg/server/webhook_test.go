@@ -0,0 +1,146 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jpappel/atlas/pkg/data"
+	"github.com/jpappel/atlas/pkg/index"
+	"github.com/jpappel/atlas/pkg/server"
+)
+
+// Start a server recording the payloads POSTed to it
+func newWebhookRecorder(t *testing.T) (*httptest.Server, func() []server.WebhookPayload) {
+	t.Helper()
+	mu := sync.Mutex{}
+	payloads := make([]server.WebhookPayload, 0)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload server.WebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error("Invalid payload:", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		payloads = append(payloads, payload)
+		mu.Unlock()
+	}))
+	t.Cleanup(ts.Close)
+
+	return ts, func() []server.WebhookPayload {
+		mu.Lock()
+		defer mu.Unlock()
+		return payloads
+	}
+}
+
+func TestNotifyWebhooks(t *testing.T) {
+	ts, received := newWebhookRecorder(t)
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	diff := index.Diff{
+		Added:    []string{"/notes/c.md", "/notes/a.md"},
+		Modified: map[string][]string{"/notes/b.md": {"title"}},
+	}
+	payload := server.NewWebhookPayload("/notes", diff)
+	want := server.WebhookPayload{
+		Root:    "/notes",
+		Added:   []string{"/notes/a.md", "/notes/c.md"},
+		Updated: []string{"/notes/b.md"},
+		Removed: []string{},
+	}
+	if !reflect.DeepEqual(payload, want) {
+		t.Fatalf("NewWebhookPayload() = %+v, want %+v", payload, want)
+	}
+
+	err := server.NotifyWebhooks(t.Context(), []string{failing.URL, ts.URL}, payload)
+	if err == nil || !strings.Contains(err.Error(), failing.URL) {
+		t.Errorf("Expected error for %s, got %v", failing.URL, err)
+	}
+
+	// a failed delivery does not stop later ones
+	got := received()
+	if len(got) != 1 || !reflect.DeepEqual(got[0], want) {
+		t.Errorf("Received %+v, want %+v", got, want)
+	}
+}
+
+func TestRPCServer_ReindexWebhooks(t *testing.T) {
+	ts, received := newWebhookRecorder(t)
+	root := t.TempDir()
+	writeNote := func(name string, title string) {
+		t.Helper()
+		content := "---\ntitle: " + title + "\n---\n"
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeNote("a.md", "A")
+	writeNote("b.md", "B")
+
+	db := data.NewMemQuery("test")
+	defer db.Close()
+	s := server.RPCServer{
+		Db:       db,
+		Root:     root,
+		Filters:  index.DefaultFilters(),
+		Workers:  1,
+		Webhooks: []string{ts.URL},
+	}
+
+	reindex := func() {
+		t.Helper()
+		out := &strings.Builder{}
+		request := `{"jsonrpc": "2.0", "id": 1, "method": "reindex"}`
+		if err := s.Serve(t.Context(), strings.NewReader(request), out); err != nil {
+			t.Fatal(err)
+		} else if strings.Contains(out.String(), `"error"`) {
+			t.Fatal("Unexpected error response:", out.String())
+		}
+	}
+
+	reindex()
+	// unchanged indexes are not reported
+	reindex()
+
+	if err := os.Remove(filepath.Join(root, "a.md")); err != nil {
+		t.Fatal(err)
+	}
+	writeNote("b.md", "B2")
+	writeNote("c.md", "C")
+	// filetimes are compared in seconds
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(root, "b.md"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	reindex()
+
+	want := []server.WebhookPayload{
+		{
+			Root:    root,
+			Added:   []string{filepath.Join(root, "a.md"), filepath.Join(root, "b.md")},
+			Updated: []string{},
+			Removed: []string{},
+		},
+		{
+			Root:    root,
+			Added:   []string{filepath.Join(root, "c.md")},
+			Updated: []string{filepath.Join(root, "b.md")},
+			Removed: []string{filepath.Join(root, "a.md")},
+		},
+	}
+	if got := received(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Received %+v, want %+v", got, want)
+	}
+}
@@ -9,7 +9,9 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/jpappel/atlas/pkg/data"
 	"github.com/jpappel/atlas/pkg/index"
@@ -30,8 +32,10 @@ type keywords struct {
 type Interpreter struct {
 	State    State
 	Workers  uint
+	Timeout  time.Duration // maximum duration of query execution, 0 for no timeout
 	env      map[string]string
 	term     *term.Terminal
+	pager    *Pager // pages execute and print output in interactive mode
 	keywords keywords
 	querier  *data.Query
 }
@@ -177,8 +181,8 @@ func (inter *Interpreter) Eval(w io.Writer, tokens []IToken) (bool, error) {
 				suggestion, goodSuggestion := util.Nearest(
 					t.Text,
 					inter.keywords.commands,
-					util.LevensteinDistance,
-					min(len(t.Text), 4),
+					util.FoldedDistance,
+					min(utf8.RuneCountInString(t.Text), 4),
 				)
 				if goodSuggestion {
 					fmt.Fprintf(b, ": Did you mean '%s'?", suggestion)
@@ -485,8 +489,8 @@ out:
 					suggestion, ok := util.Nearest(
 						optName,
 						inter.keywords.optimizations,
-						util.LevensteinDistance,
-						min(len(optName), 4),
+						util.FoldedDistance,
+						min(utf8.RuneCountInString(optName), 4),
 					)
 					suggestionTxt := ""
 					if ok {
@@ -534,7 +538,15 @@ out:
 				return true, errors.New("Type corruption during compilation, expected query.CompilationArtifact")
 			}
 
-			resultsMap, err := inter.querier.Execute(context.Background(), artifact)
+			var ctx context.Context
+			var cancel context.CancelFunc
+			if inter.Timeout > 0 {
+				ctx, cancel = context.WithTimeout(context.Background(), inter.Timeout)
+			} else {
+				ctx, cancel = context.WithCancel(context.Background())
+			}
+			resultsMap, err := inter.querier.Execute(ctx, artifact)
+			cancel()
 			if err != nil {
 				return false, fmt.Errorf("Error occured while excuting query: %s", err)
 			}
@@ -552,8 +564,8 @@ out:
 				suggestion, ok := util.Nearest(
 					t.Text,
 					inter.keywords.variables,
-					util.LevensteinDistance,
-					min(len(t.Text), 4),
+					util.FoldedDistance,
+					min(utf8.RuneCountInString(t.Text), 4),
 				)
 				suggestionTxt := ""
 				if ok {
@@ -843,6 +855,8 @@ func PrintHelp(w io.Writer) {
 	fmt.Fprintln(w, "compile (clause)                      - compile clause into query")
 	fmt.Fprintln(w, "execute (artifact)                    - excute the compiled query against the connected database")
 	fmt.Fprintln(w, "query (query_string)                  - alias for 'execute compile optimize 0 parse tokenize <query_string>'")
+	fmt.Fprintln(w, "\nOutput of execute and print longer than the terminal is paged")
+	fmt.Fprintln(w, "space shows the next page, enter the next line, and q stops paging")
 	fmt.Fprintln(w, "\nBare commands which return a value assign to an implicit variable _")
 	fmt.Fprintln(w, "Basic integer arrithmetic (+ - * /) is supported in polish notation")
 }
@@ -0,0 +1,99 @@
+package shell
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+const pagerPrompt = "\033[7m-- More -- (space: next page, enter: next line, q: quit)\033[0m"
+
+// Pages output longer than a terminal screen
+type Pager struct {
+	w      io.Writer
+	r      io.Reader // raw terminal input
+	width  int
+	height int
+}
+
+// Create a pager for a terminal of the given size which reads keys from r
+func NewPager(w io.Writer, r io.Reader, width, height int) *Pager {
+	return &Pager{w: w, r: r, width: width, height: height}
+}
+
+// Update the terminal size used for later pages
+func (p *Pager) Resize(width, height int) {
+	p.width = width
+	p.height = height
+}
+
+// Write out a screen at a time, waiting for a key between screens.
+// Space shows the next screen, enter shows the next line, and q or ctrl-c stops paging.
+func (p *Pager) Page(out string) error {
+	lines := strings.SplitAfter(out, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	// leave a row for the prompt
+	screen := p.height - 1
+	rows := 0
+	for _, line := range lines {
+		rows += p.rows(line)
+	}
+	if screen < 1 || rows <= screen {
+		_, err := io.WriteString(p.w, out)
+		return err
+	}
+
+	key := make([]byte, 1)
+	avail := screen
+	for len(lines) > 0 {
+		// lines which do not fit wait for the next screen, at least one is always shown
+		for first := true; len(lines) > 0 && (first || p.rows(lines[0]) <= avail); first = false {
+			if _, err := io.WriteString(p.w, lines[0]); err != nil {
+				return err
+			}
+			avail -= p.rows(lines[0])
+			lines = lines[1:]
+		}
+		if len(lines) == 0 {
+			break
+		}
+
+		if _, err := io.WriteString(p.w, pagerPrompt); err != nil {
+			return err
+		}
+		avail = 0
+		for avail == 0 {
+			if _, err := p.r.Read(key); err != nil {
+				return err
+			}
+			switch key[0] {
+			case ' ':
+				avail = screen
+			case '\r', '\n':
+				avail = 1
+			case 'q', 'Q', 3:
+				avail = -1
+			}
+		}
+		// clear the prompt
+		if _, err := io.WriteString(p.w, "\r\033[K"); err != nil {
+			return err
+		} else if avail < 0 {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// Number of terminal rows a line wraps onto
+func (p *Pager) rows(line string) int {
+	n := utf8.RuneCountInString(strings.TrimRight(line, "\r\n"))
+	if p.width <= 0 || n == 0 {
+		return 1
+	}
+	return (n + p.width - 1) / p.width
+}
@@ -0,0 +1,81 @@
+package shell_test
+
+import (
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/jpappel/atlas/pkg/shell"
+)
+
+// Matches the pager's prompt along with the sequence clearing it
+var promptPattern = regexp.MustCompile("\033\\[7m.*?\033\\[0m\r\033\\[K")
+
+func TestPager_Page(t *testing.T) {
+	lines := "1\n2\n3\n4\n5\n6\n7\n"
+	tests := []struct {
+		name   string
+		width  int
+		height int
+		out    string
+		keys   string
+		want   string
+	}{
+		{"shorter than screen", 80, 8, lines, "", lines},
+		{"exactly one screen", 80, 8, "1\n2\n3\n4\n5\n6\n7", "", "1\n2\n3\n4\n5\n6\n7"},
+		{"height one", 80, 1, lines, "", lines},
+		{"height zero", 80, 0, lines, "", lines},
+		{"space", 80, 4, lines, "  ", "1\n2\n3\n|4\n5\n6\n|7\n"},
+		{"enter", 80, 4, lines, "\r\n\r\r", "1\n2\n3\n|4\n|5\n|6\n|7\n"},
+		{"quit", 80, 4, lines, "q", "1\n2\n3\n|"},
+		{"ctrl-c", 80, 4, lines, "\x03", "1\n2\n3\n|"},
+		{"ignored keys", 80, 4, lines, "xj q", "1\n2\n3\n|4\n5\n6\n|"},
+		{"wrapped rows", 4, 4, "abcdefgh\nab\ncd\nef\n", " ", "abcdefgh\nab\n|cd\nef\n"},
+		{"wrapped unicode", 4, 4, "ééééé\nab\ncd\nef\n", " ", "ééééé\nab\n|cd\nef\n"},
+		{"line taller than screen", 4, 4, "ab\nabcdefghijklmnop\ncd\n", "  ", "ab\n|abcdefghijklmnop\n|cd\n"},
+		{"wrapped fits", 4, 4, "abcdefgh\nab\n", "", "abcdefgh\nab\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &strings.Builder{}
+			p := shell.NewPager(b, strings.NewReader(tt.keys), tt.width, tt.height)
+			if err := p.Page(tt.out); err != nil {
+				t.Fatal("Unexpected error:", err)
+			}
+			if got := promptPattern.ReplaceAllString(b.String(), "|"); got != tt.want {
+				t.Errorf("Page() wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPager_PageInputClosed(t *testing.T) {
+	p := shell.NewPager(io.Discard, strings.NewReader(""), 80, 4)
+	if err := p.Page("1\n2\n3\n4\n"); !errors.Is(err, io.EOF) {
+		t.Errorf("Page() error = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestPager_Resize(t *testing.T) {
+	b := &strings.Builder{}
+	p := shell.NewPager(b, strings.NewReader(""), 80, 4)
+	p.Resize(80, 8)
+	if err := p.Page("1\n2\n3\n4\n5\n"); err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	if got := b.String(); got != "1\n2\n3\n4\n5\n" {
+		t.Errorf("Page() wrote %q after resizing, want the output unpaged", got)
+	}
+
+	b.Reset()
+	p = shell.NewPager(b, strings.NewReader("q"), 4, 8)
+	p.Resize(2, 8)
+	if err := p.Page("abcdefgh\nabcdefgh\n"); err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	if got := promptPattern.ReplaceAllString(b.String(), "|"); got != "abcdefgh\n|" {
+		t.Errorf("Page() wrote %q after narrowing, want %q", got, "abcdefgh\n|")
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"slices"
+	"strings"
 
 	"golang.org/x/term"
 )
@@ -27,6 +29,13 @@ func (inter *Interpreter) runNonInteractive() error {
 	return io.EOF
 }
 
+// Output of execute and print can be longer than the terminal
+func (inter *Interpreter) shouldPage(tokens []IToken) bool {
+	return inter.pager != nil && slices.ContainsFunc(tokens, func(t IToken) bool {
+		return t.Type == ITOK_CMD_EXECUTE || t.Type == ITOK_CMD_PRINT
+	})
+}
+
 func (inter *Interpreter) Run() error {
 	if !term.IsTerminal(int(os.Stdin.Fd())) {
 		return inter.runNonInteractive()
@@ -48,6 +57,7 @@ func (inter *Interpreter) Run() error {
 	if err := inter.term.SetSize(width, height); err != nil {
 		panic(err)
 	}
+	inter.pager = NewPager(inter.term, os.Stdin, width, height)
 	inter.term.SetPrompt(
 		string(inter.term.Escape.Yellow) + "atlasi> " +
 			string(inter.term.Escape.Reset),
@@ -58,8 +68,22 @@ func (inter *Interpreter) Run() error {
 		if err != nil {
 			return err
 		}
+		// the terminal may have been resized since the last line
+		if width, height, err := term.GetSize(int(os.Stdin.Fd())); err == nil {
+			inter.term.SetSize(width, height)
+			inter.pager.Resize(width, height)
+		}
 		tokens := inter.Tokenize(line)
-		fatal, err := inter.Eval(inter.term, tokens)
+		var fatal bool
+		if inter.shouldPage(tokens) {
+			b := &strings.Builder{}
+			fatal, err = inter.Eval(b, tokens)
+			if pageErr := inter.pager.Page(b.String()); pageErr != nil {
+				return pageErr
+			}
+		} else {
+			fatal, err = inter.Eval(inter.term, tokens)
+		}
 		if fatal {
 			return err
 		} else if err != nil {
@@ -0,0 +1,111 @@
+package util
+
+// A growable last in first out stack
+type Stack[T any] struct {
+	items []T
+}
+
+func NewStack[T any](capacity int) *Stack[T] {
+	return &Stack[T]{items: make([]T, 0, capacity)}
+}
+
+func (s *Stack[T]) Len() int {
+	return len(s.items)
+}
+
+func (s *Stack[T]) Push(items ...T) {
+	s.items = append(s.items, items...)
+}
+
+// Remove and return the top item, returns false if the stack is empty
+func (s *Stack[T]) Pop() (T, bool) {
+	var zero T
+	if len(s.items) == 0 {
+		return zero, false
+	}
+	top := len(s.items) - 1
+	item := s.items[top]
+	s.items[top] = zero
+	s.items = s.items[:top]
+	return item, true
+}
+
+// Return the top item without removing it, returns false if the stack is empty
+func (s *Stack[T]) Peek() (T, bool) {
+	if len(s.items) == 0 {
+		var zero T
+		return zero, false
+	}
+	return s.items[len(s.items)-1], true
+}
+
+// A growable double ended queue backed by a ring buffer
+type Deque[T any] struct {
+	buf  []T
+	head int // index of the first item
+	size int
+}
+
+func NewDeque[T any](capacity int) *Deque[T] {
+	return &Deque[T]{buf: make([]T, max(capacity, 1))}
+}
+
+func (d *Deque[T]) Len() int {
+	return d.size
+}
+
+// double the capacity, moving items to the start of the buffer
+func (d *Deque[T]) grow() {
+	buf := make([]T, max(2*len(d.buf), 1))
+	n := copy(buf, d.buf[d.head:min(d.head+d.size, len(d.buf))])
+	copy(buf[n:], d.buf[:d.size-n])
+	d.buf = buf
+	d.head = 0
+}
+
+func (d *Deque[T]) PushBack(items ...T) {
+	for _, item := range items {
+		if d.size == len(d.buf) {
+			d.grow()
+		}
+		d.buf[(d.head+d.size)%len(d.buf)] = item
+		d.size++
+	}
+}
+
+func (d *Deque[T]) PushFront(items ...T) {
+	for _, item := range items {
+		if d.size == len(d.buf) {
+			d.grow()
+		}
+		d.head = (d.head - 1 + len(d.buf)) % len(d.buf)
+		d.buf[d.head] = item
+		d.size++
+	}
+}
+
+// Remove and return the first item, returns false if the deque is empty
+func (d *Deque[T]) PopFront() (T, bool) {
+	var zero T
+	if d.size == 0 {
+		return zero, false
+	}
+	item := d.buf[d.head]
+	d.buf[d.head] = zero
+	d.head = (d.head + 1) % len(d.buf)
+	d.size--
+	return item, true
+}
+
+// Remove and return the last item, returns false if the deque is empty
+func (d *Deque[T]) PopBack() (T, bool) {
+	var zero T
+	if d.size == 0 {
+		return zero, false
+	}
+	tail := (d.head + d.size - 1) % len(d.buf)
+	item := d.buf[tail]
+	d.buf[tail] = zero
+	d.size--
+	return item, true
+}
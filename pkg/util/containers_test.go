@@ -0,0 +1,77 @@
+package util_test
+
+import (
+	"testing"
+
+	"github.com/jpappel/atlas/pkg/util"
+)
+
+func TestStack(t *testing.T) {
+	s := util.NewStack[int](0)
+	if _, ok := s.Pop(); ok {
+		t.Fatal("Expected pop from empty stack to fail")
+	}
+
+	s.Push(1, 2)
+	s.Push(3)
+	if top, ok := s.Peek(); !ok || top != 3 {
+		t.Errorf("Peek() = %d, %v, want 3, true", top, ok)
+	}
+	for _, want := range []int{3, 2, 1} {
+		if got, ok := s.Pop(); !ok || got != want {
+			t.Errorf("Pop() = %d, %v, want %d, true", got, ok, want)
+		}
+	}
+	if s.Len() != 0 {
+		t.Errorf("Expected empty stack, got length %d", s.Len())
+	}
+}
+
+func TestDeque(t *testing.T) {
+	d := util.NewDeque[int](2)
+	if _, ok := d.PopFront(); ok {
+		t.Fatal("Expected pop from empty deque to fail")
+	}
+
+	// wrap around the ring buffer before growing
+	d.PushBack(1, 2)
+	d.PopFront()
+	d.PushBack(3)
+	d.PushFront(0)
+	d.PushBack(4, 5)
+	d.PushFront(-1)
+
+	want := []int{-1, 0, 2, 3, 4, 5}
+	if d.Len() != len(want) {
+		t.Fatalf("Len() = %d, want %d", d.Len(), len(want))
+	}
+	if got, ok := d.PopBack(); !ok || got != 5 {
+		t.Errorf("PopBack() = %d, %v, want 5, true", got, ok)
+	}
+	for _, w := range want[:len(want)-1] {
+		if got, ok := d.PopFront(); !ok || got != w {
+			t.Errorf("PopFront() = %d, %v, want %d, true", got, ok, w)
+		}
+	}
+	if _, ok := d.PopBack(); ok {
+		t.Error("Expected pop from emptied deque to fail")
+	}
+}
+
+func TestDeque_Grow(t *testing.T) {
+	d := util.NewDeque[int](0)
+	for i := range 1000 {
+		d.PushBack(i)
+		if i%3 == 0 {
+			d.PopFront()
+		}
+	}
+	prev := -1
+	for d.Len() > 0 {
+		got, _ := d.PopFront()
+		if got <= prev {
+			t.Fatalf("Deque out of order: %d after %d", got, prev)
+		}
+		prev = got
+	}
+}
@@ -0,0 +1,105 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// A log file which is rotated once it grows past MaxSize bytes.
+// Rotated files are renamed to path.1, path.2, ... with at most Retain kept.
+type LogFile struct {
+	Path    string
+	MaxSize int64 // size in bytes before rotating, <=0 disables rotation
+	Retain  int   // number of rotated files to keep
+	mu      sync.Mutex
+	f       *os.File
+	size    int64
+}
+
+// Open a log file, appending to an existing file rather than truncating it if append is set
+func OpenLogFile(path string, append bool, maxSize int64, retain int) (*LogFile, error) {
+	l := &LogFile{Path: path, MaxSize: maxSize, Retain: retain}
+	flags := os.O_WRONLY | os.O_CREATE
+	if append {
+		flags |= os.O_APPEND
+	} else {
+		flags |= os.O_TRUNC
+	}
+
+	if err := l.open(flags); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *LogFile) open(flags int) error {
+	f, err := os.OpenFile(l.Path, flags, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	l.f = f
+	l.size = info.Size()
+	return nil
+}
+
+func (l *LogFile) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.MaxSize > 0 && l.size > 0 && l.size+int64(len(p)) > l.MaxSize {
+		if err := l.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := l.f.Write(p)
+	l.size += int64(n)
+	return n, err
+}
+
+// shift rotated files up by one, dropping those past Retain.
+// The current file is closed once its replacement is open, so the log is
+// still writable after a failed rotation.
+func (l *LogFile) rotate() error {
+	if l.Retain <= 0 {
+		if err := os.Remove(l.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	} else {
+		oldest := fmt.Sprintf("%s.%d", l.Path, l.Retain)
+		if err := os.Remove(oldest); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		for i := l.Retain - 1; i > 0; i-- {
+			src := fmt.Sprintf("%s.%d", l.Path, i)
+			dst := fmt.Sprintf("%s.%d", l.Path, i+1)
+			if err := os.Rename(src, dst); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+		if err := os.Rename(l.Path, l.Path+".1"); err != nil {
+			return err
+		}
+	}
+
+	prev := l.f
+	if err := l.open(os.O_WRONLY | os.O_CREATE | os.O_TRUNC); err != nil {
+		return err
+	}
+	return prev.Close()
+}
+
+func (l *LogFile) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.f.Close()
+}
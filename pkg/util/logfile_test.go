@@ -0,0 +1,108 @@
+package util_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jpappel/atlas/pkg/util"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestOpenLogFile_Append(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "atlas.log")
+	if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := util.OpenLogFile(path, true, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Write([]byte("new\n"))
+	l.Close()
+
+	if got, want := readFile(t, path), "old\nnew\n"; got != want {
+		t.Errorf("Appended log = %q, want %q", got, want)
+	}
+
+	l, err = util.OpenLogFile(path, false, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	if got := readFile(t, path); got != "" {
+		t.Errorf("Expected truncated log, got %q", got)
+	}
+}
+
+func TestLogFile_Rotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "atlas.log")
+	l, err := util.OpenLogFile(path, false, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"aaa\n", "bbb\n", "ccc\n", "ddd\n"} {
+		if _, err := l.Write([]byte(line)); err != nil {
+			t.Fatal("Unexpected write error:", err)
+		}
+	}
+	l.Close()
+
+	want := map[string]string{
+		path:        "ddd\n",
+		path + ".1": "ccc\n",
+		path + ".2": "bbb\n",
+	}
+	for p, w := range want {
+		if got := readFile(t, p); got != w {
+			t.Errorf("%s = %q, want %q", filepath.Base(p), got, w)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); err == nil {
+		t.Error("Expected only 2 rotated files to be kept")
+	}
+}
+
+func TestLogFile_RotateFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "atlas.log")
+	l, err := util.OpenLogFile(path, false, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// the oldest rotated file cannot be removed
+	blocker := path + ".2"
+	if err := os.MkdirAll(filepath.Join(blocker, "child"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Write([]byte("aaa\n")); err != nil {
+		t.Fatal("Unexpected write error:", err)
+	}
+	if _, err := l.Write([]byte("bbb\n")); err == nil {
+		t.Fatal("Expected an error when rotation fails")
+	}
+
+	if err := os.RemoveAll(blocker); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Write([]byte("ccc\n")); err != nil {
+		t.Fatal("Unexpected write error after a failed rotation:", err)
+	}
+	if got, want := readFile(t, path), "ccc\n"; got != want {
+		t.Errorf("Log = %q, want %q", got, want)
+	}
+	if got, want := readFile(t, path+".1"), "aaa\n"; got != want {
+		t.Errorf("Rotated log = %q, want %q", got, want)
+	}
+}
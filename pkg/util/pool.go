@@ -0,0 +1,119 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// A bounded pool of workers processing jobs of type J.
+//
+// Jobs are queued without blocking, so work may submit further jobs.
+// Once the context is done remaining jobs are skipped.
+type Pool[J any] struct {
+	ctx      context.Context
+	work     func(context.Context, J) error
+	mu       sync.Mutex
+	jobReady *sync.Cond
+	idle     *sync.Cond
+	queue    []J
+	pending  int
+	closed   bool
+	errs     []error
+	workers  sync.WaitGroup
+}
+
+// Start numWorkers goroutines which call work for each submitted job
+func NewPool[J any](ctx context.Context, numWorkers uint, work func(context.Context, J) error) *Pool[J] {
+	if numWorkers == 0 {
+		panic("Invalid number of workers: 0")
+	}
+
+	p := &Pool[J]{ctx: ctx, work: work}
+	p.jobReady = sync.NewCond(&p.mu)
+	p.idle = sync.NewCond(&p.mu)
+
+	p.workers.Add(int(numWorkers))
+	for range numWorkers {
+		go p.worker()
+	}
+
+	return p
+}
+
+// Queue jobs for processing, must not be called after Wait returns
+func (p *Pool[J]) Submit(jobs ...J) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		panic("Submit called on a closed pool")
+	}
+
+	p.queue = append(p.queue, jobs...)
+	p.pending += len(jobs)
+	for range jobs {
+		p.jobReady.Signal()
+	}
+}
+
+// Block until every submitted job has been processed, leaving the workers running
+func (p *Pool[J]) Flush() {
+	p.mu.Lock()
+	for p.pending > 0 {
+		p.idle.Wait()
+	}
+	p.mu.Unlock()
+}
+
+// Block until every submitted job has been processed then stop the workers.
+// Returns errors from work joined with the context's error.
+func (p *Pool[J]) Wait() error {
+	p.mu.Lock()
+	for p.pending > 0 {
+		p.idle.Wait()
+	}
+	p.closed = true
+	p.jobReady.Broadcast()
+	p.mu.Unlock()
+
+	p.workers.Wait()
+
+	if err := p.ctx.Err(); err != nil {
+		p.errs = append(p.errs, err)
+	}
+	return errors.Join(p.errs...)
+}
+
+func (p *Pool[J]) worker() {
+	defer p.workers.Done()
+	for {
+		p.mu.Lock()
+		for len(p.queue) == 0 && !p.closed {
+			p.jobReady.Wait()
+		}
+		if len(p.queue) == 0 {
+			p.mu.Unlock()
+			return
+		}
+		job := p.queue[0]
+		var zero J
+		p.queue[0] = zero
+		p.queue = p.queue[1:]
+		p.mu.Unlock()
+
+		var err error
+		if p.ctx.Err() == nil {
+			err = p.work(p.ctx, job)
+		}
+
+		p.mu.Lock()
+		if err != nil {
+			p.errs = append(p.errs, err)
+		}
+		p.pending--
+		if p.pending == 0 {
+			p.idle.Broadcast()
+		}
+		p.mu.Unlock()
+	}
+}
@@ -0,0 +1,105 @@
+package util_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jpappel/atlas/pkg/util"
+)
+
+func TestPool(t *testing.T) {
+	sum := &atomic.Int64{}
+	pool := util.NewPool(t.Context(), 4, func(_ context.Context, n int) error {
+		sum.Add(int64(n))
+		return nil
+	})
+	for i := range 1000 {
+		pool.Submit(i + 1)
+	}
+
+	if err := pool.Wait(); err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	if got, want := sum.Load(), int64(1000*1001/2); got != want {
+		t.Errorf("Sum = %d, want %d", got, want)
+	}
+}
+
+func TestPool_RecursiveSubmit(t *testing.T) {
+	count := &atomic.Int64{}
+	var pool *util.Pool[int]
+	// a full binary tree of depth 10 with a single worker
+	pool = util.NewPool(t.Context(), 1, func(_ context.Context, depth int) error {
+		count.Add(1)
+		if depth < 10 {
+			pool.Submit(depth+1, depth+1)
+		}
+		return nil
+	})
+	pool.Submit(0)
+
+	if err := pool.Wait(); err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	if got, want := count.Load(), int64(1<<11-1); got != want {
+		t.Errorf("Processed %d jobs, want %d", got, want)
+	}
+}
+
+func TestPool_Flush(t *testing.T) {
+	sum := &atomic.Int64{}
+	pool := util.NewPool(t.Context(), 4, func(_ context.Context, n int) error {
+		sum.Add(int64(n))
+		return nil
+	})
+
+	// the pool can be reused after each flush
+	for round := range 3 {
+		for i := range 100 {
+			pool.Submit(i + 1)
+		}
+		pool.Flush()
+		if got, want := sum.Load(), int64((round+1)*100*101/2); got != want {
+			t.Errorf("Round %d: sum = %d, want %d", round, got, want)
+		}
+	}
+
+	if err := pool.Wait(); err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+}
+
+func TestPool_Errors(t *testing.T) {
+	errOdd := errors.New("odd")
+	pool := util.NewPool(t.Context(), 3, func(_ context.Context, n int) error {
+		if n%2 == 1 {
+			return errOdd
+		}
+		return nil
+	})
+	pool.Submit(1, 2, 3, 4)
+
+	if err := pool.Wait(); !errors.Is(err, errOdd) {
+		t.Errorf("Expected odd error, got %v", err)
+	}
+}
+
+func TestPool_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	processed := &atomic.Int64{}
+	pool := util.NewPool(ctx, 1, func(_ context.Context, n int) error {
+		processed.Add(1)
+		cancel()
+		return nil
+	})
+	pool.Submit(1, 2, 3, 4, 5)
+
+	if err := pool.Wait(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected cancellation error, got %v", err)
+	}
+	if got := processed.Load(); got != 1 {
+		t.Errorf("Processed %d jobs after cancelling, want 1", got)
+	}
+}
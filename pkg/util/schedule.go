@@ -0,0 +1,165 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A recurring schedule
+type Schedule interface {
+	// The first time after t in the schedule, zero if there is none
+	Next(t time.Time) time.Time
+}
+
+// Run at a fixed interval
+type IntervalSchedule time.Duration
+
+func (s IntervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(s))
+}
+
+// Run on minutes matching the fields of a cron expression.
+// Each field is a bitset of matching values.
+type CronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// cron matches either day field when both are restricted
+	domStar, dowStar bool
+}
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse a schedule from a five field cron expression (minute hour day-of-month month day-of-week),
+// a descriptor such as @daily or @hourly, or an interval as @every <duration>.
+//
+// Fields may be *, a value, a range a-b, or a comma separated list of these,
+// each optionally followed by /step.
+func ParseSchedule(s string) (Schedule, error) {
+	s = strings.TrimSpace(s)
+	if after, ok := strings.CutPrefix(s, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(after))
+		if err != nil {
+			return nil, fmt.Errorf("Cannot parse schedule interval: %w", err)
+		} else if d <= 0 {
+			return nil, fmt.Errorf("Schedule interval must be positive, got %s", d)
+		}
+		return IntervalSchedule(d), nil
+	}
+	if expr, ok := cronDescriptors[s]; ok {
+		s = expr
+	} else if strings.HasPrefix(s, "@") {
+		return nil, fmt.Errorf("Unrecognized schedule descriptor %s", s)
+	}
+
+	fields := strings.Fields(s)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("Expected 5 fields in cron expression, got %d", len(fields))
+	}
+
+	var sched CronSchedule
+	var err error
+	if sched.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("Cannot parse minute: %w", err)
+	}
+	if sched.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("Cannot parse hour: %w", err)
+	}
+	if sched.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("Cannot parse day of month: %w", err)
+	}
+	if sched.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("Cannot parse month: %w", err)
+	}
+	if sched.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("Cannot parse day of week: %w", err)
+	}
+	// 7 is another name for sunday
+	if sched.dow&(1<<7) != 0 {
+		sched.dow |= 1
+	}
+	sched.domStar = strings.HasPrefix(fields[2], "*")
+	sched.dowStar = strings.HasPrefix(fields[4], "*")
+
+	return sched, nil
+}
+
+func parseCronField(field string, low, high int) (uint64, error) {
+	var bits uint64
+	for item := range strings.SplitSeq(field, ",") {
+		rangeStr, stepStr, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepStr); err != nil || step <= 0 {
+				return 0, fmt.Errorf("Invalid step %s", stepStr)
+			}
+		}
+
+		start, end := low, high
+		if rangeStr != "*" {
+			startStr, endStr, isRange := strings.Cut(rangeStr, "-")
+			var err error
+			if start, err = strconv.Atoi(startStr); err != nil {
+				return 0, fmt.Errorf("Invalid value %s", startStr)
+			}
+			end = start
+			if isRange {
+				if end, err = strconv.Atoi(endStr); err != nil {
+					return 0, fmt.Errorf("Invalid value %s", endStr)
+				}
+			} else if hasStep {
+				// a/step runs from a to the end of the field
+				end = high
+			}
+		}
+		if start < low || end > high || start > end {
+			return 0, fmt.Errorf("%s is outside of %d-%d", rangeStr, low, high)
+		}
+
+		for i := start; i <= end; i += step {
+			bits |= 1 << i
+		}
+	}
+
+	return bits, nil
+}
+
+func (s CronSchedule) matchesDay(t time.Time) bool {
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<int(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func (s CronSchedule) Next(t time.Time) time.Time {
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, t.Location())
+	// every schedule with a matching day repeats within a leap cycle
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<int(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		} else if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		} else if s.hour&(1<<t.Hour()) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		} else if s.minute&(1<<t.Minute()) == 0 {
+			t = t.Add(time.Minute)
+		} else {
+			return t
+		}
+	}
+
+	return time.Time{}
+}
@@ -0,0 +1,55 @@
+package util_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jpappel/atlas/pkg/util"
+)
+
+func TestParseSchedule(t *testing.T) {
+	from := time.Date(2025, time.March, 14, 15, 9, 26, 0, time.UTC) // a Friday
+	tests := []struct {
+		schedule string
+		want     time.Time
+		wantErr  bool
+	}{
+		{"@every 90m", from.Add(90 * time.Minute), false},
+		{"@hourly", time.Date(2025, time.March, 14, 16, 0, 0, 0, time.UTC), false},
+		{"@daily", time.Date(2025, time.March, 15, 0, 0, 0, 0, time.UTC), false},
+		{"@weekly", time.Date(2025, time.March, 16, 0, 0, 0, 0, time.UTC), false},
+		{"@monthly", time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC), false},
+		{"* * * * *", time.Date(2025, time.March, 14, 15, 10, 0, 0, time.UTC), false},
+		{"*/15 * * * *", time.Date(2025, time.March, 14, 15, 15, 0, 0, time.UTC), false},
+		{"30 3 * * 0", time.Date(2025, time.March, 16, 3, 30, 0, 0, time.UTC), false},
+		{"0 9-17/4 * * 1-5", time.Date(2025, time.March, 14, 17, 0, 0, 0, time.UTC), false},
+		{"0 0 1,15 * *", time.Date(2025, time.March, 15, 0, 0, 0, 0, time.UTC), false},
+		{"0 0 13 * 5", time.Date(2025, time.March, 15, 0, 0, 0, 0, time.UTC).AddDate(0, 0, 6), false}, // the 13th or a Friday
+		{"0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC), false},
+		{"0 0 * * 7", time.Date(2025, time.March, 16, 0, 0, 0, 0, time.UTC), false},
+		{"0 0 30 2 *", time.Time{}, false},
+		{"@every -1h", time.Time{}, true},
+		{"@fortnightly", time.Time{}, true},
+		{"* * * *", time.Time{}, true},
+		{"60 * * * *", time.Time{}, true},
+		{"5-1 * * * *", time.Time{}, true},
+		{"*/0 * * * *", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.schedule, func(t *testing.T) {
+			sched, err := util.ParseSchedule(tt.schedule)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error")
+				}
+				return
+			} else if err != nil {
+				t.Fatal("Unexpected error:", err)
+			}
+
+			if got := sched.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,11 +3,49 @@ package util
 import (
 	"iter"
 	"math"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
 
 func ParseDateTime(s string) (time.Time, error) {
+	return ParseDateTimeAt(s, time.Now())
+}
+
+// Parse a datetime which may be relative to now.
+//
+// Accepts absolute formats as well as natural language such as
+// "today", "yesterday", "last week", "3 months ago", and weekday names.
+// Relative dates are truncated to midnight, except for hours and minutes.
+func ParseDateTimeAt(s string, now time.Time) (time.Time, error) {
+	start, _, err := ParseDateRangeAt(s, now)
+	return start, err
+}
+
+func ParseDateRange(s string) (start time.Time, end time.Time, err error) {
+	return ParseDateRangeAt(s, time.Now())
+}
+
+// Parse a datetime which may describe a period, such as a year (2024),
+// ISO week (2024-W23) or quarter (2024Q2). For periods end is the exclusive
+// end of the period, otherwise it is the zero time.
+//
+// Unsigned integers of 4 digits are years, of 8 digits are compact dates (20240102),
+// and of more than 8 digits are unix timestamps. Other integers, including negative
+// ones, are only recognized as unix timestamps when prefixed with @.
+func ParseDateRangeAt(s string, now time.Time) (start time.Time, end time.Time, err error) {
+	s = strings.TrimSpace(s)
+	if t, ok := parseRelativeDate(strings.ToLower(s), now); ok {
+		return t, time.Time{}, nil
+	}
+	if start, end, ok := parsePeriod(s); ok {
+		return start, end, nil
+	}
+	if start, end, ok := parseInteger(s); ok {
+		return start, end, nil
+	}
+
 	dateFormats := []string{
 		"Jan _2, 2006",
 		"January 2, 2006",
@@ -29,14 +67,159 @@ func ParseDateTime(s string) (time.Time, error) {
 	}
 
 	var t time.Time
-	var err error
 	for _, layout := range dateFormats {
 		if t, err = time.Parse(layout, s); err == nil {
-			return t, nil
+			return t, time.Time{}, nil
+		}
+	}
+
+	return time.Time{}, time.Time{}, err
+}
+
+// parse years (2024), compact dates (20240102), and unix timestamps (@1700000000, 1700000000)
+func parseInteger(s string) (time.Time, time.Time, bool) {
+	digits, isEpoch := strings.CutPrefix(s, "@")
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || digits[0] == '+' || (digits[0] == '-' && !isEpoch) {
+		return time.Time{}, time.Time{}, false
+	}
+
+	switch {
+	case isEpoch || len(digits) > 8:
+		return time.Unix(n, 0).UTC(), time.Time{}, true
+	case len(digits) == 4:
+		start := time.Date(int(n), time.January, 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(1, 0, 0), true
+	case len(digits) == 8:
+		if t, err := time.Parse("20060102", digits); err == nil {
+			return t, time.Time{}, true
+		}
+	}
+
+	return time.Time{}, time.Time{}, false
+}
+
+// parse ISO weeks (2024-W23, 2024W23) and quarters (2024Q2, 2024-Q2)
+func parsePeriod(s string) (time.Time, time.Time, bool) {
+	if len(s) < 6 {
+		return time.Time{}, time.Time{}, false
+	}
+	year, err := strconv.Atoi(s[:4])
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	rest := strings.TrimPrefix(s[4:], "-")
+	if len(rest) < 2 {
+		return time.Time{}, time.Time{}, false
+	}
+
+	n, err := strconv.Atoi(rest[1:])
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	switch rest[0] {
+	case 'W', 'w':
+		if n < 1 || n > 53 {
+			return time.Time{}, time.Time{}, false
+		}
+		// week 1 contains January 4th
+		jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+		weekOffset := (int(jan4.Weekday()) + 6) % 7
+		start := jan4.AddDate(0, 0, 7*(n-1)-weekOffset)
+		if _, week := start.ISOWeek(); week != n {
+			return time.Time{}, time.Time{}, false
+		}
+		return start, start.AddDate(0, 0, 7), true
+	case 'Q', 'q':
+		if n < 1 || n > 4 {
+			return time.Time{}, time.Time{}, false
+		}
+		start := time.Date(year, time.Month(3*(n-1)+1), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 3, 0), true
+	}
+
+	return time.Time{}, time.Time{}, false
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+func parseRelativeDate(s string, now time.Time) (time.Time, bool) {
+	year, month, day := now.Date()
+	midnight := time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+
+	switch s {
+	case "now":
+		return now, true
+	case "today":
+		return midnight, true
+	case "yesterday":
+		return midnight.AddDate(0, 0, -1), true
+	case "tomorrow":
+		return midnight.AddDate(0, 0, 1), true
+	}
+
+	if unit, ok := strings.CutPrefix(s, "last "); ok {
+		if weekday, ok := weekdays[unit]; ok {
+			// strictly before today
+			daysBack := (int(midnight.Weekday())-int(weekday)+6)%7 + 1
+			return midnight.AddDate(0, 0, -daysBack), true
 		}
+		return shiftDate(now, midnight, 1, unit)
 	}
 
-	return time.Time{}, err
+	if weekday, ok := weekdays[s]; ok {
+		// most recent, including today
+		daysBack := (int(midnight.Weekday()) - int(weekday) + 7) % 7
+		return midnight.AddDate(0, 0, -daysBack), true
+	}
+
+	if rest, ok := strings.CutSuffix(s, " ago"); ok {
+		countStr, unit, ok := strings.Cut(rest, " ")
+		if !ok {
+			return time.Time{}, false
+		}
+		var count int
+		switch countStr {
+		case "a", "an":
+			count = 1
+		default:
+			n, err := strconv.Atoi(countStr)
+			if err != nil || n < 0 {
+				return time.Time{}, false
+			}
+			count = n
+		}
+		return shiftDate(now, midnight, count, strings.TrimSuffix(unit, "s"))
+	}
+
+	return time.Time{}, false
+}
+
+// move back count units from now
+func shiftDate(now time.Time, midnight time.Time, count int, unit string) (time.Time, bool) {
+	switch unit {
+	case "minute":
+		return now.Add(time.Duration(-count) * time.Minute), true
+	case "hour":
+		return now.Add(time.Duration(-count) * time.Hour), true
+	case "day":
+		return midnight.AddDate(0, 0, -count), true
+	case "week":
+		return midnight.AddDate(0, 0, -7*count), true
+	case "month":
+		return midnight.AddDate(0, -count, 0), true
+	case "year":
+		return midnight.AddDate(-count, 0, 0), true
+	}
+	return time.Time{}, false
 }
 
 // Estimate an interval around a time which is still "meaningful"
@@ -108,46 +291,142 @@ func BackwardsFilterIter[E any](s []E, cond func(e E) bool) iter.Seq2[int, E] {
 // https://en.wikipedia.org/wiki/Levenshtein_distance#Iterative_with_full_matrix
 // PERF: more performant implementations exist
 func LevensteinDistance(s, t string) int {
-	m, n := len(s), len(t)
-	d := make([][]int, m+1)
-	for i := range m + 1 {
-		d[i] = make([]int, n+1)
+	return LevensteinDistanceCeil(s, t, math.MaxInt)
+}
+
+// Compute the Levenshtein distance between s and t, stopping early once the
+// distance is known to be at least ceil. Distances at or above ceil are
+// returned as ceil.
+func LevensteinDistanceCeil(s, t string, ceil int) int {
+	a, b := []rune(s), []rune(t)
+	m, n := len(a), len(b)
+	if m < n {
+		a, b = b, a
+		m, n = n, m
+	}
+	if m-n >= ceil {
+		return ceil
+	}
+
+	// rows of the distance matrix for the previous and current rune of a
+	prev := make([]int, n+1)
+	cur := make([]int, n+1)
+	for j := range n + 1 {
+		prev[j] = j
 	}
 
 	for i := range m {
-		d[i+1][0] = i
+		cur[0] = i + 1
+		rowMin := cur[0]
+		for j := range n {
+			subCost := 1
+			if a[i] == b[j] {
+				subCost = 0
+			}
+
+			cur[j+1] = min(prev[j+1]+1, cur[j]+1, prev[j]+subCost)
+			rowMin = min(rowMin, cur[j+1])
+		}
+		if rowMin >= ceil {
+			return ceil
+		}
+		prev, cur = cur, prev
 	}
-	for j := range n {
-		d[0][j+1] = j
+
+	return min(prev[n], ceil)
+}
+
+// Compute the smallest Levenshtein distance between pattern and any substring of s
+func SubstringDistance(s, pattern string) int {
+	a, b := []rune(s), []rune(pattern)
+
+	// rows of the distance matrix for the previous and current rune of pattern,
+	// a match may start anywhere in s so the first row is all zero
+	prev := make([]int, len(a)+1)
+	cur := make([]int, len(a)+1)
+
+	for i := range b {
+		cur[0] = i + 1
+		for j := range a {
+			subCost := 1
+			if a[j] == b[i] {
+				subCost = 0
+			}
+
+			cur[j+1] = min(prev[j+1]+1, cur[j]+1, prev[j]+subCost)
+		}
+		prev, cur = cur, prev
+	}
+
+	// a match may also end anywhere in s
+	return slices.Min(prev)
+}
+
+// Compute the optimal string alignment distance between two strings, where
+// transposing adjacent characters counts as a single edit.
+func DamerauLevenshteinDistance(s, t string) int {
+	return DamerauLevenshteinDistanceCeil(s, t, math.MaxInt)
+}
+
+// Compute the optimal string alignment distance between s and t, stopping
+// early once the distance is known to be at least ceil. Distances at or above
+// ceil are returned as ceil.
+func DamerauLevenshteinDistanceCeil(s, t string, ceil int) int {
+	a, b := []rune(s), []rune(t)
+	m, n := len(a), len(b)
+	if max(m-n, n-m) >= ceil {
+		return ceil
+	}
+
+	// rolling rows of the distance matrix, transpositions look back two rows
+	prevPrev := make([]int, n+1)
+	prev := make([]int, n+1)
+	cur := make([]int, n+1)
+	for j := range n + 1 {
+		prev[j] = j
 	}
 
-	var subCost int
-	for j := range n {
-		for i := range m {
-			if s[i] == t[j] {
+	prevMin := 0
+	for i := 1; i <= m; i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= n; j++ {
+			subCost := 1
+			if a[i-1] == b[j-1] {
 				subCost = 0
-			} else {
-				subCost = 1
 			}
 
-			del := d[i][j+1] + 1
-			insert := d[i+1][j] + 1
-			sub := d[i][j] + subCost
-			d[i+1][j+1] = min(del, insert, sub)
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+subCost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prevPrev[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		// later rows build on the last two
+		if rowMin >= ceil && prevMin >= ceil {
+			return ceil
 		}
+		prevMin = rowMin
+		prevPrev, prev, cur = prev, cur, prevPrev
 	}
 
-	return d[m][n]
+	return min(prev[n], ceil)
+}
+
+// Damerau-Levenshtein distance ignoring case with a ceiling, use for suggestions
+func FoldedDistance(s, t string, ceil int) int {
+	return DamerauLevenshteinDistanceCeil(strings.ToLower(s), strings.ToLower(t), ceil)
 }
 
 // Find nearest element of a slice using cmp, returns the found element and
-// if the distance is below ceil
-func Nearest[E any](candidate E, valid []E, cmp func(E, E) int, ceil int) (E, bool) {
+// if the distance is below ceil. cmp is given the distance to beat and may
+// stop early once it is reached.
+func Nearest[E any](candidate E, valid []E, cmp func(E, E, int) int, ceil int) (E, bool) {
 	minDistance := math.MaxInt
 	minIdx := -1
 	var d int
 	for i, e := range valid {
-		if sd := cmp(candidate, e); sd < 0 {
+		if sd := cmp(candidate, e, min(minDistance, ceil)); sd < 0 {
 			d = -sd
 		} else {
 			d = sd
@@ -1,8 +1,10 @@
 package util_test
 
 import (
-	"github.com/jpappel/atlas/pkg/util"
 	"testing"
+	"time"
+
+	"github.com/jpappel/atlas/pkg/util"
 )
 
 func TestLevensteinDistance(t *testing.T) {
@@ -14,6 +16,9 @@ func TestLevensteinDistance(t *testing.T) {
 		{"sitting", "kitten", 3},
 		{"Saturday", "Sunday", 3},
 		{"hello", "kelm", 3},
+		{"", "abc", 3},
+		{"abc", "abc", 0},
+		{"flaw", "lawn", 2},
 	}
 	for _, tt := range tests {
 		t.Run(tt.s+" "+tt.t, func(t *testing.T) {
@@ -24,3 +29,227 @@ func TestLevensteinDistance(t *testing.T) {
 		})
 	}
 }
+
+func TestParseDateTimeAt(t *testing.T) {
+	// Wednesday
+	now := time.Date(2025, 6, 18, 15, 30, 0, 0, time.UTC)
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		s       string
+		want    time.Time
+		wantErr bool
+	}{
+		{"now", now, false},
+		{"today", day(2025, 6, 18), false},
+		{" Yesterday ", day(2025, 6, 17), false},
+		{"tomorrow", day(2025, 6, 19), false},
+		{"last week", day(2025, 6, 11), false},
+		{"last month", day(2025, 5, 18), false},
+		{"last year", day(2024, 6, 18), false},
+		{"3 months ago", day(2025, 3, 18), false},
+		{"2 days ago", day(2025, 6, 16), false},
+		{"a week ago", day(2025, 6, 11), false},
+		{"1 year ago", day(2024, 6, 18), false},
+		{"an hour ago", now.Add(-time.Hour), false},
+		{"90 minutes ago", now.Add(-90 * time.Minute), false},
+		{"wednesday", day(2025, 6, 18), false},
+		{"monday", day(2025, 6, 16), false},
+		{"thursday", day(2025, 6, 12), false},
+		{"last wednesday", day(2025, 6, 11), false},
+		{"last friday", day(2025, 6, 13), false},
+		{"2025-01-02", day(2025, 1, 2), false},
+		{"some months ago", time.Time{}, true},
+		{"last fortnight", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, gotErr := util.ParseDateTimeAt(tt.s, now)
+			if (gotErr != nil) != tt.wantErr {
+				t.Fatalf("ParseDateTimeAt() error = %v, wantErr %v", gotErr, tt.wantErr)
+			} else if gotErr != nil {
+				return
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseDateTimeAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLevensteinDistanceCeil(t *testing.T) {
+	tests := []struct {
+		s    string
+		t    string
+		ceil int
+		want int
+	}{
+		{"sitting", "kitten", 4, 3},
+		{"sitting", "kitten", 3, 3},
+		{"sitting", "kitten", 2, 2},
+		{"", "abc", 10, 3},
+		{"abc", "", 2, 2},
+		{"a", "a", 1, 0},
+		{"query", "shell", 100, 4},
+		{"café", "cafe", 2, 1},
+		{"日本語", "日本", 2, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s+" "+tt.t, func(t *testing.T) {
+			got := util.LevensteinDistanceCeil(tt.s, tt.t, tt.ceil)
+			if got != tt.want {
+				t.Errorf("LevensteinDistanceCeil() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDamerauLevenshteinDistance(t *testing.T) {
+	tests := []struct {
+		s    string
+		t    string
+		want int
+	}{
+		{"sitting", "kitten", 3},
+		{"Saturday", "Sunday", 3},
+		{"", "abc", 3},
+		{"ab", "ba", 1},
+		{"qeury", "query", 1},
+		{"ca", "abc", 3},
+		{"héllo", "hlélo", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s+" "+tt.t, func(t *testing.T) {
+			got := util.DamerauLevenshteinDistance(tt.s, tt.t)
+			if got != tt.want {
+				t.Errorf("DamerauLevenshteinDistance() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFoldedDistance(t *testing.T) {
+	tests := []struct {
+		s    string
+		t    string
+		ceil int
+		want int
+	}{
+		{"QEURY", "query", 3, 1},
+		{"sitting", "kitten", 4, 3},
+		{"sitting", "kitten", 2, 2},
+		{"tidy", "mergeregex", 3, 3},
+		{"", "abc", 10, 3},
+		{"ÉCOLE", "école", 1, 0},
+		{"héllo", "hlélo", 2, 1},
+		{"naïve", "naive", 5, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s+" "+tt.t, func(t *testing.T) {
+			got := util.FoldedDistance(tt.s, tt.t, tt.ceil)
+			if got != tt.want {
+				t.Errorf("FoldedDistance() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubstringDistance(t *testing.T) {
+	tests := []struct {
+		s       string
+		pattern string
+		want    int
+	}{
+		{"Notes on Plan 9", "plan 9", 1},
+		{"Notes on Plan 9", "Plan 9", 0},
+		{"Notes on Plan 9", "plna", 2},
+		{"abc", "", 0},
+		{"", "abc", 3},
+		{"café society", "cafe", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s+" "+tt.pattern, func(t *testing.T) {
+			got := util.SubstringDistance(tt.s, tt.pattern)
+			if got != tt.want {
+				t.Errorf("SubstringDistance() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNearest_Folded(t *testing.T) {
+	valid := []string{"strictEq", "mergeregex", "mergeap", "sort"}
+	tests := []struct {
+		candidate string
+		want      string
+		wantOk    bool
+	}{
+		{"STRICTEQ", "strictEq", true},
+		{"mergaeregex", "mergeregex", true},
+		{"mreegap", "mergeap", true},
+		{"tidy", "sort", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.candidate, func(t *testing.T) {
+			got, gotOk := util.Nearest(tt.candidate, valid, util.FoldedDistance, 3)
+			if gotOk != tt.wantOk || (gotOk && got != tt.want) {
+				t.Errorf("Nearest() = %v, %v, want %v, %v", got, gotOk, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestParseDateRange(t *testing.T) {
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		s         string
+		wantStart time.Time
+		wantEnd   time.Time
+		wantErr   bool
+	}{
+		{"2024-W23", day(2024, 6, 3), day(2024, 6, 10), false},
+		{"2024W01", day(2024, 1, 1), day(2024, 1, 8), false},
+		{"2021-W01", day(2021, 1, 4), day(2021, 1, 11), false},
+		{"2020-W53", day(2020, 12, 28), day(2021, 1, 4), false},
+		{"2021-W53", time.Time{}, time.Time{}, true},
+		{"2024Q2", day(2024, 4, 1), day(2024, 7, 1), false},
+		{"2024-q4", day(2024, 10, 1), day(2025, 1, 1), false},
+		{"2024Q5", time.Time{}, time.Time{}, true},
+		{"1718000000", time.Unix(1718000000, 0), time.Time{}, false},
+		{"@1718000000", time.Unix(1718000000, 0), time.Time{}, false},
+		{"@0", time.Unix(0, 0), time.Time{}, false},
+		{"2024", day(2024, 1, 1), day(2025, 1, 1), false},
+		{"20240102", day(2024, 1, 2), time.Time{}, false},
+		{"20241302", time.Time{}, time.Time{}, true},
+		{"123", time.Time{}, time.Time{}, true},
+		{"-123", time.Time{}, time.Time{}, true},
+		{"-2024", time.Time{}, time.Time{}, true},
+		{"+2024", time.Time{}, time.Time{}, true},
+		{"0999", day(999, 1, 1), day(1000, 1, 1), false},
+		{"12345", time.Time{}, time.Time{}, true},
+		{"1234567", time.Time{}, time.Time{}, true},
+		{"-20240102", time.Time{}, time.Time{}, true},
+		{"123456789", time.Unix(123456789, 0), time.Time{}, false},
+		{"-1718000000", time.Time{}, time.Time{}, true},
+		{"@-1718000000", time.Unix(-1718000000, 0), time.Time{}, false},
+		{"@12345", time.Unix(12345, 0), time.Time{}, false},
+		{"@", time.Time{}, time.Time{}, true},
+		{"2024-06-03", day(2024, 6, 3), time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			gotStart, gotEnd, gotErr := util.ParseDateRange(tt.s)
+			if (gotErr != nil) != tt.wantErr {
+				t.Fatalf("ParseDateRange() error = %v, wantErr %v", gotErr, tt.wantErr)
+			} else if gotErr != nil {
+				return
+			}
+			if !gotStart.Equal(tt.wantStart) || !gotEnd.Equal(tt.wantEnd) {
+				t.Errorf("ParseDateRange() = [%v, %v), want [%v, %v)", gotStart, gotEnd, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}